@@ -2,6 +2,7 @@ package easylog
 
 import (
 	"strconv"
+	"time"
 )
 
 type Bytes struct {
@@ -16,10 +17,49 @@ func (b *Bytes) AppendInt(i int64) {
 	b.bytes = strconv.AppendInt(b.bytes, i, 10)
 }
 
+func (b *Bytes) AppendUint(i uint64) {
+	b.bytes = strconv.AppendUint(b.bytes, i, 10)
+}
+
+func (b *Bytes) AppendFloat(f float64, bitSize int) {
+	b.bytes = strconv.AppendFloat(b.bytes, f, 'f', -1, bitSize)
+}
+
+func (b *Bytes) AppendBool(v bool) {
+	b.bytes = strconv.AppendBool(b.bytes, v)
+}
+
+func (b *Bytes) AppendTime(t time.Time, layout string) {
+	b.bytes = t.AppendFormat(b.bytes, layout)
+}
+
 func (b *Bytes) AppendString(s string) {
 	b.bytes = append(b.bytes, s...)
 }
 
+func (b *Bytes) Write(p []byte) (int, error) {
+	b.bytes = append(b.bytes, p...)
+	return len(p), nil
+}
+
+func (b *Bytes) Len() int {
+	return len(b.bytes)
+}
+
+func (b *Bytes) Reset() {
+	b.bytes = b.bytes[:0]
+}
+
+// Truncate discards all but the first n bytes.
+func (b *Bytes) Truncate(n int) {
+	b.bytes = b.bytes[:n]
+}
+
+// Bytes returns the underlying byte slice, it's only valid until the next modification.
+func (b *Bytes) Bytes() []byte {
+	return b.bytes
+}
+
 func (b *Bytes) String() string {
 	return string(b.bytes)
 }
@@ -0,0 +1,457 @@
+package easylog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config declares Loggers, Handlers and ErrorHandlers, in the spirit of logging.config.dictConfig of Python.
+// Handlers and ErrorHandlers are declared by name and referenced by name from the Loggers.
+//
+// The types of Handlers and ErrorHandlers are resolved by the factories registered with
+// RegisterHandler and RegisterErrorHandler, the handler package registers the builtin ones.
+type Config struct {
+	Handlers      map[string]HandlerConfig `json:"handlers" yaml:"handlers"`
+	ErrorHandlers map[string]HandlerConfig `json:"error_handlers" yaml:"error_handlers"`
+	Loggers       map[string]LoggerConfig  `json:"loggers" yaml:"loggers"`
+}
+
+// LoggerConfig declares a Logger, keyed by its name in Config.Loggers. The root Logger is "".
+type LoggerConfig struct {
+	Level        Level                  `json:"level" yaml:"level"`
+	Propagate    bool                   `json:"propagate" yaml:"propagate"`
+	Caller       []Level                `json:"caller" yaml:"caller"`
+	Stack        []Level                `json:"stack" yaml:"stack"`
+	Tags         map[string]interface{} `json:"tags" yaml:"tags"`
+	Kvs          map[string]interface{} `json:"kvs" yaml:"kvs"`
+	Handlers     []string               `json:"handlers" yaml:"handlers"`
+	ErrorHandler string                 `json:"error_handler" yaml:"error_handler"`
+}
+
+// HandlerConfig declares a Handler or an ErrorHandler.
+// Type selects the registered factory, which interprets the other members.
+type HandlerConfig struct {
+	Type       string                 `json:"type" yaml:"type"`
+	Formatter  string                 `json:"formatter" yaml:"formatter"`
+	Level      *Level                 `json:"level" yaml:"level"`
+	Writer     *WriterConfig          `json:"writer" yaml:"writer"`
+	BufferSize int                    `json:"buffer_size" yaml:"buffer_size"`
+	Options    map[string]interface{} `json:"options" yaml:"options"`
+}
+
+// WriterConfig declares the writer of a Handler.
+type WriterConfig struct {
+	Type       string   `json:"type" yaml:"type"`
+	Path       string   `json:"path" yaml:"path"`
+	MaxSize    int64    `json:"max_size" yaml:"max_size"`
+	MaxBackups int      `json:"max_backups" yaml:"max_backups"`
+	MaxAge     Duration `json:"max_age" yaml:"max_age"`
+	Interval   Duration `json:"interval" yaml:"interval"`
+	Compress   bool     `json:"compress" yaml:"compress"`
+}
+
+// Duration is a time.Duration encoded as text, such as "1h30m".
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(v)
+
+	return nil
+}
+
+type HandlerFactory func(cfg HandlerConfig) (Handler, error)
+
+type ErrorHandlerFactory func(cfg HandlerConfig) (ErrorHandler, error)
+
+// ConfigHook is called once the Handlers and ErrorHandlers of a Config are built, with whether the Config is
+// applied. It lets the factories stage the changes to the resources shared with the running Handlers until the
+// Config is applied, and drop them otherwise. It's called with the configuration locked.
+type ConfigHook func(applied bool)
+
+var (
+	factoriesMu           sync.RWMutex
+	handlerFactories      = make(map[string]HandlerFactory)
+	errorHandlerFactories = make(map[string]ErrorHandlerFactory)
+	configHooks           []ConfigHook
+)
+
+// RegisterHandler makes a type of Handler available to Config. It overrides the factory registered for typ, if any.
+func RegisterHandler(typ string, f HandlerFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	handlerFactories[typ] = f
+}
+
+// RegisterErrorHandler makes a type of ErrorHandler available to Config. It overrides the factory registered for typ, if any.
+func RegisterErrorHandler(typ string, f ErrorHandlerFactory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	errorHandlerFactories[typ] = f
+}
+
+// RegisterConfigHook adds a hook called by every Configure.
+func RegisterConfigHook(h ConfigHook) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+
+	configHooks = append(configHooks, h)
+}
+
+func init() {
+	RegisterErrorHandler("nop", func(HandlerConfig) (ErrorHandler, error) {
+		return NewNopErrorHandler(), nil
+	})
+}
+
+func handlerFactory(typ string) (HandlerFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	f, ok := handlerFactories[typ]
+	return f, ok
+}
+
+func errorHandlerFactory(typ string) (ErrorHandlerFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+
+	f, ok := errorHandlerFactories[typ]
+	return f, ok
+}
+
+func runConfigHooks(applied bool) {
+	factoriesMu.RLock()
+	hooks := configHooks
+	factoriesMu.RUnlock()
+
+	for _, h := range hooks {
+		h(applied)
+	}
+}
+
+// Validate checks the references between the declarations, the types and the levels.
+// It reports all the problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	for _, name := range sortedKeys(c.Handlers) {
+		h := c.Handlers[name]
+		if _, ok := handlerFactory(h.Type); !ok {
+			errs = append(errs, fmt.Errorf("handler %q: unknown type %q", name, h.Type))
+		}
+		if h.Level != nil && !validLevel(*h.Level) {
+			errs = append(errs, fmt.Errorf("handler %q: unknown level %d", name, *h.Level))
+		}
+	}
+
+	for _, name := range sortedKeys(c.ErrorHandlers) {
+		h := c.ErrorHandlers[name]
+		if _, ok := errorHandlerFactory(h.Type); !ok {
+			errs = append(errs, fmt.Errorf("error handler %q: unknown type %q", name, h.Type))
+		}
+	}
+
+	for _, name := range sortedKeys(c.Loggers) {
+		l := c.Loggers[name]
+		if !validLevel(l.Level) {
+			errs = append(errs, fmt.Errorf("logger %q: unknown level %d", name, l.Level))
+		}
+		for _, level := range append(append([]Level(nil), l.Caller...), l.Stack...) {
+			if !validLevel(level) {
+				errs = append(errs, fmt.Errorf("logger %q: unknown level %d", name, level))
+			}
+		}
+		for _, h := range l.Handlers {
+			if _, ok := c.Handlers[h]; !ok {
+				errs = append(errs, fmt.Errorf("logger %q: undeclared handler %q", name, h))
+			}
+		}
+		if l.ErrorHandler != "" {
+			if _, ok := c.ErrorHandlers[l.ErrorHandler]; !ok {
+				errs = append(errs, fmt.Errorf("logger %q: undeclared error handler %q", name, l.ErrorHandler))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func validLevel(l Level) bool {
+	return l >= _MIN && l <= _MAX
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseConfig decodes a Config from JSON or YAML, according to format ("json", "yaml" or "yml").
+// Unknown members are rejected.
+func ParseConfig(data []byte, format string) (*Config, error) {
+	cfg := &Config{}
+
+	switch strings.ToLower(format) {
+	case "json":
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.DisallowUnknownFields()
+		if err := d.Decode(cfg); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		if err := d.Decode(cfg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+
+	return cfg, nil
+}
+
+// ReadConfigFile reads a Config from a file, whose format is given by the extension.
+func ReadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(data, strings.TrimPrefix(filepath.Ext(path), "."))
+}
+
+// LoadConfigFile reads a Config from a file and applies it, see Configure.
+func LoadConfigFile(path string) error {
+	cfg, err := ReadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	return Configure(cfg)
+}
+
+// Configure validates cfg, builds its Handlers and ErrorHandlers, then applies it.
+// If anything fails, nothing is applied and the current configuration keeps running.
+//
+// Configure diffs cfg against the previous Config: the Handlers and ErrorHandlers declared with the same name
+// and the same settings are carried over as they are, with their open files, the others are built anew.
+// Each declared Logger whose settings differ from its live settings gets them all replaced at once.
+// The Loggers declared by the previous Config but not by cfg are reset to the default settings.
+// An Event sees the settings of cfg for all the Loggers or for none of them.
+// The Handlers and ErrorHandlers of the previous Config which are not carried over are flushed and closed
+// after the switch, their errors are reported to the root ErrorHandler.
+// Loggers and Handlers not managed by Config are left untouched.
+func Configure(cfg *Config) error {
+	return m.configure(cfg)
+}
+
+// appliedConfig is a Config applied by the manager, with the Handlers and ErrorHandlers built for it.
+type appliedConfig struct {
+	cfg           *Config
+	handlers      map[string]Handler
+	errorHandlers map[string]ErrorHandler
+
+	// kept and keptErrors are the names of the Handlers and ErrorHandlers carried over from the previous Config.
+	kept       map[string]bool
+	keptErrors map[string]bool
+}
+
+func (m *manager) configure(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.configMu.Lock()
+	defer m.configMu.Unlock()
+
+	prev := m.applied
+	next, err := buildConfig(cfg, prev)
+	if err != nil {
+		runConfigHooks(false)
+		return err
+	}
+
+	m.apply(prev, next)
+	m.applied = next
+	runConfigHooks(true)
+
+	if prev != nil {
+		m.release(prev, next)
+	}
+
+	return nil
+}
+
+// buildConfig builds the Handlers and ErrorHandlers of cfg, reusing the ones of prev declared identically.
+func buildConfig(cfg *Config, prev *appliedConfig) (*appliedConfig, error) {
+	a := &appliedConfig{
+		cfg:           cfg,
+		handlers:      make(map[string]Handler),
+		errorHandlers: make(map[string]ErrorHandler),
+		kept:          make(map[string]bool),
+		keptErrors:    make(map[string]bool),
+	}
+
+	for _, name := range sortedKeys(cfg.Handlers) {
+		if prev != nil {
+			if hc, ok := prev.cfg.Handlers[name]; ok && reflect.DeepEqual(hc, cfg.Handlers[name]) {
+				a.handlers[name] = prev.handlers[name]
+				a.kept[name] = true
+				continue
+			}
+		}
+
+		f, _ := handlerFactory(cfg.Handlers[name].Type)
+		h, err := f(cfg.Handlers[name])
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("handler %q: %w", name, err)
+		}
+		a.handlers[name] = h
+	}
+
+	for _, name := range sortedKeys(cfg.ErrorHandlers) {
+		if prev != nil {
+			if hc, ok := prev.cfg.ErrorHandlers[name]; ok && reflect.DeepEqual(hc, cfg.ErrorHandlers[name]) {
+				a.errorHandlers[name] = prev.errorHandlers[name]
+				a.keptErrors[name] = true
+				continue
+			}
+		}
+
+		f, _ := errorHandlerFactory(cfg.ErrorHandlers[name].Type)
+		h, err := f(cfg.ErrorHandlers[name])
+		if err != nil {
+			a.close()
+			return nil, fmt.Errorf("error handler %q: %w", name, err)
+		}
+		a.errorHandlers[name] = h
+	}
+
+	return a, nil
+}
+
+// close closes the Handlers and ErrorHandlers built for a Config which is never applied.
+func (a *appliedConfig) close() {
+	for name, h := range a.handlers {
+		if !a.kept[name] {
+			_ = h.Close()
+		}
+	}
+	for name, h := range a.errorHandlers {
+		if !a.keptErrors[name] {
+			_ = h.Close()
+		}
+	}
+}
+
+func (a *appliedConfig) loggerConfig(name string) *loggerConfig {
+	lc := a.cfg.Loggers[name]
+
+	c := newLoggerConfig()
+	c.level = lc.Level
+	c.propagate = lc.Propagate
+	for _, level := range lc.Caller {
+		c.caller[level-_MIN] = true
+	}
+	for _, level := range lc.Stack {
+		c.stack[level-_MIN] = true
+	}
+	for k, v := range lc.Tags {
+		c.tags[k] = v
+	}
+	for k, v := range lc.Kvs {
+		c.kvs[k] = v
+	}
+	for _, h := range lc.Handlers {
+		c.handlers = append(c.handlers, a.handlers[h])
+	}
+	if lc.ErrorHandler != "" {
+		c.errorHandler = a.errorHandlers[lc.ErrorHandler]
+	}
+
+	return c
+}
+
+// apply publishes the settings of the Loggers declared by next which differ from their live settings,
+// and resets the Loggers only declared by prev. The Events see the settings of all the Loggers published at once.
+func (m *manager) apply(prev, next *appliedConfig) {
+	loggers := make(map[*Logger]*loggerConfig)
+	for _, name := range sortedKeys(next.cfg.Loggers) {
+		l := m.getLogger(name)
+		if c := next.loggerConfig(name); !c.equal(l.load()) {
+			loggers[l] = c
+		}
+	}
+	if prev != nil {
+		for _, name := range sortedKeys(prev.cfg.Loggers) {
+			if _, ok := next.cfg.Loggers[name]; !ok {
+				loggers[m.getLogger(name)] = newLoggerConfig()
+			}
+		}
+	}
+
+	m.gen.Add(1)
+	defer m.gen.Add(1)
+
+	for l, c := range loggers {
+		l.store(c)
+	}
+}
+
+// release flushes and closes the Handlers and ErrorHandlers of a replaced Config which next does not carry over.
+func (m *manager) release(a, next *appliedConfig) {
+	eh := m.root.GetErrorHandler()
+
+	for _, name := range sortedKeys(a.handlers) {
+		if next.kept[name] {
+			continue
+		}
+		h := a.handlers[name]
+		if err := h.Flush(); err != nil {
+			_ = eh.Handle(fmt.Errorf("handler %q: %w", name, err))
+		}
+		if err := h.Close(); err != nil {
+			_ = eh.Handle(fmt.Errorf("handler %q: %w", name, err))
+		}
+	}
+
+	for _, name := range sortedKeys(a.errorHandlers) {
+		if next.keptErrors[name] {
+			continue
+		}
+		h := a.errorHandlers[name]
+		if err := h.Flush(); err != nil {
+			_ = eh.Handle(fmt.Errorf("error handler %q: %w", name, err))
+		}
+		if err := h.Close(); err != nil {
+			_ = eh.Handle(fmt.Errorf("error handler %q: %w", name, err))
+		}
+	}
+}
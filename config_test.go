@@ -0,0 +1,413 @@
+package easylog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLevelText(t *testing.T) {
+	for _, l := range []Level{DEBUG, INFO, WARN, ERROR, PANIC, FATAL} {
+		b, err := l.MarshalText()
+		assert.Nil(t, err)
+
+		var p Level
+		assert.Nil(t, p.UnmarshalText(b))
+		assert.Equal(t, l, p)
+	}
+
+	l, err := ParseLevel("warn")
+	assert.Nil(t, err)
+	assert.Equal(t, WARN, l)
+
+	_, err = ParseLevel("verbose")
+	assert.NotNil(t, err)
+
+	_, err = Level(100).MarshalText()
+	assert.NotNil(t, err)
+}
+
+func TestParseConfig(t *testing.T) {
+	yml := `
+handlers:
+  console:
+    type: test
+    formatter: json
+    level: warn
+    buffer_size: 1024
+    writer:
+      type: rolling
+      path: /tmp/app.log
+      max_age: 24h
+      interval: 1h
+    options:
+      size: 10
+error_handlers:
+  errors:
+    type: nop
+loggers:
+  "":
+    level: debug
+    handlers: [console]
+    error_handler: errors
+  db.pool:
+    level: ERROR
+    propagate: true
+    caller: [ERROR, FATAL]
+    stack: [FATAL]
+    tags:
+      service: db
+    kvs:
+      pool: 1
+`
+	cfg, err := ParseConfig([]byte(yml), "yaml")
+	assert.Nil(t, err)
+
+	h := cfg.Handlers["console"]
+	assert.Equal(t, "test", h.Type)
+	assert.Equal(t, WARN, *h.Level)
+	assert.Equal(t, 1024, h.BufferSize)
+	assert.Equal(t, Duration(24*time.Hour), h.Writer.MaxAge)
+	assert.Equal(t, Duration(time.Hour), h.Writer.Interval)
+	assert.Equal(t, 10, h.Options["size"])
+
+	db := cfg.Loggers["db.pool"]
+	assert.Equal(t, ERROR, db.Level)
+	assert.True(t, db.Propagate)
+	assert.Equal(t, []Level{ERROR, FATAL}, db.Caller)
+	assert.Equal(t, []Level{FATAL}, db.Stack)
+	assert.Equal(t, "db", db.Tags["service"])
+	assert.Equal(t, DEBUG, cfg.Loggers[""].Level)
+	assert.Equal(t, "errors", cfg.Loggers[""].ErrorHandler)
+
+	js := `{"loggers": {"a": {"level": "WARN", "handlers": ["h"]}}, "handlers": {"h": {"type": "test", "writer": {"max_age": "1m"}}}}`
+	cfg, err = ParseConfig([]byte(js), "json")
+	assert.Nil(t, err)
+	assert.Equal(t, WARN, cfg.Loggers["a"].Level)
+	assert.Equal(t, Duration(time.Minute), cfg.Handlers["h"].Writer.MaxAge)
+
+	_, err = ParseConfig([]byte(`{"loggers": {"a": {"level": "LOUD"}}}`), "json")
+	assert.NotNil(t, err)
+	_, err = ParseConfig([]byte(`{"logger": {}}`), "json")
+	assert.NotNil(t, err)
+	_, err = ParseConfig([]byte("loggers:\n  a:\n    lvl: INFO\n"), "yaml")
+	assert.NotNil(t, err)
+	_, err = ParseConfig([]byte(""), "toml")
+	assert.NotNil(t, err)
+}
+
+func TestParseConfigMalformedYAML(t *testing.T) {
+	// panicked in yaml.v3 before v3.0.1, CVE-2022-28948
+	for _, data := range []string{"0: [:!00 \xef", "loggers: {a: [", "\t- :\n  -", "loggers:\n  a: &a [*a]\n"} {
+		assert.NotPanics(t, func() {
+			_, err := ParseConfig([]byte(data), "yaml")
+			assert.NotNil(t, err, data)
+		}, data)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	RegisterHandler("validate_test", func(HandlerConfig) (Handler, error) {
+		return NewNopHandler(), nil
+	})
+
+	bad := Level(100)
+	cfg := &Config{
+		Handlers: map[string]HandlerConfig{
+			"ok":      {Type: "validate_test"},
+			"unknown": {Type: "unknown"},
+			"level":   {Type: "validate_test", Level: &bad},
+		},
+		ErrorHandlers: map[string]HandlerConfig{
+			"unknown": {Type: "unknown"},
+		},
+		Loggers: map[string]LoggerConfig{
+			"a": {Level: 100, Caller: []Level{100}, Handlers: []string{"ok", "missing"}, ErrorHandler: "missing"},
+		},
+	}
+
+	err := cfg.Validate()
+	assert.NotNil(t, err)
+	for _, s := range []string{
+		`handler "unknown": unknown type "unknown"`,
+		`handler "level": unknown level 100`,
+		`error handler "unknown": unknown type "unknown"`,
+		`logger "a": unknown level 100`,
+		`logger "a": undeclared handler "missing"`,
+		`logger "a": undeclared error handler "missing"`,
+	} {
+		assert.Contains(t, err.Error(), s)
+	}
+
+	assert.Nil(t, (&Config{}).Validate())
+}
+
+func TestConfigure(t *testing.T) {
+	defer clear()
+	defer func() {
+		assert.Nil(t, Configure(&Config{}))
+	}()
+
+	var built []*MockHandler
+	RegisterHandler("configure_test", func(cfg HandlerConfig) (Handler, error) {
+		if cfg.Formatter == "fail" {
+			return nil, errors.New("build failed")
+		}
+		h := &MockHandler{}
+		h.On("Handle", mock.Anything).Return(true, nil)
+		h.On("Flush").Return(nil)
+		h.On("Close").Return(nil)
+		built = append(built, h)
+		return h, nil
+	})
+
+	eh := &MockErrorHandler{}
+	SetErrorHandler(eh)
+
+	cfg := &Config{
+		Handlers: map[string]HandlerConfig{
+			"a": {Type: "configure_test"},
+			"b": {Type: "configure_test"},
+		},
+		ErrorHandlers: map[string]HandlerConfig{
+			"nop": {Type: "nop"},
+		},
+		Loggers: map[string]LoggerConfig{
+			"configure_test": {
+				Level:        WARN,
+				Propagate:    true,
+				Caller:       []Level{ERROR},
+				Stack:        []Level{FATAL},
+				Tags:         map[string]interface{}{"t": 1},
+				Kvs:          map[string]interface{}{"k": 2},
+				Handlers:     []string{"b", "a"},
+				ErrorHandler: "nop",
+			},
+			"configure_test.child": {
+				Level: DEBUG,
+			},
+		},
+	}
+	assert.Nil(t, Configure(cfg))
+	assert.Equal(t, 2, len(built))
+
+	l := GetLogger("configure_test")
+	assert.Equal(t, WARN, l.GetLevel())
+	assert.True(t, l.GetPropagate())
+	assert.True(t, l.logCaller(ERROR))
+	assert.False(t, l.logCaller(WARN))
+	assert.True(t, l.logStack(FATAL))
+	assert.Equal(t, 1, l.Tags()["t"])
+	assert.Equal(t, 2, l.Kvs()["k"])
+	assert.Equal(t, []Handler{built[1], built[0]}, l.Handlers())
+	assert.Equal(t, NewNopErrorHandler(), l.GetErrorHandler())
+	assert.Equal(t, DEBUG, GetLogger("configure_test.child").GetLevel())
+
+	// a failing Config is not applied at all, and keeps the carried over Handlers open
+	failing := &Config{
+		Handlers: map[string]HandlerConfig{
+			"a": {Type: "configure_test"},
+			"z": {Type: "configure_test", Formatter: "fail"},
+			"y": {Type: "configure_test"},
+		},
+		Loggers: map[string]LoggerConfig{
+			"configure_test": {Level: ERROR, Handlers: []string{"a", "y", "z"}},
+		},
+	}
+	err := Configure(failing)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), `handler "z": build failed`)
+	assert.Equal(t, WARN, l.GetLevel())
+	assert.Equal(t, 3, len(built))
+	built[0].AssertNumberOfCalls(t, "Close", 0)
+	built[2].AssertNumberOfCalls(t, "Close", 1)
+	built = built[:2]
+
+	assert.NotNil(t, Configure(&Config{Loggers: map[string]LoggerConfig{"x": {Handlers: []string{"missing"}}}}))
+
+	// the next Config carries "a" over, replaces "b" and releases it
+	next := &Config{
+		Handlers: map[string]HandlerConfig{
+			"a": {Type: "configure_test"},
+			"b": {Type: "configure_test", Formatter: "json"},
+		},
+		Loggers: map[string]LoggerConfig{
+			"configure_test": {Level: ERROR, Handlers: []string{"a", "b"}},
+		},
+	}
+	assert.Nil(t, Configure(next))
+	assert.Equal(t, 3, len(built))
+
+	assert.Equal(t, ERROR, l.GetLevel())
+	assert.False(t, l.GetPropagate())
+	assert.Equal(t, 0, len(l.Tags()))
+	assert.Equal(t, []Handler{built[0], built[2]}, l.Handlers())
+	assert.Equal(t, INFO, GetLogger("configure_test.child").GetLevel())
+
+	built[0].AssertNumberOfCalls(t, "Close", 0)
+	built[1].AssertNumberOfCalls(t, "Flush", 1)
+	built[1].AssertNumberOfCalls(t, "Close", 1)
+
+	// an unchanged Config keeps everything, including the live settings
+	same := *next
+	before := l.load()
+	assert.Nil(t, Configure(&same))
+	assert.Equal(t, 3, len(built))
+	assert.True(t, before == l.load())
+
+	// the Config of a Logger changed by hand is applied again
+	l.SetLevel(DEBUG)
+	assert.Nil(t, Configure(next))
+	assert.Equal(t, ERROR, l.GetLevel())
+
+	assert.Nil(t, Configure(&Config{}))
+	for _, h := range built {
+		h.AssertNumberOfCalls(t, "Flush", 1)
+		h.AssertNumberOfCalls(t, "Close", 1)
+	}
+	eh.AssertExpectations(t)
+}
+
+func TestLoggerConfigEqual(t *testing.T) {
+	h := &MockHandler{}
+	a := newLoggerConfig()
+	a.handlers = []Handler{h}
+	a.tags["k"] = "v"
+
+	b := a.clone()
+	assert.True(t, a.equal(b))
+
+	b.handlers = []Handler{&MockHandler{}}
+	assert.False(t, a.equal(b))
+
+	b = a.clone()
+	b.caller[ERROR-_MIN] = true
+	assert.False(t, a.equal(b))
+
+	b = a.clone()
+	b.tags = copyMap(a.tags)
+	b.tags["k"] = "w"
+	assert.False(t, a.equal(b))
+
+	b = a.clone()
+	b.errorHandler = nil
+	assert.False(t, a.equal(b))
+
+	assert.False(t, sameHandler(funcHandler(nil), funcHandler(nil)))
+}
+
+type funcHandler func()
+
+func TestLoadConfigFile(t *testing.T) {
+	defer clear()
+	defer func() {
+		assert.Nil(t, Configure(&Config{}))
+	}()
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "log.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte("loggers:\n  load_test:\n    level: ERROR\n"), 0666))
+	assert.Nil(t, LoadConfigFile(path))
+	assert.Equal(t, ERROR, GetLogger("load_test").GetLevel())
+
+	path = filepath.Join(dir, "log.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"loggers": {"load_test": {"level": "WARN"}}}`), 0666))
+	assert.Nil(t, LoadConfigFile(path))
+	assert.Equal(t, WARN, GetLogger("load_test").GetLevel())
+
+	err := LoadConfigFile(filepath.Join(dir, "missing.json"))
+	assert.True(t, os.IsNotExist(err))
+
+	path = filepath.Join(dir, "log.conf")
+	assert.Nil(t, os.WriteFile(path, []byte(""), 0666))
+	err = LoadConfigFile(path)
+	assert.True(t, strings.Contains(err.Error(), "unknown config format"))
+}
+
+// configHandler checks that the Handlers of an Event belong to the same Config.
+type configHandler struct {
+	name     string
+	last     *string
+	mismatch *int64
+}
+
+func (c *configHandler) Handle(_ *Event) (bool, error) {
+	// widens the window between the Handlers of the Event
+	runtime.Gosched()
+
+	if *c.last == "" {
+		*c.last = c.name
+	} else {
+		if *c.last != c.name {
+			atomic.AddInt64(c.mismatch, 1)
+		}
+		*c.last = ""
+	}
+	return true, nil
+}
+
+func (c *configHandler) Flush() error {
+	return nil
+}
+
+func (c *configHandler) Close() error {
+	return nil
+}
+
+func TestConfigureAtomic(t *testing.T) {
+	defer clear()
+	defer func() {
+		assert.Nil(t, Configure(&Config{}))
+	}()
+
+	// the Events are logged by a single goroutine
+	var last string
+	var mismatch int64
+	RegisterHandler("configure_atomic_test", func(cfg HandlerConfig) (Handler, error) {
+		return &configHandler{name: cfg.Formatter, last: &last, mismatch: &mismatch}, nil
+	})
+	config := func(name string) *Config {
+		return &Config{
+			Handlers: map[string]HandlerConfig{"h": {Type: "configure_atomic_test", Formatter: name}},
+			Loggers: map[string]LoggerConfig{
+				"configure_atomic_test":       {Handlers: []string{"h"}},
+				"configure_atomic_test.child": {Handlers: []string{"h"}, Propagate: true},
+			},
+		}
+	}
+	assert.Nil(t, Configure(config("a")))
+
+	var logged int64
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		l := GetLogger("configure_atomic_test.child")
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				l.Info().Log()
+				atomic.AddInt64(&logged, 1)
+			}
+		}
+	}()
+
+	for i := 0; i < 1000 || atomic.LoadInt64(&logged) < 1000; i++ {
+		assert.Nil(t, Configure(config(string(rune('a'+i%2)))))
+		runtime.Gosched()
+	}
+	close(stop)
+	<-done
+
+	assert.Equal(t, int64(0), atomic.LoadInt64(&mismatch))
+}
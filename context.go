@@ -0,0 +1,55 @@
+package easylog
+
+import "context"
+
+type ctxKvsKey struct{}
+
+type ctxLoggerKey struct{}
+
+// WithContext returns a copy of ctx carrying kvs, after the kvs already carried by ctx.
+// kvs are alternating keys and values, a trailing key without value is paired with nil.
+// Use Event.Ctx to add them to an Event.
+func WithContext(ctx context.Context, kvs ...interface{}) context.Context {
+	if len(kvs) == 0 {
+		return ctx
+	}
+
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, nil)
+	}
+
+	// the carried slice is never modified, as it could be shared by other contexts
+	parent := ContextKvs(ctx)
+	merged := make([]interface{}, 0, len(parent)+len(kvs))
+	merged = append(merged, parent...)
+	merged = append(merged, kvs...)
+
+	return context.WithValue(ctx, ctxKvsKey{}, merged)
+}
+
+// ContextKvs returns the kvs carried by ctx, as alternating keys and values.
+// The returned slice is shared, do not modify it.
+func ContextKvs(ctx context.Context) []interface{} {
+	if ctx == nil {
+		return nil
+	}
+
+	kvs, _ := ctx.Value(ctxKvsKey{}).([]interface{})
+	return kvs
+}
+
+// NewContext returns a copy of ctx carrying the Logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxLoggerKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx, or the root Logger if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxLoggerKey{}).(*Logger); ok && l != nil {
+			return l
+		}
+	}
+
+	return GetRootLogger()
+}
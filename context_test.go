@@ -0,0 +1,56 @@
+package easylog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWithContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ctx, WithContext(ctx))
+	assert.Nil(t, ContextKvs(ctx))
+	assert.Nil(t, ContextKvs(nil))
+
+	c1 := WithContext(ctx, "request_id", "r1")
+	c2 := WithContext(c1, "user_id", 2, "dangling")
+	c3 := WithContext(c1, "trace_id", "t3")
+
+	assert.Equal(t, []interface{}{"request_id", "r1"}, ContextKvs(c1))
+	assert.Equal(t, []interface{}{"request_id", "r1", "user_id", 2, "dangling", nil}, ContextKvs(c2))
+	assert.Equal(t, []interface{}{"request_id", "r1", "trace_id", "t3"}, ContextKvs(c3))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, GetRootLogger(), FromContext(context.Background()))
+	assert.Equal(t, GetRootLogger(), FromContext(nil))
+
+	l := GetLogger("context_test")
+	ctx := NewContext(context.Background(), l)
+	assert.Equal(t, l, FromContext(ctx))
+	assert.Equal(t, l, FromContext(WithContext(ctx, "k", "v")))
+}
+
+func TestEventCtx(t *testing.T) {
+	l := newLogger()
+
+	ctx := WithContext(context.Background(), "request_id", "r1", "user_id", 2)
+
+	h := &MockHandler{}
+	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
+		return e.GetCtx() == ctx && e.GetKvs()["request_id"] == "r1" && e.GetKvs()["user_id"] == 2 &&
+			e.GetKvs()["k"] == "v"
+	})).Once().Return(true, nil)
+	l.AddHandler(h)
+
+	l.Info().Ctx(ctx).Kv("k", "v").Log()
+
+	h.AssertExpectations(t)
+
+	var e *Event
+	assert.NotPanics(t, func() {
+		e.Ctx(ctx).Log()
+	})
+}
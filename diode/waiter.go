@@ -39,7 +39,7 @@ func NewWaiter(d Diode, opts ...WaiterConfigOption) *Waiter {
 
 	go func() {
 		<-w.ctx.Done()
-		w.c.Broadcast()
+		w.broadcast()
 	}()
 
 	return w
@@ -49,7 +49,15 @@ func NewWaiter(d Diode, opts ...WaiterConfigOption) *Waiter {
 // to wake up any readers.
 func (w *Waiter) Set(data GenericDataType) {
 	w.Diode.Set(data)
+	w.broadcast()
+}
+
+// broadcast wakes up the readers. It holds the lock, so that a reader which has just
+// found the diode empty is waiting already, and does not miss the wake up.
+func (w *Waiter) broadcast() {
+	w.mu.Lock()
 	w.c.Broadcast()
+	w.mu.Unlock()
 }
 
 // Next returns the next data point on the wrapped diode. If there is not any
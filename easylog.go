@@ -1,16 +1,30 @@
 package easylog
 
+import "github.com/covine/easylog/internal/hook"
+
 var m *manager
 var root *Logger
 
 func init() {
-	m = &manager{
+	m = newManager()
+	root = m.root
+
+	hook.Isolate = func(tap interface{}) func() {
+		return m.isolate(tap.(Handler))
+	}
+	hook.StringsField = func(key string, s []string) interface{} {
+		return stringsField(key, s)
+	}
+}
+
+func newManager() *manager {
+	m := &manager{
 		loggerMap: make(map[string]*Logger),
 	}
 
-	root = m.getLogger("")
+	m.root = m.getLogger("")
 
-	m.root = root
+	return m
 }
 
 func GetLogger(name string) *Logger {
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -37,21 +38,21 @@ func TestGetRootLogger(t *testing.T) {
 
 	assert.NotNil(t, r.manager)
 	assert.Equal(t, "", r.Name())
-	assert.Nil(t, r.parent)
-	assert.False(t, r.propagate)
+	assert.Nil(t, r.getParent())
+	assert.False(t, r.load().propagate)
 	assert.False(t, r.placeholder)
-	assert.NotNil(t, r.tags)
-	assert.NotNil(t, r.kvs)
+	assert.NotNil(t, r.load().tags)
+	assert.NotNil(t, r.load().kvs)
 	assert.False(t, r.logCaller(DEBUG))
 	assert.False(t, r.logCaller(INFO))
 	assert.False(t, r.logCaller(WARN))
 	assert.False(t, r.logCaller(ERROR))
 	assert.False(t, r.logCaller(PANIC))
 	assert.False(t, r.logCaller(FATAL))
-	assert.Equal(t, INFO, r.level)
+	assert.Equal(t, INFO, r.load().level)
 	assert.NotNil(t, r.children)
-	assert.Equal(t, 0, len(r.handlers))
-	assert.Equal(t, nopErrorHandler{}, *(r.errorHandler.(*nopErrorHandler)))
+	assert.Equal(t, 0, len(r.load().handlers))
+	assert.Equal(t, nopErrorHandler{}, *(r.load().errorHandler.(*nopErrorHandler)))
 }
 
 func TestSetGetLevel(t *testing.T) {
@@ -144,7 +145,7 @@ func TestEnableDisableStack(t *testing.T) {
 func TestNopErrorHandler(t *testing.T) {
 	defer clear()
 
-	assert.Equal(t, nopErrorHandler{}, *(root.errorHandler.(*nopErrorHandler)))
+	assert.Equal(t, nopErrorHandler{}, *(root.load().errorHandler.(*nopErrorHandler)))
 
 	h := &MockHandler{}
 	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
@@ -168,13 +169,13 @@ func TestNopErrorHandler(t *testing.T) {
 func TestSetErrorHandler(t *testing.T) {
 	defer clear()
 
-	assert.Equal(t, nopErrorHandler{}, *(root.errorHandler.(*nopErrorHandler)))
+	assert.Equal(t, nopErrorHandler{}, *(root.load().errorHandler.(*nopErrorHandler)))
 
 	h := &MockErrorHandler{}
 
 	SetErrorHandler(h)
 
-	assert.Equal(t, h, root.errorHandler)
+	assert.Equal(t, h, root.load().errorHandler)
 }
 
 func TestTag(t *testing.T) {
@@ -224,41 +225,41 @@ func TestKv(t *testing.T) {
 func TestAddRemoveHandler(t *testing.T) {
 	defer clear()
 
-	assert.Equal(t, 0, len(root.handlers))
+	assert.Equal(t, 0, len(root.load().handlers))
 
 	AddHandler(nil)
-	assert.Equal(t, 0, len(root.handlers))
+	assert.Equal(t, 0, len(root.load().handlers))
 	RemoveHandler(nil)
-	assert.Equal(t, 0, len(root.handlers))
+	assert.Equal(t, 0, len(root.load().handlers))
 
 	n := NewNopHandler()
 
 	AddHandler(n)
-	assert.Equal(t, 1, len(root.handlers))
-	assert.Equal(t, n, root.handlers[0])
+	assert.Equal(t, 1, len(root.load().handlers))
+	assert.Equal(t, n, root.load().handlers[0])
 
 	AddHandler(NewNopHandler())
-	assert.Equal(t, 1, len(root.handlers))
-	assert.Equal(t, n, root.handlers[0])
+	assert.Equal(t, 1, len(root.load().handlers))
+	assert.Equal(t, n, root.load().handlers[0])
 
 	h := &MockHandler{}
 
 	AddHandler(h)
-	assert.Equal(t, 2, len(root.handlers))
-	assert.Equal(t, n, root.handlers[0])
-	assert.Equal(t, h, root.handlers[1])
+	assert.Equal(t, 2, len(root.load().handlers))
+	assert.Equal(t, n, root.load().handlers[0])
+	assert.Equal(t, h, root.load().handlers[1])
 
 	AddHandler(h)
-	assert.Equal(t, 2, len(root.handlers))
-	assert.Equal(t, n, root.handlers[0])
-	assert.Equal(t, h, root.handlers[1])
+	assert.Equal(t, 2, len(root.load().handlers))
+	assert.Equal(t, n, root.load().handlers[0])
+	assert.Equal(t, h, root.load().handlers[1])
 
 	RemoveHandler(h)
-	assert.Equal(t, 1, len(root.handlers))
-	assert.Equal(t, n, root.handlers[0])
+	assert.Equal(t, 1, len(root.load().handlers))
+	assert.Equal(t, n, root.load().handlers[0])
 
 	RemoveHandler(n)
-	assert.Equal(t, 0, len(root.handlers))
+	assert.Equal(t, 0, len(root.load().handlers))
 }
 
 func TestLog(t *testing.T) {
@@ -921,49 +922,49 @@ func TestGetLogger(t *testing.T) {
 		r := GetRootLogger()
 		assert.NotNil(t, r.manager)
 		assert.Equal(t, "", r.name)
-		assert.Nil(t, r.parent)
-		assert.False(t, r.propagate)
+		assert.Nil(t, r.getParent())
+		assert.False(t, r.load().propagate)
 		assert.False(t, r.placeholder)
-		assert.NotNil(t, r.tags)
-		assert.NotNil(t, r.kvs)
-		assert.Equal(t, DEBUG, r.level)
+		assert.NotNil(t, r.load().tags)
+		assert.NotNil(t, r.load().kvs)
+		assert.Equal(t, DEBUG, r.load().level)
 		assert.NotNil(t, r.children)
-		assert.NotNil(t, r.handlers)
+		assert.NotNil(t, r.load().handlers)
 		assert.Equal(t, 0, len(r.children))
 
 		empty := GetLogger("")
 		assert.True(t, r == empty)
-		assert.Equal(t, false, empty.propagate)
+		assert.Equal(t, false, empty.load().propagate)
 		assert.Equal(t, 0, len(empty.children))
 		assert.Equal(t, false, empty.placeholder)
 		assert.Equal(t, 0, len(r.children))
 
 		emptyEmpty := GetLogger(".")
-		assert.True(t, r == emptyEmpty.parent)
-		assert.Equal(t, false, emptyEmpty.propagate)
+		assert.True(t, r == emptyEmpty.getParent())
+		assert.Equal(t, false, emptyEmpty.load().propagate)
 		assert.Equal(t, 0, len(emptyEmpty.children))
 		assert.Equal(t, false, emptyEmpty.placeholder)
 		assert.Equal(t, 0, len(r.children))
 
 		emptyA := GetLogger(".a")
-		assert.True(t, root == emptyA.parent)
-		assert.Equal(t, false, emptyA.propagate)
+		assert.True(t, root == emptyA.getParent())
+		assert.Equal(t, false, emptyA.load().propagate)
 		assert.Equal(t, 0, len(emptyA.children))
 		assert.Equal(t, false, emptyA.placeholder)
 		assert.Equal(t, 0, len(r.children))
 
 		emptyEmptyA := GetLogger("..a")
-		assert.True(t, emptyEmpty == emptyEmptyA.parent)
-		assert.Equal(t, false, emptyEmptyA.propagate)
+		assert.True(t, emptyEmpty == emptyEmptyA.getParent())
+		assert.Equal(t, false, emptyEmptyA.load().propagate)
 		assert.Equal(t, 0, len(emptyEmptyA.children))
 		assert.Equal(t, false, emptyEmptyA.placeholder)
 		assert.Equal(t, 0, len(emptyEmpty.children))
 		assert.Equal(t, 0, len(r.children))
 
 		emptyEmptyAEmptyEmpty := GetLogger("..a..")
-		assert.True(t, emptyEmptyA == emptyEmptyAEmptyEmpty.parent)
-		assert.True(t, GetLogger("..a.") == emptyEmptyAEmptyEmpty.parent)
-		assert.True(t, GetLogger("..a.").parent == emptyEmptyA)
+		assert.True(t, emptyEmptyA == emptyEmptyAEmptyEmpty.getParent())
+		assert.True(t, GetLogger("..a.") == emptyEmptyAEmptyEmpty.getParent())
+		assert.True(t, GetLogger("..a.").getParent() == emptyEmptyA)
 		assert.Equal(t, false, GetLogger("..a.").placeholder)
 		assert.Equal(t, 0, len(GetLogger("..a.").children))
 		assert.Equal(t, 0, len(emptyEmptyA.children))
@@ -971,46 +972,46 @@ func TestGetLogger(t *testing.T) {
 		assert.Equal(t, 0, len(r.children))
 
 		a5 := GetLogger("a.b.c.d.e")
-		assert.True(t, r == a5.parent)
-		assert.True(t, GetLogger("a") == a5.parent)
-		assert.Equal(t, root, GetLogger("a").parent)
+		assert.True(t, r == a5.getParent())
+		assert.True(t, GetLogger("a") == a5.getParent())
+		assert.Equal(t, root, GetLogger("a").getParent())
 		assert.Equal(t, false, GetLogger("a").placeholder)
 		assert.Equal(t, 0, len(GetLogger("a").children))
 		assert.Equal(t, 0, len(r.children))
 
 		ab := GetLogger("a.b")
-		assert.True(t, ab == a5.parent)
-		assert.Equal(t, GetLogger("a"), GetLogger("a.b").parent)
+		assert.True(t, ab == a5.getParent())
+		assert.Equal(t, GetLogger("a"), GetLogger("a.b").getParent())
 		assert.Equal(t, 0, len(GetLogger("a").children))
 		assert.Equal(t, 0, len(GetLogger("a.b").children))
 		assert.Equal(t, 0, len(r.children))
 
 		a4 := GetLogger("a.b.c.d")
-		assert.True(t, a4.parent == ab)
+		assert.True(t, a4.getParent() == ab)
 
-		assert.True(t, a5.parent == a4)
+		assert.True(t, a5.getParent() == a4)
 
 		a7 := GetLogger("a.b.c.d.e.d.c")
-		assert.True(t, a7.parent == a5)
+		assert.True(t, a7.getParent() == a5)
 
 		b7 := GetLogger("b.b.c.d.e.d.c")
-		assert.True(t, b7.parent == root)
+		assert.True(t, b7.getParent() == root)
 
 		c5 := GetLogger("1.2.3.4.5")
-		assert.True(t, c5.parent == root)
+		assert.True(t, c5.getParent() == root)
 
 		c4 := GetLogger("1.2.3.4")
-		assert.True(t, c4.parent == root)
-		assert.True(t, c5.parent == c4)
+		assert.True(t, c4.getParent() == root)
+		assert.True(t, c5.getParent() == c4)
 
 		c1 := GetLogger("1")
 		c3 := GetLogger("1.2")
-		assert.True(t, c3.parent == c1)
-		assert.True(t, c4.parent == c3)
+		assert.True(t, c3.getParent() == c1)
+		assert.True(t, c4.getParent() == c3)
 
 		fakeRoot := GetLogger("root")
 		assert.True(t, r != fakeRoot)
-		assert.True(t, r == fakeRoot.parent)
+		assert.True(t, r == fakeRoot.getParent())
 	})
 
 	t.Run("get Logger concurrently", func(t *testing.T) {
@@ -1020,7 +1021,7 @@ func TestGetLogger(t *testing.T) {
 			go func(j int) {
 				defer w.Done()
 				l := GetLogger(strconv.Itoa(j))
-				assert.True(t, l.parent == GetRootLogger())
+				assert.True(t, l.getParent() == GetRootLogger())
 			}(i)
 		}
 		w.Wait()
@@ -1034,11 +1035,11 @@ func TestLogger(t *testing.T) {
 	ab := GetLogger("a.b")
 	abc := GetLogger("a.b.c")
 	abcd := GetLogger("a.b.c.d")
-	assert.True(t, root == a.parent)
-	assert.True(t, a == ab.parent)
-	assert.True(t, ab == abc.parent)
-	assert.True(t, abc == abcd.parent)
-	assert.True(t, nil == root.parent)
+	assert.True(t, root == a.getParent())
+	assert.True(t, a == ab.getParent())
+	assert.True(t, ab == abc.getParent())
+	assert.True(t, abc == abcd.getParent())
+	assert.True(t, nil == root.getParent())
 
 	assert.Equal(t, "a", a.Name())
 	assert.Equal(t, false, a.GetPropagate())
@@ -1055,7 +1056,7 @@ func TestLogger(t *testing.T) {
 	abcd.SetPropagate(true)
 	assert.Equal(t, true, abcd.GetPropagate())
 
-	assert.Equal(t, false, root.propagate)
+	assert.Equal(t, false, root.load().propagate)
 
 	t.Run("event -> <a.b.c.d> -> <a.b.c>", func(t *testing.T) {
 		m1 := &MockHandler{}
@@ -1145,3 +1146,49 @@ func TestLogger(t *testing.T) {
 
 	})
 }
+
+func TestIsolate(t *testing.T) {
+	defer clear()
+
+	a := GetLogger("isolate_test")
+	a.SetLevel(ERROR)
+	ah := NewNopHandler()
+	a.AddHandler(ah)
+	SetLevel(WARN)
+
+	var n int64
+	applied := m.applied
+	restore := m.isolate(&countHandler{n: &n})
+	assert.Equal(t, INFO, a.GetLevel())
+	assert.Empty(t, a.Handlers())
+	assert.Equal(t, INFO, GetLevel())
+
+	// every Logger is tapped, whatever its Handlers and propagation
+	a.Info().Log()
+	GetLogger("isolate_test.created").Info().Log()
+	Debug().Log()
+	assert.Equal(t, int64(2), atomic.LoadInt64(&n))
+
+	// the Handlers of a Config applied meanwhile are closed
+	h := &MockHandler{}
+	h.On("Flush").Return(nil).Once()
+	h.On("Close").Return(nil).Once()
+	RegisterHandler("isolate_test", func(HandlerConfig) (Handler, error) {
+		return h, nil
+	})
+	assert.Nil(t, Configure(&Config{
+		Handlers: map[string]HandlerConfig{"h": {Type: "isolate_test"}},
+		Loggers:  map[string]LoggerConfig{"isolate_test.created": {Level: DEBUG, Handlers: []string{"h"}}},
+	}))
+
+	restore()
+	h.AssertExpectations(t)
+
+	a.Info().Log()
+	assert.Equal(t, int64(2), atomic.LoadInt64(&n))
+	assert.Equal(t, ERROR, a.GetLevel())
+	assert.Equal(t, []Handler{ah}, a.Handlers())
+	assert.Equal(t, WARN, GetLevel())
+	assert.Equal(t, INFO, GetLogger("isolate_test.created").GetLevel())
+	assert.Same(t, applied, m.applied)
+}
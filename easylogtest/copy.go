@@ -0,0 +1,62 @@
+package easylogtest
+
+import "reflect"
+
+// deepCopy returns a copy of v, whose maps, slices and arrays are copied recursively, as are the exported
+// fields of its structs. The pointers, the channels, the funcs and the unexported fields are kept, so the
+// errors keep matching with errors.Is.
+func deepCopy(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	return deepCopyValue(reflect.ValueOf(v)).Interface()
+}
+
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			r.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return r
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return r
+	case reflect.Array:
+		r := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			r.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return r
+	case reflect.Struct:
+		r := reflect.New(v.Type()).Elem()
+		r.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := r.Field(i); f.CanSet() {
+				f.Set(deepCopyValue(v.Field(i)))
+			}
+		}
+		return r
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		r := reflect.New(v.Type()).Elem()
+		r.Set(deepCopyValue(v.Elem()))
+		return r
+	default:
+		return v
+	}
+}
@@ -0,0 +1,124 @@
+package easylogtest
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/covine/easylog"
+)
+
+// Matcher reports whether a Record matches, see Recorder.Find.
+type Matcher func(r Record) bool
+
+func matchAll(r Record, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Level matches the Records at level.
+func Level(level easylog.Level) Matcher {
+	return func(r Record) bool {
+		return r.Level == level
+	}
+}
+
+// MinLevel matches the Records at level or above.
+func MinLevel(level easylog.Level) Matcher {
+	return func(r Record) bool {
+		return r.Level >= level
+	}
+}
+
+// Logger matches the Records of the Logger named name, "" being the root one.
+func Logger(name string) Matcher {
+	return func(r Record) bool {
+		return r.Logger == name
+	}
+}
+
+// Msg matches the Records whose message is msg.
+func Msg(msg string) Matcher {
+	return func(r Record) bool {
+		return r.Msg == msg
+	}
+}
+
+// MsgContains matches the Records whose message contains s.
+func MsgContains(s string) Matcher {
+	return func(r Record) bool {
+		return strings.Contains(r.Msg, s)
+	}
+}
+
+// Kv matches the Records with a kv, or else a field, keyed by key with value, see Record.Value.
+// The integers are compared whatever their type, as the floats are.
+func Kv(key string, value interface{}) Matcher {
+	return func(r Record) bool {
+		v, ok := r.Value(key)
+		return ok && equal(v, value)
+	}
+}
+
+// HasKey matches the Records with a kv or a field keyed by key.
+func HasKey(key string) Matcher {
+	return func(r Record) bool {
+		_, ok := r.Value(key)
+		return ok
+	}
+}
+
+// Tag matches the Records with the tag key set to value.
+func Tag(key string, value interface{}) Matcher {
+	return func(r Record) bool {
+		v, ok := r.Tags[key]
+		return ok && equal(v, value)
+	}
+}
+
+// Err matches the Records with an error whose message contains s.
+func Err(s string) Matcher {
+	return func(r Record) bool {
+		return r.Err != nil && strings.Contains(r.Err.Error(), s)
+	}
+}
+
+// equal compares a and b deeply, the integers and the floats by value whatever their type.
+func equal(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isInt(va) && isInt(vb):
+		return toInt(va) == toInt(vb)
+	case isFloat(va) && isFloat(vb):
+		return va.Float() == vb.Float()
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFloat(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+// toInt returns the integer v, the unsigned ones above math.MaxInt64 wrapping.
+func toInt(v reflect.Value) int64 {
+	if v.CanInt() {
+		return v.Int()
+	}
+
+	return int64(v.Uint())
+}
@@ -0,0 +1,259 @@
+// Package easylogtest helps testing the code logging with easylog.
+//
+// Recorder is a Handler recording copies of the Events, to query them and assert what was logged.
+// Isolate resets the Loggers for a test, and records what any of them logs:
+//
+//	func TestServe(t *testing.T) {
+//		rec := easylogtest.Isolate(t)
+//
+//		serve()
+//
+//		rec.AssertLogged(t, easylogtest.Level(easylog.WARN), easylogtest.MsgContains("retry"))
+//	}
+package easylogtest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/covine/easylog"
+	"github.com/covine/easylog/internal/hook"
+)
+
+// Record is a deep copy of an Event, kept after the Event is put back to its pool.
+// The maps, slices and arrays of the values are copied, see deepCopy.
+type Record struct {
+	Logger string
+	Level  easylog.Level
+	Time   time.Time
+	Msg    string
+	Tags   map[interface{}]interface{}
+	Kvs    map[interface{}]interface{}
+	Fields []easylog.Field
+	Err    error
+	Extra  interface{}
+	Trace  easylog.TraceContext
+
+	// File, Line and Func are the caller, when the Logger records it.
+	File  string
+	Line  int
+	Func  string
+	Stack string
+}
+
+func newRecord(e *easylog.Event) Record {
+	r := Record{
+		Level: e.GetLevel(),
+		Time:  e.GetTime(),
+		Msg:   e.GetMsg(),
+		Tags:  copyMap(e.GetTags()),
+		Kvs:   copyMap(e.GetKvs()),
+		Err:   e.GetError(),
+		Extra: deepCopy(e.GetExtra()),
+		Trace: e.GetTrace(),
+		Stack: e.GetStack(),
+	}
+	if l := e.GetLogger(); l != nil {
+		r.Logger = l.Name()
+	}
+	if fields := e.GetFields(); len(fields) > 0 {
+		r.Fields = make([]easylog.Field, len(fields))
+		for i, f := range fields {
+			// the error and the time are kept
+			switch f.Type {
+			case easylog.StringsType:
+				f = hook.StringsField(f.Key, append([]string(nil), f.GetStrings()...)).(easylog.Field)
+			case easylog.ObjectType:
+				f.Interface = deepCopy(f.Interface)
+			}
+			r.Fields[i] = f
+		}
+	}
+	if c := e.GetCaller(); c.GetOK() {
+		r.File, r.Line, r.Func = c.GetFile(), c.GetLine(), c.GetFunc()
+	}
+
+	return r
+}
+
+func copyMap(m map[interface{}]interface{}) map[interface{}]interface{} {
+	if m == nil {
+		return nil
+	}
+
+	r := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		r[k] = deepCopy(v)
+	}
+
+	return r
+}
+
+// Value returns the value of the kv, or else of the field, keyed by key.
+func (r Record) Value(key string) (interface{}, bool) {
+	if v, ok := r.Kvs[key]; ok {
+		return v, true
+	}
+	for i := range r.Fields {
+		if r.Fields[i].Key == key {
+			return r.Fields[i].Value(), true
+		}
+	}
+
+	return nil, false
+}
+
+// String formats r on a line, as the level, the logger, the message and the sorted tags and kvs,
+// followed by the fields.
+func (r Record) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %q %q", r.Level, r.Logger, r.Msg)
+
+	for _, m := range []map[interface{}]interface{}{r.Tags, r.Kvs} {
+		keys := make([]string, 0, len(m))
+		for k, v := range m {
+			keys = append(keys, fmt.Sprintf(" %v=%v", k, v))
+		}
+		sort.Strings(keys)
+		b.WriteString(strings.Join(keys, ""))
+	}
+	for i := range r.Fields {
+		fmt.Fprintf(&b, " %s=%v", r.Fields[i].Key, r.Fields[i].Value())
+	}
+	if r.Err != nil {
+		fmt.Fprintf(&b, " error=%v", r.Err)
+	}
+
+	return b.String()
+}
+
+// Recorder is a Handler recording a copy of each Event, passing them to the next Handlers.
+// It's safe to use concurrently.
+type Recorder struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Handle(e *easylog.Event) (bool, error) {
+	rec := newRecord(e)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = append(r.records, rec)
+
+	return true, nil
+}
+
+func (r *Recorder) Flush() error {
+	return nil
+}
+
+func (r *Recorder) Close() error {
+	return nil
+}
+
+// Records returns the Records, in the order the Events were handled.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Record(nil), r.records...)
+}
+
+// Len returns the number of Records.
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.records)
+}
+
+// Reset drops the Records.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records = nil
+}
+
+// Find returns the Records matching all the matchers, in order.
+func (r *Recorder) Find(matchers ...Matcher) []Record {
+	var found []Record
+	for _, rec := range r.Records() {
+		if matchAll(rec, matchers) {
+			found = append(found, rec)
+		}
+	}
+
+	return found
+}
+
+// AssertLogged fails tb unless a Record matches all the matchers, and returns the first one.
+func (r *Recorder) AssertLogged(tb testing.TB, matchers ...Matcher) Record {
+	tb.Helper()
+
+	found := r.Find(matchers...)
+	if len(found) == 0 {
+		tb.Errorf("no matching event logged, got:%s", r.dump())
+		return Record{}
+	}
+
+	return found[0]
+}
+
+// AssertNotLogged fails tb if a Record matches all the matchers.
+func (r *Recorder) AssertNotLogged(tb testing.TB, matchers ...Matcher) {
+	tb.Helper()
+
+	if found := r.Find(matchers...); len(found) > 0 {
+		tb.Errorf("unexpected event logged: %s", found[0])
+	}
+}
+
+// AssertCount fails tb unless n Records match all the matchers.
+func (r *Recorder) AssertCount(tb testing.TB, n int, matchers ...Matcher) {
+	tb.Helper()
+
+	if found := r.Find(matchers...); len(found) != n {
+		tb.Errorf("%d matching events logged, want %d, got:%s", len(found), n, r.dump())
+	}
+}
+
+// dump formats the Records, one per line.
+func (r *Recorder) dump() string {
+	records := r.Records()
+	if len(records) == 0 {
+		return " none"
+	}
+
+	var b strings.Builder
+	for _, rec := range records {
+		b.WriteString("\n\t")
+		b.WriteString(rec.String())
+	}
+
+	return b.String()
+}
+
+// Isolate resets the settings of every Logger to the defaults until tb ends, so the Handlers and the settings
+// of a test don't leak into the others, and returns a Recorder of the Events logged meanwhile by any Logger,
+// whatever its Handlers and propagation. The Loggers stay the same, the ones held by package variables
+// included, and log at INFO and above unless the test sets their levels.
+// The tests calling it must not run in parallel.
+func Isolate(tb testing.TB) *Recorder {
+	tb.Helper()
+
+	r := NewRecorder()
+	tb.Cleanup(hook.Isolate(r))
+
+	return r
+}
@@ -0,0 +1,111 @@
+package easylogtest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// fakeTB records the failures of the assertions.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	rec := Isolate(t)
+	api, db := easylog.GetLogger("api"), easylog.GetLogger("db")
+	api.EnableCaller(easylog.WARN)
+	db.SetLevel(easylog.DEBUG)
+
+	ids, names, attrs := []int{1}, []string{"n"}, map[string][]string{"a": {"x"}}
+	api.Warn().Tag("dc", "eu").Kv("user", "u1").Kv("attrs", attrs).Int("n", 7).E(errors.New("boom")).
+		Logf("retry %d", 2)
+	db.Debug().Object("ids", ids).Strs("names", names).Log()
+	easylog.Info().Float64("f", 0.5).Log()
+
+	// deep copied, the Events are pooled and the values could be modified
+	ids[0], names[0], attrs["a"][0] = 2, "m", "y"
+
+	assert.Equal(t, 3, rec.Len())
+
+	r := rec.AssertLogged(t, Level(easylog.WARN), Logger("api"), MsgContains("retry"), Kv("user", "u1"),
+		Kv("n", 7), Tag("dc", "eu"), Err("boom"))
+	assert.Equal(t, "retry 2", r.Msg)
+	assert.True(t, strings.HasSuffix(r.File, "recorder_test.go"), r.File)
+	assert.Equal(t, "github.com/covine/easylog/easylogtest.TestRecorder", r.Func)
+	assert.Equal(t, `WARN "api" "retry 2" dc=eu attrs=map[a:[x]] user=u1 n=7 error=boom`, r.String())
+	assert.Equal(t, []easylog.Field{{Key: "n", Type: easylog.IntType, Integer: 7}}, r.Fields)
+
+	assert.Equal(t, 1, len(rec.Find(Kv("f", float32(0.5)), Logger(""))))
+	assert.Equal(t, 2, len(rec.Find(MinLevel(easylog.INFO))))
+	rec.AssertCount(t, 1, Msg(""), HasKey("ids"))
+	rec.AssertNotLogged(t, Level(easylog.ERROR))
+
+	tb := &fakeTB{TB: t}
+	rec.AssertLogged(tb, Kv("n", 8))
+	rec.AssertCount(tb, 2, Logger("db"))
+	rec.AssertNotLogged(tb, Logger("db"))
+	assert.Equal(t, 3, len(tb.errors))
+	assert.True(t, strings.HasPrefix(tb.errors[0],
+		"no matching event logged, got:\n\tWARN \"api\" \"retry 2\" dc=eu attrs=map[a:[x]] user=u1 n=7 error=boom\n\tDEBUG \"db\""),
+		tb.errors[0])
+	assert.True(t, strings.HasPrefix(tb.errors[1], "1 matching events logged, want 2, got:"), tb.errors[1])
+	assert.Equal(t, `unexpected event logged: DEBUG "db" "" ids=[1] names=[n]`, tb.errors[2])
+
+	rec.Reset()
+	assert.Equal(t, 0, rec.Len())
+	rec.AssertLogged(tb)
+	assert.Equal(t, "no matching event logged, got: none", tb.errors[3])
+}
+
+// pkgLogger is a Logger held by a package variable, got before the tests.
+var pkgLogger = easylog.GetLogger("easylogtest")
+
+func TestIsolate(t *testing.T) {
+	h := NewRecorder()
+	pkgLogger.AddHandler(h)
+	pkgLogger.SetLevel(easylog.ERROR)
+	defer pkgLogger.SetLevel(easylog.INFO)
+	defer pkgLogger.ResetHandler()
+
+	t.Run("isolated", func(t *testing.T) {
+		rec := Isolate(t)
+		assert.Empty(t, pkgLogger.Handlers())
+		assert.Equal(t, easylog.INFO, pkgLogger.GetLevel())
+
+		pkgLogger.Info().Log()
+		created := easylog.GetLogger("easylogtest.created")
+		created.SetLevel(easylog.DEBUG)
+		created.Debug().Log()
+
+		t.Run("nested", func(t *testing.T) {
+			nested := Isolate(t)
+			created.Info().Log()
+			nested.AssertCount(t, 1, Logger("easylogtest.created"))
+		})
+
+		created.Debug().Log()
+		rec.AssertCount(t, 1, Logger("easylogtest"))
+		rec.AssertCount(t, 2, Logger("easylogtest.created"), Level(easylog.DEBUG))
+		rec.AssertCount(t, 2, Logger("easylogtest.created"))
+	})
+
+	assert.Equal(t, []easylog.Handler{h}, pkgLogger.Handlers())
+	assert.Equal(t, easylog.ERROR, pkgLogger.GetLevel())
+	assert.Equal(t, easylog.INFO, easylog.GetLogger("easylogtest.created").GetLevel())
+
+	pkgLogger.Error().Log()
+	assert.Equal(t, 1, h.Len())
+}
@@ -1,8 +1,10 @@
 package easylog
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"runtime"
 	"sync"
 	"time"
@@ -16,13 +18,15 @@ var _bytesPool = &sync.Pool{
 	},
 }
 
-func newBytes() *Bytes {
+// NewBytes returns an empty Bytes from a pool, give it back with PutBytes once done.
+func NewBytes() *Bytes {
 	b := _bytesPool.Get().(*Bytes)
 	b.bytes = b.bytes[:0]
 	return b
 }
 
-func putBytes(b *Bytes) {
+// PutBytes gives b back to the pool, b must not be used afterwards.
+func PutBytes(b *Bytes) {
 	const max = 1 << 16
 	if cap(b.bytes) > max {
 		return
@@ -87,6 +91,10 @@ type Event struct {
 	e     error
 	extra interface{}
 
+	fields []Field
+	ctx    context.Context
+	trace  TraceContext
+
 	caller caller
 	stack  string
 }
@@ -109,6 +117,9 @@ func newEvent(logger *Logger, level Level) *Event {
 	r.msg = ""
 	r.e = nil
 	r.extra = nil
+	r.fields = r.fields[:0]
+	r.ctx = nil
+	r.trace = TraceContext{}
 
 	r.caller.ok = false
 	r.caller.pc = 0
@@ -121,6 +132,16 @@ func newEvent(logger *Logger, level Level) *Event {
 	return r
 }
 
+// NewEvent returns an Event of logger at level, which happened at t with msg, for the Handlers synthesizing
+// Events like summaries. It's given to Handlers directly instead of being logged, and should be put back with Put.
+func NewEvent(logger *Logger, level Level, t time.Time, msg string) *Event {
+	e := newEvent(logger, level)
+	e.time = t
+	e.msg = msg
+
+	return e
+}
+
 func (e *Event) Tag(k interface{}, v interface{}) *Event {
 	if e == nil {
 		return e
@@ -153,6 +174,178 @@ func (e *Event) GetKvs() map[interface{}]interface{} {
 	return e.kvs
 }
 
+// Str adds the field key with s as a string to the Event.
+func (e *Event) Str(key, s string) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: StringType, String: s})
+
+	return e
+}
+
+// Int adds the field key with i as an int to the Event.
+func (e *Event) Int(key string, i int) *Event {
+	return e.Int64(key, int64(i))
+}
+
+// Int64 adds the field key with i as an int64 to the Event.
+func (e *Event) Int64(key string, i int64) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: IntType, Integer: i})
+
+	return e
+}
+
+// Float64 adds the field key with f as a float64 to the Event.
+func (e *Event) Float64(key string, f float64) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: Float64Type, Integer: int64(math.Float64bits(f))})
+
+	return e
+}
+
+// Bool adds the field key with b as a bool to the Event.
+func (e *Event) Bool(key string, b bool) *Event {
+	if e == nil {
+		return e
+	}
+
+	var i int64
+	if b {
+		i = 1
+	}
+	e.fields = append(e.fields, Field{Key: key, Type: BoolType, Integer: i})
+
+	return e
+}
+
+// Dur adds the field key with d as a time.Duration to the Event.
+func (e *Event) Dur(key string, d time.Duration) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: DurationType, Integer: int64(d)})
+
+	return e
+}
+
+// Time adds the field key with t as a time.Time to the Event.
+// It allocates only for the times out of the years 1678 to 2262, which are boxed.
+func (e *Event) Time(key string, t time.Time) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, timeField(key, t))
+
+	return e
+}
+
+// Err adds the field key with err as an error to the Event.
+// Use E to set the error of the Event itself.
+func (e *Event) Err(key string, err error) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: ErrorType, Interface: err})
+
+	return e
+}
+
+// Bytes adds the field key with b as a []byte to the Event.
+// b is copied, so it could be reused after the call. The copy is the only allocation of the typed methods.
+func (e *Event) Bytes(key string, b []byte) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: BytesType, String: string(b)})
+
+	return e
+}
+
+// Strs adds the field key with s as a []string to the Event.
+// s is not copied, do not modify it after the call.
+func (e *Event) Strs(key string, s []string) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, stringsField(key, s))
+
+	return e
+}
+
+// Object adds the field key with an arbitrary value to the Event.
+// Formatters encode it with reflection, prefer the typed methods when possible.
+func (e *Event) Object(key string, obj interface{}) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.fields = append(e.fields, Field{Key: key, Type: ObjectType, Interface: obj})
+
+	return e
+}
+
+// GetFields returns the typed fields of the Event in insertion order.
+// The returned slice belongs to the Event, it's only valid until the Event is put back to the pool.
+func (e *Event) GetFields() []Field {
+	return e.fields
+}
+
+// Ctx attaches ctx to the Event and adds the kvs carried by ctx, see WithContext, and the trace context
+// carried by ctx, see TraceFromContext.
+func (e *Event) Ctx(ctx context.Context) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.ctx = ctx
+
+	if t, ok := TraceFromContext(ctx); ok {
+		e.trace = t
+	}
+
+	kvs := ContextKvs(ctx)
+	for i := 0; i+1 < len(kvs); i += 2 {
+		e.Kv(kvs[i], kvs[i+1])
+	}
+
+	return e
+}
+
+// GetCtx returns the context attached by Ctx, or nil.
+func (e *Event) GetCtx() context.Context {
+	return e.ctx
+}
+
+// Trace sets the trace context of the Event, instead of the one found by Ctx.
+func (e *Event) Trace(t TraceContext) *Event {
+	if e == nil {
+		return e
+	}
+
+	e.trace = t
+
+	return e
+}
+
+// GetTrace returns the trace context of the Event, which is not valid if there is none.
+func (e *Event) GetTrace() TraceContext {
+	return e.trace
+}
+
 func (e *Event) Attach(extra interface{}) *Event {
 	if e == nil {
 		return e
@@ -241,6 +434,9 @@ func (e *Event) Clone() *Event {
 	r.msg = e.msg
 	r.e = e.e
 	r.extra = e.extra
+	r.fields = append(r.fields[:0], e.fields...)
+	r.ctx = e.ctx
+	r.trace = e.trace
 
 	r.caller = e.caller
 
@@ -249,7 +445,19 @@ func (e *Event) Clone() *Event {
 	return r
 }
 
+// Put puts e back to its pool, dropping its references so the pool doesn't keep their values alive.
 func (e *Event) Put() {
+	e.logger = nil
+	e.tags = nil
+	e.kvs = nil
+	e.e = nil
+	e.extra = nil
+	for i := range e.fields {
+		e.fields[i] = Field{}
+	}
+	e.fields = e.fields[:0]
+	e.ctx = nil
+
 	_eventPool.Put(e)
 }
 
@@ -260,7 +468,7 @@ func (e *Event) log(msg string, skip int) {
 	if e.logger.logCaller(e.level) {
 		frame, ok := e.getCallerFrame(skip)
 		if !ok {
-			_ = e.logger.errorHandler.Handle(
+			_ = e.logger.GetErrorHandler().Handle(
 				errors.New(
 					fmt.Sprintf("[%v] [%v] [%v]:get caller failed\n", e.logger.name, e.level, e.time),
 				),
@@ -278,9 +486,12 @@ func (e *Event) log(msg string, skip int) {
 		e.stack = e.stacktrace(skip)
 	}
 
-	e.logger.handle(e)
+	// The Event is put back to the pool once handled, keep what couldEnd needs.
+	logger, level := e.logger, e.level
+
+	logger.handle(e)
 
-	e.logger.couldEnd(e.level, e.msg)
+	logger.couldEnd(level, msg)
 }
 
 func (e *Event) getCallerFrame(skip int) (frame runtime.Frame, ok bool) {
@@ -298,31 +509,59 @@ func (e *Event) getCallerFrame(skip int) (frame runtime.Frame, ok bool) {
 }
 
 func (e *Event) stacktrace(skip int) string {
-	bs := newBytes()
-	defer putBytes(bs)
+	// ignore callers and stacktrace
+	p, n := callers(skip + 2)
+	defer putPcs(p)
 
-	p := newPcs()
+	frames := runtime.CallersFrames(p.pcs[:n])
+	frame, more := frames.Next()
+
+	return formatStack(frame, more, frames)
+}
+
+// stacktraceFrom formats the goroutine stack from the frame of pc, a program counter returned by
+// runtime.Callers. If pc is not on the stack, only its frame is formatted.
+func (e *Event) stacktraceFrom(pc uintptr) string {
+	p, n := callers(2)
 	defer putPcs(p)
 
-	var numFrames int
-	for {
-		numFrames = runtime.Callers(skip+2, p.pcs)
-		if numFrames < len(p.pcs) {
+	pcs := []uintptr{pc}
+	for i := 0; i < n; i++ {
+		if p.pcs[i] == pc {
+			pcs = p.pcs[i:n]
 			break
 		}
+	}
+
+	frames := runtime.CallersFrames(pcs)
+	frame, more := frames.Next()
+
+	return formatStack(frame, more, frames)
+}
+
+// callers returns the program counters of the goroutine stack, skipping skip frames as runtime.Callers does.
+// The pcs are put back with putPcs.
+func callers(skip int) (*pcs, int) {
+	p := newPcs()
+	for {
+		n := runtime.Callers(skip+1, p.pcs)
+		if n < len(p.pcs) {
+			return p, n
+		}
 
 		p = &pcs{pcs: make([]uintptr, len(p.pcs)*2)}
 	}
+}
 
-	i := 0
-	frames := runtime.CallersFrames(p.pcs[:numFrames])
+// formatStack formats frame and the frames following it, as the function and file:line of each, indented.
+func formatStack(frame runtime.Frame, more bool, frames *runtime.Frames) string {
+	bs := NewBytes()
+	defer PutBytes(bs)
 
-	for {
-		frame, more := frames.Next()
+	for i := 0; ; i++ {
 		if i != 0 {
 			bs.AppendByte('\n')
 		}
-		i++
 		bs.AppendByte('\t')
 		bs.AppendString(frame.Function)
 		bs.AppendByte('\n')
@@ -333,6 +572,7 @@ func (e *Event) stacktrace(skip int) string {
 		if !more {
 			break
 		}
+		frame, more = frames.Next()
 	}
 
 	return bs.String()
@@ -1,9 +1,11 @@
 package easylog
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -108,3 +110,123 @@ func TestEventWithDeepStack(t *testing.T) {
 
 	assert.True(t, e.stack != "")
 }
+
+func TestEventFields(t *testing.T) {
+	l := newLogger()
+	e := newEvent(l, INFO)
+	defer e.Put()
+
+	now := time.Now()
+	err := errors.New("error")
+	b := []byte("bytes")
+
+	e.Str("str", "s").
+		Int("int", 1).
+		Int64("int64", 2).
+		Float64("float64", 1.5).
+		Bool("bool", true).
+		Dur("dur", time.Second).
+		Time("time", now).
+		Err("err", err).
+		Bytes("bytes", b).
+		Strs("strs", []string{"a", "b"}).
+		Object("object", map[string]int{"a": 1})
+
+	b[0] = 'B'
+
+	fields := e.GetFields()
+	assert.Equal(t, 11, len(fields))
+
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal(t, []string{
+		"str", "int", "int64", "float64", "bool", "dur", "time", "err", "bytes", "strs", "object",
+	}, keys)
+
+	assert.Equal(t, "s", fields[0].Value())
+	assert.Equal(t, int64(1), fields[1].GetInt())
+	assert.Equal(t, int64(2), fields[2].GetInt())
+	assert.Equal(t, 1.5, fields[3].GetFloat64())
+	assert.Equal(t, true, fields[4].GetBool())
+	assert.Equal(t, time.Second, fields[5].GetDuration())
+	assert.True(t, now.Equal(fields[6].GetTime()))
+	assert.Equal(t, err, fields[7].GetError())
+	assert.Equal(t, []byte("bytes"), fields[8].Value())
+	assert.Equal(t, []string{"a", "b"}, fields[9].GetStrings())
+	assert.Equal(t, map[string]int{"a": 1}, fields[10].Value())
+
+	ce := e.Clone()
+	defer ce.Put()
+	e.Str("more", "m")
+	assert.Equal(t, 11, len(ce.GetFields()))
+
+	var n *Event
+	assert.NotPanics(t, func() {
+		n.Str("k", "v").Int("k", 1).Int64("k", 1).Float64("k", 1).Bool("k", true).
+			Dur("k", 1).Time("k", now).Err("k", err).Bytes("k", b).Strs("k", nil).Object("k", nil).Log()
+	})
+}
+
+func TestEventFieldsReset(t *testing.T) {
+	e := newEvent(nil, INFO)
+	e.Str("k", "v").Err("err", errors.New("boom"))
+	e.extra = "extra"
+	e.ctx = context.Background()
+	e.Put()
+
+	// the pool doesn't keep the values alive
+	e = newEvent(nil, INFO)
+	defer e.Put()
+	assert.Equal(t, 0, len(e.GetFields()))
+	for _, f := range e.fields[:cap(e.fields)] {
+		assert.Equal(t, Field{}, f)
+	}
+	assert.Nil(t, e.extra)
+	assert.Nil(t, e.ctx)
+}
+
+func TestEventTimeField(t *testing.T) {
+	e := newEvent(nil, INFO)
+	defer e.Put()
+
+	loc := time.FixedZone("X", 3600)
+	for _, tm := range []time.Time{
+		{},
+		time.Date(1, 2, 3, 4, 5, 6, 7, time.UTC),
+		time.Date(3000, 1, 2, 3, 4, 5, 6, loc),
+		time.Date(2000, 1, 2, 3, 4, 5, 6, loc),
+		time.Now(),
+	} {
+		e.Time("t", tm)
+		got := e.GetFields()[len(e.GetFields())-1].GetTime()
+		assert.True(t, tm.Equal(got), got)
+		assert.Equal(t, tm.Location(), got.Location())
+	}
+}
+
+func TestEventStringsField(t *testing.T) {
+	e := newEvent(nil, INFO)
+	defer e.Put()
+
+	e.Strs("nil", nil).Strs("empty", []string{}).Strs("s", []string{"a", "b"})
+	fields := e.GetFields()
+	assert.Nil(t, fields[0].GetStrings())
+	assert.Equal(t, []string{}, fields[1].GetStrings())
+	assert.Equal(t, []string{"a", "b"}, fields[2].GetStrings())
+	assert.Equal(t, []string{"a", "b"}, fields[2].Value())
+}
+
+func TestEventFieldsAllocs(t *testing.T) {
+	e := newEvent(nil, INFO)
+	defer e.Put()
+
+	now, err, s := time.Now(), errors.New("boom"), []string{"a"}
+	allocs := testing.AllocsPerRun(100, func() {
+		e.fields = e.fields[:0]
+		e.Str("s", "v").Int("i", 1).Float64("f", 1).Bool("b", true).Dur("d", time.Second).Time("t", now).
+			Err("err", err).Strs("ss", s)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
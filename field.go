@@ -0,0 +1,125 @@
+package easylog
+
+import (
+	"math"
+	"time"
+	"unsafe"
+)
+
+type FieldType uint8
+
+const (
+	UnknownType FieldType = iota
+	StringType
+	IntType
+	Float64Type
+	BoolType
+	DurationType
+	TimeType
+	ErrorType
+	BytesType
+	StringsType
+	ObjectType
+)
+
+// Field is a typed key-value pair attached to an Event.
+// Only the members matching the Type are meaningful, so formatters could encode
+// the value without reflection.
+type Field struct {
+	Key  string
+	Type FieldType
+
+	// Integer holds the value of IntType, BoolType, DurationType, the bits of Float64Type, the unix nanoseconds
+	// of TimeType and the length of StringsType.
+	Integer int64
+	// String holds the value of StringType and BytesType.
+	String string
+	// Interface holds the value of ErrorType and ObjectType, the *time.Location of TimeType and the pointer to
+	// the first string of StringsType, none of them being boxed. The times out of the range of the unix
+	// nanoseconds are held as a time.Time without its monotonic clock reading.
+	Interface interface{}
+}
+
+// the range of the times held as unix nanoseconds
+var (
+	minUnixNanoTime = time.Unix(0, math.MinInt64)
+	maxUnixNanoTime = time.Unix(0, math.MaxInt64)
+)
+
+func timeField(key string, t time.Time) Field {
+	if t.Before(minUnixNanoTime) || t.After(maxUnixNanoTime) {
+		return Field{Key: key, Type: TimeType, Interface: t.Round(0)}
+	}
+
+	return Field{Key: key, Type: TimeType, Integer: t.UnixNano(), Interface: t.Location()}
+}
+
+func stringsField(key string, s []string) Field {
+	return Field{Key: key, Type: StringsType, Integer: int64(len(s)), Interface: unsafe.SliceData(s)}
+}
+
+func (f *Field) GetInt() int64 {
+	return f.Integer
+}
+
+func (f *Field) GetFloat64() float64 {
+	return math.Float64frombits(uint64(f.Integer))
+}
+
+func (f *Field) GetBool() bool {
+	return f.Integer == 1
+}
+
+func (f *Field) GetDuration() time.Duration {
+	return time.Duration(f.Integer)
+}
+
+func (f *Field) GetTime() time.Time {
+	switch v := f.Interface.(type) {
+	case *time.Location:
+		return time.Unix(0, f.Integer).In(v)
+	case time.Time:
+		return v
+	default:
+		return time.Time{}
+	}
+}
+
+func (f *Field) GetError() error {
+	err, _ := f.Interface.(error)
+	return err
+}
+
+func (f *Field) GetStrings() []string {
+	p, _ := f.Interface.(*string)
+	if p == nil {
+		return nil
+	}
+
+	return unsafe.Slice(p, f.Integer)
+}
+
+// Value returns the value of the Field as an interface{}.
+// It boxes the value, formatters should prefer the typed getters.
+func (f *Field) Value() interface{} {
+	switch f.Type {
+	case StringType:
+		return f.String
+	case IntType:
+		return f.Integer
+	case Float64Type:
+		return f.GetFloat64()
+	case BoolType:
+		return f.GetBool()
+	case DurationType:
+		return f.GetDuration()
+	case TimeType:
+		return f.GetTime()
+	case BytesType:
+		return []byte(f.String)
+	case StringsType:
+		return f.GetStrings()
+	default:
+		return f.Interface
+	}
+}
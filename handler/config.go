@@ -0,0 +1,817 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/covine/easylog"
+	"github.com/covine/easylog/writer"
+)
+
+var (
+	formattersMu sync.RWMutex
+	formatters   = map[string]Formatter{
+		"std":    StdFormatter,
+		"json":   JsonFormatter,
+		"logfmt": LogfmtFormatter,
+		"text":   mustTextFormatter(WithTextColor(false)),
+	}
+)
+
+// RegisterFormatter makes a Formatter available to easylog.Config by name.
+// It overrides the Formatter registered for name, if any.
+func RegisterFormatter(name string, f Formatter) {
+	formattersMu.Lock()
+	defer formattersMu.Unlock()
+
+	formatters[name] = f
+}
+
+func formatterByName(name string) (Formatter, error) {
+	if name == "" {
+		name = "std"
+	}
+
+	formattersMu.RLock()
+	defer formattersMu.RUnlock()
+
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown formatter %q", name)
+	}
+
+	return f, nil
+}
+
+// The builtin types of easylog.Config:
+//
+//	handlers:
+//	  writer: a WriterHandler writing to the declared writer, stderr by default, options: flush_interval,
+//	    flush_level
+//	  stdout, stderr: a WriterHandler writing to stdout or stderr, options: as writer
+//	  ring: a RingBufferHandler writing to the declared writer, options: size, pull_interval,
+//	    overflow (drop_oldest, drop_newest, block, block_timeout), block_timeout, retries, retry_backoff,
+//	    close_timeout, flush_interval, flush_level
+//	  syslog: a SyslogHandler, options: network, address, format (rfc5424, rfc3164), framing (octet_counting,
+//	    newline), facility, hostname, app_name, procid, msgid
+//	  http: an HTTPBatchHandler, formatting as json by default, options: url, encoder (json, loki,
+//	    elasticsearch), labels (the tags labeling the loki streams), index, gzip, batch_count, batch_bytes,
+//	    max_age, max_buffered, retries, retry_backoff, close_timeout
+//	  otlp: an HTTPBatchHandler exporting to an OTLP/HTTP endpoint, options: url, encoding (protobuf, json),
+//	    resource (the attributes of the resource), and the options of http from gzip
+//	error handlers:
+//	  writer, stdout, stderr: a WriterErrorHandler
+//	writers:
+//	  stdout, stderr, file, rolling
+//
+// A positive buffer_size buffers the writer with a writer.BufWriter.
+//
+// The file and rolling writers are shared by path: the Handlers of successive Configs writing to the same file
+// use the same writer, so a reload never has two writers appending to, or rotating, the file. A writer declared
+// with other settings replaces the shared one for all of them once its Config is applied, see openSharedFile.
+func init() {
+	easylog.RegisterHandler("writer", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		return newWriterHandlerFromConfig(cfg)
+	})
+	easylog.RegisterHandler("stdout", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		cfg.Writer = &easylog.WriterConfig{Type: "stdout"}
+		return newWriterHandlerFromConfig(cfg)
+	})
+	easylog.RegisterHandler("stderr", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		cfg.Writer = &easylog.WriterConfig{Type: "stderr"}
+		return newWriterHandlerFromConfig(cfg)
+	})
+	easylog.RegisterHandler("ring", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		return newRingBufferHandlerFromConfig(cfg)
+	})
+
+	easylog.RegisterHandler("syslog", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		return newSyslogHandlerFromConfig(cfg)
+	})
+
+	easylog.RegisterHandler("http", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		return newHTTPBatchHandlerFromConfig(cfg)
+	})
+	easylog.RegisterHandler("otlp", func(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+		return newOTLPHandlerFromConfig(cfg)
+	})
+
+	easylog.RegisterErrorHandler("writer", func(cfg easylog.HandlerConfig) (easylog.ErrorHandler, error) {
+		return newWriterErrorHandlerFromConfig(cfg)
+	})
+	easylog.RegisterErrorHandler("stdout", func(cfg easylog.HandlerConfig) (easylog.ErrorHandler, error) {
+		cfg.Writer = &easylog.WriterConfig{Type: "stdout"}
+		return newWriterErrorHandlerFromConfig(cfg)
+	})
+	easylog.RegisterErrorHandler("stderr", func(cfg easylog.HandlerConfig) (easylog.ErrorHandler, error) {
+		cfg.Writer = &easylog.WriterConfig{Type: "stderr"}
+		return newWriterErrorHandlerFromConfig(cfg)
+	})
+
+	easylog.RegisterConfigHook(commitSharedFiles)
+}
+
+func newWriterHandlerFromConfig(cfg easylog.HandlerConfig) (*WriterHandler, error) {
+	if err := checkOptions(cfg.Options, "flush_interval", "flush_level"); err != nil {
+		return nil, err
+	}
+
+	interval, err := durationOption(cfg.Options, "flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	opts := []WriterHandlerOption{WithWriterFlushInterval(interval)}
+
+	level, ok, err := levelOption(cfg.Options, "flush_level")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts = append(opts, WithWriterFlushLevel(level))
+	}
+
+	f, err := formatterByName(cfg.Formatter)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := newWriterFromConfig(cfg.Writer, cfg.BufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Level != nil {
+		opts = append(opts, WithWriterLevel(*cfg.Level))
+	}
+
+	return NewWriterHandler(w, f, opts...), nil
+}
+
+func newRingBufferHandlerFromConfig(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+	err := checkOptions(cfg.Options,
+		"size", "pull_interval", "overflow", "block_timeout", "retries", "retry_backoff", "close_timeout",
+		"flush_interval", "flush_level",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := formatterByName(cfg.Formatter)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := intOption(cfg.Options, "size", 1024)
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("option size: must be positive")
+	}
+
+	interval, err := durationOption(cfg.Options, "pull_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []RingOption
+
+	name, err := stringOption(cfg.Options, "overflow", OverflowDropOldest.String())
+	if err != nil {
+		return nil, err
+	}
+	overflow, err := ParseOverflow(name)
+	if err != nil {
+		return nil, fmt.Errorf("option overflow: %w", err)
+	}
+	opts = append(opts, WithRingOverflow(overflow))
+
+	if _, ok := cfg.Options["block_timeout"]; ok {
+		if overflow != OverflowBlockTimeout {
+			return nil, fmt.Errorf("option block_timeout: requires overflow %s", OverflowBlockTimeout)
+		}
+		timeout, err := durationOption(cfg.Options, "block_timeout", defaultRingBlockTimeout)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithRingBlockTimeout(timeout))
+	}
+
+	retries, err := intOption(cfg.Options, "retries", 0)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := durationOption(cfg.Options, "retry_backoff", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithRingRetry(retries, backoff))
+
+	closeTimeout, err := durationOption(cfg.Options, "close_timeout", defaultRingCloseTimeout)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithRingCloseTimeout(closeTimeout))
+
+	flushInterval, err := durationOption(cfg.Options, "flush_interval", 0)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithRingFlushInterval(flushInterval))
+
+	flushLevel, ok, err := levelOption(cfg.Options, "flush_level")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		opts = append(opts, WithRingFlushLevel(flushLevel))
+	}
+
+	w, err := newWriterFromConfig(cfg.Writer, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	bw, err := writer.NewBufWriter(cfg.BufferSize, w)
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	var h easylog.Handler = NewRingBufferHandler(bw, f, size, nil, interval, opts...)
+	if cfg.Level != nil {
+		h = NewFiltered(h, MinLevel(*cfg.Level))
+	}
+
+	return h, nil
+}
+
+func newSyslogHandlerFromConfig(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+	keys := []string{"network", "address", "format", "framing", "facility", "hostname", "app_name", "procid", "msgid"}
+	if err := checkOptions(cfg.Options, keys...); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, k := range keys {
+		v, err := stringOption(cfg.Options, k, "")
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+
+	var opts []SyslogOption
+	switch values["format"] {
+	case "", "rfc5424":
+	case "rfc3164":
+		opts = append(opts, WithSyslogFormat(SyslogRFC3164))
+	default:
+		return nil, fmt.Errorf("option format: unknown format %q", values["format"])
+	}
+	switch values["framing"] {
+	case "":
+	case "octet_counting":
+		opts = append(opts, WithSyslogFraming(SyslogOctetCounting))
+	case "newline":
+		opts = append(opts, WithSyslogFraming(SyslogNewline))
+	default:
+		return nil, fmt.Errorf("option framing: unknown framing %q", values["framing"])
+	}
+	if values["facility"] != "" {
+		facility, err := ParseFacility(values["facility"])
+		if err != nil {
+			return nil, fmt.Errorf("option facility: %w", err)
+		}
+		opts = append(opts, WithSyslogFacility(facility))
+	}
+	if values["hostname"] != "" {
+		opts = append(opts, WithSyslogHostname(values["hostname"]))
+	}
+	if values["app_name"] != "" {
+		opts = append(opts, WithSyslogAppName(values["app_name"]))
+	}
+	if values["procid"] != "" {
+		opts = append(opts, WithSyslogProcID(values["procid"]))
+	}
+	if values["msgid"] != "" {
+		opts = append(opts, WithSyslogMsgID(values["msgid"]))
+	}
+
+	sh, err := NewSyslogHandler(values["network"], values["address"], opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var h easylog.Handler = sh
+	if cfg.Level != nil {
+		h = NewFiltered(h, MinLevel(*cfg.Level))
+	}
+
+	return h, nil
+}
+
+func newHTTPBatchHandlerFromConfig(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+	if err := checkOptions(cfg.Options, append(httpBatchOptions, "url", "encoder", "labels", "index")...); err != nil {
+		return nil, err
+	}
+
+	name := cfg.Formatter
+	if name == "" {
+		name = "json"
+	}
+	f, err := formatterByName(name)
+	if err != nil {
+		return nil, err
+	}
+	opts := []HTTPBatchOption{WithHTTPBatchFormatter(f)}
+
+	rawURL, err := stringOption(cfg.Options, "url", "")
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := stringOption(cfg.Options, "encoder", "json")
+	if err != nil {
+		return nil, err
+	}
+	labels, err := stringOption(cfg.Options, "labels", "")
+	if err != nil {
+		return nil, err
+	}
+	index, err := stringOption(cfg.Options, "index", "")
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoder {
+	case "json":
+	case "loki":
+		var names []string
+		for _, name := range strings.Split(labels, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		opts = append(opts, WithHTTPBatchEncoder(NewLokiEncoder(names...)))
+	case "elasticsearch":
+		opts = append(opts, WithHTTPBatchEncoder(NewElasticsearchEncoder(index)))
+	default:
+		return nil, fmt.Errorf("option encoder: unknown encoder %q", encoder)
+	}
+
+	batchOpts, err := httpBatchOptionsFromConfig(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, batchOpts...)
+
+	hh, err := NewHTTPBatchHandler(rawURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var h easylog.Handler = hh
+	if cfg.Level != nil {
+		h = NewFiltered(h, MinLevel(*cfg.Level))
+	}
+
+	return h, nil
+}
+
+// httpBatchOptions are the options of the HTTPBatchHandlers, as the http and otlp types.
+var httpBatchOptions = []string{
+	"gzip", "batch_count", "batch_bytes", "max_age", "max_buffered", "retries", "retry_backoff", "close_timeout",
+}
+
+func httpBatchOptionsFromConfig(options map[string]interface{}) ([]HTTPBatchOption, error) {
+	var opts []HTTPBatchOption
+
+	gzip, err := boolOption(options, "gzip", false)
+	if err != nil {
+		return nil, err
+	}
+	if gzip {
+		opts = append(opts, WithHTTPBatchGzip())
+	}
+
+	count, err := intOption(options, "batch_count", defaultHTTPBatchCount)
+	if err != nil {
+		return nil, err
+	}
+	batchBytes, err := intOption(options, "batch_bytes", defaultHTTPBatchBytes)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithHTTPBatchSize(count, batchBytes))
+
+	maxAge, err := durationOption(options, "max_age", defaultHTTPBatchMaxAge)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithHTTPBatchMaxAge(maxAge))
+
+	maxBuffered, err := intOption(options, "max_buffered", defaultHTTPBatchMaxBuffered)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithHTTPBatchMaxBuffered(maxBuffered))
+
+	retries, err := intOption(options, "retries", defaultHTTPBatchRetries)
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := durationOption(options, "retry_backoff", defaultHTTPBatchBackoff)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithHTTPBatchRetry(retries, backoff))
+
+	closeTimeout, err := durationOption(options, "close_timeout", defaultHTTPBatchCloseTimeout)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, WithHTTPBatchCloseTimeout(closeTimeout))
+
+	return opts, nil
+}
+
+func newOTLPHandlerFromConfig(cfg easylog.HandlerConfig) (easylog.Handler, error) {
+	if err := checkOptions(cfg.Options, append(httpBatchOptions, "url", "encoding", "resource")...); err != nil {
+		return nil, err
+	}
+
+	rawURL, err := stringOption(cfg.Options, "url", "")
+	if err != nil {
+		return nil, err
+	}
+
+	var encOpts []OTLPOption
+	encoding, err := stringOption(cfg.Options, "encoding", "protobuf")
+	if err != nil {
+		return nil, err
+	}
+	switch encoding {
+	case "protobuf":
+	case "json":
+		encOpts = append(encOpts, WithOTLPEncoding(OTLPJSON))
+	default:
+		return nil, fmt.Errorf("option encoding: unknown encoding %q", encoding)
+	}
+
+	if v, ok := cfg.Options["resource"]; ok {
+		resource, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("option resource: %v is not a map", v)
+		}
+		encOpts = append(encOpts, WithOTLPResource(resource))
+	}
+
+	opts, err := httpBatchOptionsFromConfig(cfg.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	oh, err := NewOTLPHandler(rawURL, NewOTLPEncoder(encOpts...), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var h easylog.Handler = oh
+	if cfg.Level != nil {
+		h = NewFiltered(h, MinLevel(*cfg.Level))
+	}
+
+	return h, nil
+}
+
+func newWriterErrorHandlerFromConfig(cfg easylog.HandlerConfig) (*WriterErrorHandler, error) {
+	if err := checkOptions(cfg.Options); err != nil {
+		return nil, err
+	}
+
+	w, err := newWriterFromConfig(cfg.Writer, cfg.BufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterErrorHandler(w), nil
+}
+
+func newWriterFromConfig(cfg *easylog.WriterConfig, bufferSize int) (writer.Writer, error) {
+	if cfg == nil {
+		cfg = &easylog.WriterConfig{Type: "stderr"}
+	}
+
+	var w writer.Writer
+	switch cfg.Type {
+	case "", "stderr":
+		w = stdWriter{writer.NewStderrWriter()}
+	case "stdout":
+		w = stdWriter{writer.NewStdoutWriter()}
+	case "file", "rolling":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("writer %q: missing path", cfg.Type)
+		}
+		sw, err := openSharedFile(cfg)
+		if err != nil {
+			return nil, err
+		}
+		w = sw
+	default:
+		return nil, fmt.Errorf("unknown writer %q", cfg.Type)
+	}
+
+	if bufferSize <= 0 {
+		return w, nil
+	}
+
+	bw, err := writer.NewBufWriter(bufferSize, w)
+	if err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	return bw, nil
+}
+
+// newFileWriter opens the file or rolling writer declared by cfg.
+func newFileWriter(cfg *easylog.WriterConfig) (writer.Writer, error) {
+	if cfg.Type == "file" {
+		return writer.NewFileWriter(cfg.Path)
+	}
+
+	return writer.NewRollingFileWriter(
+		cfg.Path,
+		writer.WithMaxSize(cfg.MaxSize),
+		writer.WithMaxBackups(cfg.MaxBackups),
+		writer.WithMaxAge(time.Duration(cfg.MaxAge)),
+		writer.WithInterval(time.Duration(cfg.Interval)),
+		writer.WithCompress(cfg.Compress),
+	)
+}
+
+var (
+	sharedFilesMu sync.Mutex
+	// sharedFiles are the open file and rolling writers, by absolute path
+	sharedFiles = make(map[string]*sharedFile)
+)
+
+// sharedFile is a file or rolling writer shared by the configured Handlers writing to its path.
+type sharedFile struct {
+	path string
+	// refs counts the open sharedWriters, it's guarded by sharedFilesMu
+	refs int
+
+	// mu guards the replacement of w, the writers being safe for concurrent use
+	mu  sync.RWMutex
+	cfg easylog.WriterConfig
+	w   writer.Writer
+
+	// staged replaces w once the Config declaring it is applied, it's guarded by sharedFilesMu
+	staged *stagedFile
+}
+
+// stagedFile is a writer open with other settings than the writer of a sharedFile.
+type stagedFile struct {
+	cfg easylog.WriterConfig
+	w   writer.Writer
+}
+
+// sharedWriter is a reference to a sharedFile, closing the file with the last reference.
+type sharedWriter struct {
+	f      *sharedFile
+	closed bool
+}
+
+// openSharedFile returns a reference to the writer of the path of cfg, opening it if needed. If the writer is
+// open with other settings, a writer with the settings of cfg is staged to replace it when the Config being
+// built is applied, see commitSharedFiles.
+func openSharedFile(cfg *easylog.WriterConfig) (*sharedWriter, error) {
+	path, err := filepath.Abs(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedFilesMu.Lock()
+	defer sharedFilesMu.Unlock()
+
+	f, ok := sharedFiles[path]
+	if !ok {
+		w, err := newFileWriter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		f = &sharedFile{path: path, cfg: *cfg, w: w}
+		sharedFiles[path] = f
+	} else if !reflect.DeepEqual(f.cfg, *cfg) && (f.staged == nil || !reflect.DeepEqual(f.staged.cfg, *cfg)) {
+		w, err := newFileWriter(cfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.staged != nil {
+			_ = f.staged.w.Close()
+		}
+		f.staged = &stagedFile{cfg: *cfg, w: w}
+	}
+
+	f.refs++
+
+	return &sharedWriter{f: f}, nil
+}
+
+func (s *sharedWriter) Write(p []byte) (int, error) {
+	s.f.mu.RLock()
+	defer s.f.mu.RUnlock()
+
+	return s.f.w.Write(p)
+}
+
+func (s *sharedWriter) Flush() error {
+	return s.f.Flush()
+}
+
+// Close releases the reference, the file is closed with the last one.
+func (s *sharedWriter) Close() error {
+	sharedFilesMu.Lock()
+	defer sharedFilesMu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.f.refs--; s.f.refs > 0 {
+		return s.f.Flush()
+	}
+	delete(sharedFiles, s.f.path)
+
+	if s.f.staged != nil {
+		_ = s.f.staged.w.Close()
+		s.f.staged = nil
+	}
+
+	return s.f.w.Close()
+}
+
+// commitSharedFiles replaces the writers of the shared files by their staged writers when the Config built is
+// applied, otherwise it closes the staged writers.
+func commitSharedFiles(applied bool) {
+	sharedFilesMu.Lock()
+	defer sharedFilesMu.Unlock()
+
+	for _, f := range sharedFiles {
+		if f.staged == nil {
+			continue
+		}
+		staged := f.staged
+		f.staged = nil
+
+		if !applied {
+			_ = staged.w.Close()
+			continue
+		}
+
+		f.mu.Lock()
+		prev := f.w
+		f.cfg, f.w = staged.cfg, staged.w
+		f.mu.Unlock()
+
+		_ = prev.Close()
+	}
+}
+
+// Flush flushes the writer of f.
+func (f *sharedFile) Flush() error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return f.w.Flush()
+}
+
+// stdWriter keeps the process streams open when a configured Handler is closed,
+// as they outlive any configuration.
+type stdWriter struct {
+	writer.Writer
+}
+
+func (s stdWriter) Close() error {
+	return nil
+}
+
+func checkOptions(opts map[string]interface{}, allowed ...string) error {
+	var unknown []string
+	for k := range opts {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, k)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown options %s", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+func intOption(opts map[string]interface{}, key string, def int) (int, error) {
+	v, ok := opts[key]
+	if !ok {
+		return def, nil
+	}
+
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case uint64:
+		return int(n), nil
+	case float64:
+		if n == math.Trunc(n) {
+			return int(n), nil
+		}
+	}
+
+	return 0, fmt.Errorf("option %s: %v is not an integer", key, v)
+}
+
+func stringOption(opts map[string]interface{}, key string, def string) (string, error) {
+	v, ok := opts[key]
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("option %s: %v is not a string", key, v)
+	}
+
+	return s, nil
+}
+
+func boolOption(opts map[string]interface{}, key string, def bool) (bool, error) {
+	v, ok := opts[key]
+	if !ok {
+		return def, nil
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("option %s: %v is not a boolean", key, v)
+	}
+
+	return b, nil
+}
+
+// levelOption returns the level named by the option key, and whether it's set.
+func levelOption(opts map[string]interface{}, key string) (easylog.Level, bool, error) {
+	if _, ok := opts[key]; !ok {
+		return 0, false, nil
+	}
+
+	s, err := stringOption(opts, key, "")
+	if err != nil {
+		return 0, false, err
+	}
+
+	level, err := easylog.ParseLevel(s)
+	if err != nil {
+		return 0, false, fmt.Errorf("option %s: %w", key, err)
+	}
+
+	return level, true, nil
+}
+
+func durationOption(opts map[string]interface{}, key string, def time.Duration) (time.Duration, error) {
+	v, ok := opts[key]
+	if !ok {
+		return def, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("option %s: %v is not a duration", key, v)
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", key, err)
+	}
+
+	return d, nil
+}
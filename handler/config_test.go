@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+func TestConfigFile(t *testing.T) {
+	defer func() {
+		assert.Nil(t, easylog.Configure(&easylog.Config{}))
+	}()
+
+	dir := t.TempDir()
+	app := filepath.Join(dir, "app.log")
+	rolling := filepath.Join(dir, "rolling.log")
+	errs := filepath.Join(dir, "errors.log")
+	ring := filepath.Join(dir, "ring.log")
+
+	yml := `
+handlers:
+  app:
+    type: writer
+    formatter: json
+    writer:
+      type: file
+      path: ` + app + `
+  rolling:
+    type: writer
+    level: ERROR
+    buffer_size: 64
+    options:
+      flush_level: ERROR
+      flush_interval: 1h
+    writer:
+      type: rolling
+      path: ` + rolling + `
+      max_size: 1048576
+      max_backups: 3
+  ring:
+    type: ring
+    formatter: logfmt
+    writer:
+      type: file
+      path: ` + ring + `
+    options:
+      overflow: block
+      close_timeout: 1s
+error_handlers:
+  errors:
+    type: writer
+    writer:
+      type: file
+      path: ` + errs + `
+loggers:
+  config_file_test:
+    level: INFO
+    handlers: [app, rolling, ring]
+    error_handler: errors
+`
+	path := filepath.Join(dir, "log.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(yml), 0666))
+	assert.Nil(t, easylog.LoadConfigFile(path))
+
+	l := easylog.GetLogger("config_file_test")
+	l.Debug().Logf("debug")
+	l.Info().Str("k", "v").Logf("info")
+	l.Error().Logf("error")
+	assert.Nil(t, l.GetErrorHandler().Handle(errors.New("failed")))
+
+	// releasing the configuration flushes and closes the writers
+	assert.Nil(t, easylog.Configure(&easylog.Config{}))
+
+	b, err := os.ReadFile(app)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Contains(t, lines[0], `"msg":"info"`)
+	assert.Contains(t, lines[0], `"fields":{"k":"v"}`)
+	assert.Contains(t, lines[1], `"msg":"error"`)
+
+	b, err = os.ReadFile(rolling)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, strings.Count(string(b), "\n"))
+	assert.Contains(t, string(b), "error")
+
+	b, err = os.ReadFile(ring)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, strings.Count(string(b), "\n"))
+	assert.Contains(t, string(b), "msg=info")
+
+	b, err = os.ReadFile(errs)
+	assert.Nil(t, err)
+	assert.Equal(t, "failed\n", string(b))
+}
+
+func TestConfigErrors(t *testing.T) {
+	for name, hc := range map[string]easylog.HandlerConfig{
+		"unknown formatter": {Type: "writer", Formatter: "xml"},
+		"unknown writer":    {Type: "writer", Writer: &easylog.WriterConfig{Type: "kafka"}},
+		"missing path":      {Type: "writer", Writer: &easylog.WriterConfig{Type: "file"}},
+		"unknown option":    {Type: "writer", Options: map[string]interface{}{"size": 1}},
+		"unknown ring":      {Type: "ring", Options: map[string]interface{}{"capacity": 1}},
+		"bad size":          {Type: "ring", Options: map[string]interface{}{"size": "big"}},
+		"negative size":     {Type: "ring", Options: map[string]interface{}{"size": -1}},
+		"bad interval":      {Type: "ring", Options: map[string]interface{}{"pull_interval": "soon"}},
+		"bad overflow":      {Type: "ring", Options: map[string]interface{}{"overflow": "spill"}},
+		"stray timeout":     {Type: "ring", Options: map[string]interface{}{"block_timeout": "1s"}},
+		"bad flush level":   {Type: "writer", Options: map[string]interface{}{"flush_level": "LOUD"}},
+	} {
+		cfg := &easylog.Config{
+			Handlers: map[string]easylog.HandlerConfig{"h": hc},
+			Loggers: map[string]easylog.LoggerConfig{
+				"config_errors_test": {Handlers: []string{"h"}},
+			},
+		}
+		assert.NotNil(t, easylog.Configure(cfg), name)
+	}
+}
+
+func TestConfigStdWriterNotClosed(t *testing.T) {
+	w, err := newWriterFromConfig(&easylog.WriterConfig{Type: "stdout"}, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	_, err = os.Stdout.Stat()
+	assert.Nil(t, err)
+}
+
+func TestConfigReloadSharesFiles(t *testing.T) {
+	defer func() {
+		assert.Nil(t, easylog.Configure(&easylog.Config{}))
+	}()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	configure := func(formatter string, maxSize int64) {
+		assert.Nil(t, easylog.Configure(&easylog.Config{
+			Handlers: map[string]easylog.HandlerConfig{
+				"app": {Type: "writer", Formatter: formatter,
+					Writer: &easylog.WriterConfig{Type: "rolling", Path: path, MaxSize: maxSize}},
+			},
+			Loggers: map[string]easylog.LoggerConfig{
+				"config_reload_test": {Handlers: []string{"app"}},
+			},
+		}))
+	}
+	l := easylog.GetLogger("config_reload_test")
+
+	configure("std", 1<<20)
+	f := sharedFiles[path]
+	l.Info().Logf("one")
+
+	// the Handler is replaced, but the writer of the path is kept, and closed only when released
+	configure("logfmt", 1<<20)
+	assert.Same(t, f, sharedFiles[path])
+	assert.Equal(t, 1, f.refs)
+	l.Info().Logf("two")
+
+	// other settings in a Config which fails to build leave the writer as it is
+	w := f.w
+	assert.NotNil(t, easylog.Configure(&easylog.Config{
+		Handlers: map[string]easylog.HandlerConfig{
+			"app": {Type: "writer", Formatter: "logfmt",
+				Writer: &easylog.WriterConfig{Type: "rolling", Path: path, MaxSize: 1 << 21}},
+			"bad": {Type: "writer", Writer: &easylog.WriterConfig{Type: "unknown"}},
+		},
+		Loggers: map[string]easylog.LoggerConfig{
+			"config_reload_test": {Handlers: []string{"app", "bad"}},
+		},
+	}))
+	assert.Same(t, f, sharedFiles[path])
+	assert.Same(t, w, f.w)
+	assert.Equal(t, int64(1<<20), f.cfg.MaxSize)
+	assert.Nil(t, f.staged)
+	assert.Equal(t, 1, f.refs)
+
+	// other settings replace the writer
+	configure("logfmt", 1<<21)
+	assert.Same(t, f, sharedFiles[path])
+	assert.NotSame(t, w, f.w)
+	l.Info().Logf("three")
+
+	assert.Nil(t, easylog.Configure(&easylog.Config{}))
+	assert.Empty(t, sharedFiles)
+
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Equal(t, 3, len(lines))
+	assert.Contains(t, lines[0], "one")
+	assert.Contains(t, lines[1], "msg=two")
+	assert.Contains(t, lines[2], "msg=three")
+
+	// the writers of the other paths are apart
+	a, err := newWriterFromConfig(&easylog.WriterConfig{Type: "file", Path: path}, 0)
+	assert.Nil(t, err)
+	other, err := newWriterFromConfig(&easylog.WriterConfig{Type: "file", Path: path + ".2"}, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(sharedFiles))
+	assert.Nil(t, a.Close())
+	assert.Nil(t, a.Close())
+	assert.Nil(t, other.Close())
+	assert.Empty(t, sharedFiles)
+}
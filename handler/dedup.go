@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/covine/easylog"
+)
+
+// Dedup collapses the duplicated Events passed to the wrapped Handler, like syslogd does.
+// Events are duplicates when they have the same Logger, level, message, kvs and fields.
+//
+// By default, only the consecutive duplicates are collapsed: the first Event of a run is passed,
+// and the run is summarized by an Event "last message repeated N times" when an Event which differs arrives.
+// With WithDedupWindow, the duplicates within a window are collapsed even when other Events come in between,
+// and each window is summarized when it elapses.
+//
+// A summary has the Logger and the level of the duplicates, with the fields "repeated" (the count)
+// and "repeated_msg" (the message). The pending summaries are passed on Flush and Close.
+//
+// The duplicates are skipped and continue to the next Handlers only if the first Event of the run did, according to
+// the wrapped Handler. The errors of the summaries passed in the background are reported to the ErrorHandler of their
+// Logger, the others are returned.
+type Dedup struct {
+	h      easylog.Handler
+	window time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	last    *dedupEntry
+	entries map[dedupKey][]*dedupEntry
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type dedupKey struct {
+	logger *easylog.Logger
+	level  easylog.Level
+	msg    string
+}
+
+type dedupEntry struct {
+	// e is a clone of the first Event
+	e        *easylog.Event
+	first    time.Time
+	repeated uint64
+	next     bool
+}
+
+// DedupOption configures a Dedup.
+type DedupOption func(*Dedup)
+
+// WithDedupWindow collapses the duplicates within window after the first Event, consecutive or not.
+func WithDedupWindow(window time.Duration) DedupOption {
+	return func(d *Dedup) {
+		d.window = window
+	}
+}
+
+// withDedupClock replaces time.Now, for testing.
+func withDedupClock(now func() time.Time) DedupOption {
+	return func(d *Dedup) {
+		d.now = now
+	}
+}
+
+// NewDedup wraps h. With a window, it starts a goroutine passing the summaries until Close.
+func NewDedup(h easylog.Handler, opts ...DedupOption) *Dedup {
+	d := &Dedup{
+		h:       h,
+		now:     time.Now,
+		entries: make(map[dedupKey][]*dedupEntry),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(d)
+	}
+
+	if d.window > 0 {
+		go d.run()
+	} else {
+		close(d.done)
+	}
+
+	return d
+}
+
+func (d *Dedup) Handle(e *easylog.Event) (bool, error) {
+	var en *dedupEntry
+	var summaries []dedupSummary
+	var next, dup bool
+
+	if d.window > 0 {
+		en, summaries, next, dup = d.windowed(e)
+	} else {
+		en, summaries, next, dup = d.consecutive(e)
+	}
+
+	if dup {
+		return next, nil
+	}
+
+	var errs []error
+	for _, s := range summaries {
+		errs = append(errs, d.pass(s))
+	}
+
+	next, err := d.h.Handle(e)
+
+	d.mu.Lock()
+	en.next = next
+	d.mu.Unlock()
+
+	if len(errs) == 0 {
+		return next, err
+	}
+
+	return next, errors.Join(append(errs, err)...)
+}
+
+// Flush passes the pending summaries, then flushes the wrapped Handler.
+func (d *Dedup) Flush() error {
+	errs := d.flush(false)
+
+	return errors.Join(append(errs, d.h.Flush())...)
+}
+
+// Close passes the pending summaries, then closes the wrapped Handler.
+func (d *Dedup) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.stop)
+	})
+	<-d.done
+
+	errs := d.flush(true)
+
+	return errors.Join(append(errs, d.h.Close())...)
+}
+
+// consecutive collapses e if it duplicates the last Event, returning whether the run continues to the next
+// Handlers, otherwise it starts a new run and returns its entry.
+func (d *Dedup) consecutive(e *easylog.Event) (*dedupEntry, []dedupSummary, bool, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.last != nil && sameEvent(d.last.e, e) {
+		d.last.repeated++
+		return nil, nil, d.last.next, true
+	}
+
+	var summaries []dedupSummary
+	if d.last != nil {
+		if d.last.repeated > 0 {
+			summaries = append(summaries, d.last.summary())
+		}
+		d.last.e.Put()
+	}
+
+	d.last = &dedupEntry{e: e.Clone(), first: d.now(), next: true}
+
+	return d.last, summaries, false, false
+}
+
+// windowed collapses e if it duplicates an Event of the current windows, returning whether the window continues
+// to the next Handlers, otherwise it starts a new window and returns its entry.
+func (d *Dedup) windowed(e *easylog.Event) (*dedupEntry, []dedupSummary, bool, bool) {
+	now := d.now()
+	k := dedupKey{logger: e.GetLogger(), level: e.GetLevel(), msg: e.GetMsg()}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var summaries []dedupSummary
+	entries := d.entries[k]
+	for i, en := range entries {
+		if !sameEvent(en.e, e) {
+			continue
+		}
+
+		if now.Sub(en.first) < d.window {
+			en.repeated++
+			return nil, nil, en.next, true
+		}
+
+		// the window elapsed, but the background has not summarized it yet
+		if en.repeated > 0 {
+			summaries = append(summaries, en.summary())
+		}
+		en.e.Put()
+		entries = append(entries[:i], entries[i+1:]...)
+		break
+	}
+
+	en := &dedupEntry{e: e.Clone(), first: now, next: true}
+	d.entries[k] = append(entries, en)
+
+	return en, summaries, false, false
+}
+
+// flush passes the pending summaries. Only on close, the collapsing state is dropped.
+func (d *Dedup) flush(closing bool) []error {
+	var summaries []dedupSummary
+
+	d.mu.Lock()
+	if d.last != nil {
+		if d.last.repeated > 0 {
+			summaries = append(summaries, d.last.summary())
+			d.last.repeated = 0
+		}
+		if closing {
+			d.last.e.Put()
+			d.last = nil
+		}
+	}
+	for k, entries := range d.entries {
+		for _, en := range entries {
+			if en.repeated > 0 {
+				summaries = append(summaries, en.summary())
+				en.repeated = 0
+			}
+			if closing {
+				en.e.Put()
+			}
+		}
+		if closing {
+			delete(d.entries, k)
+		}
+	}
+	d.mu.Unlock()
+
+	var errs []error
+	for _, s := range summaries {
+		errs = append(errs, d.pass(s))
+	}
+
+	return errs
+}
+
+// expire summarizes and forgets the windows which elapsed.
+func (d *Dedup) expire() {
+	now := d.now()
+
+	var summaries []dedupSummary
+
+	d.mu.Lock()
+	for k, entries := range d.entries {
+		kept := entries[:0]
+		for _, en := range entries {
+			if now.Sub(en.first) < d.window {
+				kept = append(kept, en)
+				continue
+			}
+			if en.repeated > 0 {
+				summaries = append(summaries, en.summary())
+			}
+			en.e.Put()
+		}
+		if len(kept) == 0 {
+			delete(d.entries, k)
+		} else {
+			d.entries[k] = kept
+		}
+	}
+	d.mu.Unlock()
+
+	for _, s := range summaries {
+		if err := d.pass(s); err != nil {
+			_ = s.logger.GetErrorHandler().Handle(err)
+		}
+	}
+}
+
+func (d *Dedup) run() {
+	defer close(d.done)
+
+	t := time.NewTicker(d.window)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-t.C:
+			d.expire()
+		}
+	}
+}
+
+type dedupSummary struct {
+	logger   *easylog.Logger
+	level    easylog.Level
+	msg      string
+	repeated uint64
+}
+
+func (en *dedupEntry) summary() dedupSummary {
+	return dedupSummary{
+		logger:   en.e.GetLogger(),
+		level:    en.e.GetLevel(),
+		msg:      en.e.GetMsg(),
+		repeated: en.repeated,
+	}
+}
+
+func (d *Dedup) pass(s dedupSummary) error {
+	e := easylog.NewEvent(s.logger, s.level, d.now(), fmt.Sprintf("last message repeated %d times", s.repeated))
+	defer e.Put()
+
+	e.Int64("repeated", int64(s.repeated)).Str("repeated_msg", s.msg)
+
+	_, err := d.h.Handle(e)
+
+	return err
+}
+
+// sameEvent reports whether a and b have the same Logger, level, message, kvs and fields.
+func sameEvent(a, b *easylog.Event) bool {
+	if a.GetLogger() != b.GetLogger() || a.GetLevel() != b.GetLevel() || a.GetMsg() != b.GetMsg() {
+		return false
+	}
+
+	if len(a.GetKvs()) != len(b.GetKvs()) || (len(a.GetKvs()) > 0 && !reflect.DeepEqual(a.GetKvs(), b.GetKvs())) {
+		return false
+	}
+
+	af, bf := a.GetFields(), b.GetFields()
+	if len(af) != len(bf) {
+		return false
+	}
+	for i := range af {
+		if af[i].Key != bf[i].Key || af[i].Type != bf[i].Type || af[i].Integer != bf[i].Integer ||
+			af[i].String != bf[i].String {
+			return false
+		}
+		// the strings are compared, not their pointers
+		if af[i].Type == easylog.StringsType {
+			if !slices.Equal(af[i].GetStrings(), bf[i].GetStrings()) {
+				return false
+			}
+		} else if !reflect.DeepEqual(af[i].Interface, bf[i].Interface) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,235 @@
+package handler
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// stopRecorder is a recorder stopping the Events while stop is set.
+type stopRecorder struct {
+	recorder
+	stop bool
+}
+
+func (r *stopRecorder) Handle(e *easylog.Event) (bool, error) {
+	next, err := r.recorder.Handle(e)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return next && !r.stop, err
+}
+
+func TestDedupConsecutive(t *testing.T) {
+	r := &recorder{}
+	d := NewDedup(r)
+
+	l := easylog.GetLogger("dedup.consecutive")
+	l.AddHandler(d)
+	defer l.ResetHandler()
+
+	l.Info().Logf("a")
+	l.Info().Logf("a")
+	l.Info().Logf("a")
+	l.Warn().Logf("a")
+	l.Warn().Kv("k", 1).Logf("a")
+	l.Warn().Kv("k", 1).Logf("a")
+	l.Warn().Kv("k", 2).Logf("a")
+	l.Warn().Int("f", 1).Logf("a")
+	l.Warn().Int("f", 1).Logf("a")
+	l.Warn().Int("f", 2).Logf("a")
+	l.Warn().Strs("s", []string{"x", "y"}).Logf("a")
+	l.Warn().Strs("s", []string{"x", "y"}).Logf("a")
+	l.Warn().Strs("s", []string{"x", "z"}).Logf("a")
+	l.Info().Logf("a")
+
+	assert.Equal(t, []string{
+		"INFO a",
+		"INFO last message repeated 2 times repeated=2 repeated_msg=a",
+		"WARN a",
+		"WARN a",
+		"WARN last message repeated 1 times repeated=1 repeated_msg=a",
+		"WARN a",
+		"WARN a f=1",
+		"WARN last message repeated 1 times repeated=1 repeated_msg=a",
+		"WARN a f=2",
+		"WARN a s=[x y]",
+		"WARN last message repeated 1 times repeated=1 repeated_msg=a",
+		"WARN a s=[x z]",
+		"INFO a",
+	}, r.Events())
+
+	// the run goes on after a Flush
+	l.Info().Logf("a")
+	assert.Nil(t, d.Flush())
+	assert.Equal(t, []string{"INFO last message repeated 1 times repeated=1 repeated_msg=a"}, r.Events())
+	assert.Equal(t, 1, r.flush)
+
+	l.Info().Logf("a")
+	l.Info().Logf("a")
+	assert.Nil(t, d.Close())
+	assert.Equal(t, []string{"INFO last message repeated 2 times repeated=2 repeated_msg=a"}, r.Events())
+	assert.Equal(t, 1, r.closed)
+}
+
+func TestDedupLoggers(t *testing.T) {
+	r := &recorder{}
+	d := NewDedup(r)
+	defer d.Close()
+
+	a := easylog.GetLogger("dedup.a")
+	a.AddHandler(d)
+	defer a.ResetHandler()
+	b := easylog.GetLogger("dedup.b")
+	b.AddHandler(d)
+	defer b.ResetHandler()
+
+	a.Info().Logf("m")
+	b.Info().Logf("m")
+	a.Info().Logf("m")
+
+	assert.Equal(t, []string{"INFO m", "INFO m", "INFO m"}, r.Events())
+}
+
+func TestDedupWindow(t *testing.T) {
+	clock := &testClock{t: time.Unix(0, 0)}
+	r := &recorder{}
+	d := NewDedup(r, WithDedupWindow(time.Hour), withDedupClock(clock.Now))
+	defer d.Close()
+
+	l := easylog.GetLogger("dedup.window")
+	l.AddHandler(d)
+	defer l.ResetHandler()
+
+	l.Info().Logf("a")
+	l.Info().Logf("b")
+	l.Info().Logf("a")
+	l.Info().Logf("b")
+	l.Info().Logf("a")
+	assert.Equal(t, []string{"INFO a", "INFO b"}, r.Events())
+
+	// the window of a elapsed, its next duplicate starts a new one
+	clock.Add(time.Hour)
+	l.Info().Logf("a")
+	assert.Equal(t, []string{"INFO last message repeated 2 times repeated=2 repeated_msg=a", "INFO a"}, r.Events())
+
+	assert.Nil(t, d.Flush())
+	assert.Equal(t, []string{"INFO last message repeated 1 times repeated=1 repeated_msg=b"}, r.Events())
+
+	// the elapsed windows are forgotten
+	l.Info().Logf("a")
+	clock.Add(time.Hour)
+	d.expire()
+	assert.Equal(t, []string{"INFO last message repeated 1 times repeated=1 repeated_msg=a"}, r.Events())
+	assert.Equal(t, 0, len(d.entries))
+}
+
+func TestDedupWindowBackground(t *testing.T) {
+	r := &recorder{err: errors.New("failed")}
+	d := NewDedup(r, WithDedupWindow(10*time.Millisecond))
+
+	l := easylog.GetLogger("dedup.background")
+	eh := &errorCollector{}
+	l.SetErrorHandler(eh)
+	l.AddHandler(d)
+	defer l.ResetHandler()
+
+	_, err := d.Handle(easylog.NewEvent(l, easylog.INFO, time.Now(), "m"))
+	assert.Equal(t, r.err, err)
+	_, err = d.Handle(easylog.NewEvent(l, easylog.INFO, time.Now(), "m"))
+	assert.Nil(t, err)
+
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.events) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []error{r.err}, eh.Errors())
+	assert.Nil(t, d.Close())
+}
+
+func TestDedupNext(t *testing.T) {
+	r := &stopRecorder{stop: true}
+	d := NewDedup(r)
+	defer d.Close()
+
+	l := easylog.GetLogger("dedup.next")
+	next := &recorder{}
+	l.AddHandler(d)
+	l.AddHandler(next)
+	defer l.ResetHandler()
+
+	// the duplicates stop as the first Event did
+	l.Info().Logf("m")
+	l.Info().Logf("m")
+	assert.Equal(t, 0, len(next.Events()))
+
+	r.mu.Lock()
+	r.stop = false
+	r.mu.Unlock()
+
+	l.Info().Logf("n")
+	l.Info().Logf("n")
+	assert.Equal(t, []string{"INFO n", "INFO n"}, next.Events())
+	assert.Equal(t, []string{"INFO m", "INFO last message repeated 1 times repeated=1 repeated_msg=m", "INFO n"}, r.Events())
+}
+
+// gateRecorder is a recorder whose Handle waits for gate to be closed.
+type gateRecorder struct {
+	recorder
+	gate chan struct{}
+}
+
+func (r *gateRecorder) Handle(e *easylog.Event) (bool, error) {
+	<-r.gate
+
+	return r.recorder.Handle(e)
+}
+
+func TestDedupConcurrent(t *testing.T) {
+	for _, opts := range [][]DedupOption{nil, {WithDedupWindow(time.Hour)}} {
+		r := &gateRecorder{gate: make(chan struct{})}
+		d := NewDedup(r, opts...)
+
+		l := easylog.GetLogger("dedup.concurrent")
+		l.AddHandler(d)
+
+		// the duplicates are handled while the first Event is in the wrapped Handler
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Info().Logf("m")
+		}()
+		assert.Eventually(t, func() bool {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			return d.last != nil || len(d.entries) > 0
+		}, time.Second, time.Millisecond)
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					l.Info().Logf("m")
+					runtime.Gosched()
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(r.gate)
+		wg.Wait()
+
+		l.ResetHandler()
+		assert.Nil(t, d.Close())
+		assert.Equal(t, []string{"INFO m", "INFO last message repeated 400 times repeated=400 repeated_msg=m"}, r.Events())
+	}
+}
@@ -0,0 +1,328 @@
+package handler
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/covine/easylog"
+)
+
+const hex = "0123456789abcdef"
+
+// writeJSONString writes s as a quoted and escaped JSON string.
+func writeJSONString(b *easylog.Bytes, s string) {
+	b.AppendByte('"')
+	writeJSONChars(b, s)
+	b.AppendByte('"')
+}
+
+// writeJSONChars writes s escaped for a JSON string, without the quotes.
+func writeJSONChars(b *easylog.Bytes, s string) {
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+
+			b.AppendString(s[start:i])
+			switch c {
+			case '"', '\\':
+				b.AppendByte('\\')
+				b.AppendByte(c)
+			case '\n':
+				b.AppendString(`\n`)
+			case '\r':
+				b.AppendString(`\r`)
+			case '\t':
+				b.AppendString(`\t`)
+			default:
+				b.AppendString(`\u00`)
+				b.AppendByte(hex[c>>4])
+				b.AppendByte(hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b.AppendString(s[start:i])
+			b.AppendString("\ufffd")
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+
+	b.AppendString(s[start:])
+}
+
+// appendJSONString appends s as a quoted and escaped JSON string.
+func appendJSONString(dst []byte, s string) []byte {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	writeJSONString(b, s)
+
+	return append(dst, b.Bytes()...)
+}
+
+// writeJSONFloat writes f as a JSON number, or as a string for NaN and infinities.
+func writeJSONFloat(b *easylog.Bytes, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		b.AppendString(`"NaN"`)
+	case math.IsInf(f, 1):
+		b.AppendString(`"+Inf"`)
+	case math.IsInf(f, -1):
+		b.AppendString(`"-Inf"`)
+	default:
+		b.AppendFloat(f, bitSize)
+	}
+}
+
+// writeFieldJSON writes the value of f as JSON.
+func writeFieldJSON(b *easylog.Bytes, f *easylog.Field) {
+	switch f.Type {
+	case easylog.StringType:
+		writeJSONString(b, f.String)
+	case easylog.BytesType:
+		b.AppendByte('"')
+		writeBase64(b, f.String)
+		b.AppendByte('"')
+	case easylog.IntType:
+		b.AppendInt(f.GetInt())
+	case easylog.Float64Type:
+		writeJSONFloat(b, f.GetFloat64(), 64)
+	case easylog.BoolType:
+		b.AppendBool(f.GetBool())
+	case easylog.DurationType:
+		b.AppendByte('"')
+		writeDuration(b, f.GetDuration())
+		b.AppendByte('"')
+	case easylog.TimeType:
+		b.AppendByte('"')
+		b.AppendTime(f.GetTime(), time.RFC3339Nano)
+		b.AppendByte('"')
+	case easylog.ErrorType:
+		if err := f.GetError(); err != nil {
+			writeJSONString(b, err.Error())
+		} else {
+			b.AppendString("null")
+		}
+	case easylog.StringsType:
+		b.AppendByte('[')
+		for i, s := range f.GetStrings() {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONString(b, s)
+		}
+		b.AppendByte(']')
+	default:
+		writeJSONValue(b, f.Interface, 0)
+	}
+}
+
+// appendFieldJSON appends the value of f as JSON.
+func appendFieldJSON(dst []byte, f *easylog.Field) []byte {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	writeFieldJSON(b, f)
+
+	return append(dst, b.Bytes()...)
+}
+
+// writeFieldsJSON writes fields as the members of a JSON object, keeping their order.
+// The keys in reserved, the names of the other members, are prefixed with "field.".
+func writeFieldsJSON(b *easylog.Bytes, fields []easylog.Field, reserved map[string]bool) {
+	for i := range fields {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+		if reserved[fields[i].Key] {
+			b.AppendByte('"')
+			b.AppendString(fieldKeyPrefix)
+			writeJSONChars(b, fields[i].Key)
+			b.AppendByte('"')
+		} else {
+			writeJSONString(b, fields[i].Key)
+		}
+		b.AppendByte(':')
+		writeFieldJSON(b, &fields[i])
+	}
+}
+
+// jsonFields marshals the typed fields of an Event without reflection.
+type jsonFields []easylog.Field
+
+func (j jsonFields) MarshalJSON() ([]byte, error) {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	b.AppendByte('{')
+	writeFieldsJSON(b, j, nil)
+	b.AppendByte('}')
+
+	return append(make([]byte, 0, b.Len()), b.Bytes()...), nil
+}
+
+// appendFieldText appends the value of f as plain text, the bytes being encoded in base64.
+func appendFieldText(dst []byte, f *easylog.Field) []byte {
+	switch f.Type {
+	case easylog.StringType:
+		return append(dst, f.String...)
+	case easylog.BytesType:
+		return appendBase64(dst, f.String)
+	case easylog.IntType:
+		return strconv.AppendInt(dst, f.GetInt(), 10)
+	case easylog.Float64Type:
+		return strconv.AppendFloat(dst, f.GetFloat64(), 'f', -1, 64)
+	case easylog.BoolType:
+		return strconv.AppendBool(dst, f.GetBool())
+	case easylog.DurationType:
+		return append(dst, f.GetDuration().String()...)
+	case easylog.TimeType:
+		return f.GetTime().AppendFormat(dst, time.RFC3339Nano)
+	case easylog.ErrorType:
+		if err := f.GetError(); err != nil {
+			return append(dst, err.Error()...)
+		}
+		return append(dst, "<nil>"...)
+	default:
+		return append(dst, fmt.Sprint(f.Value())...)
+	}
+}
+
+// writeDuration writes d as time.Duration.String does, without allocating.
+func writeDuration(b *easylog.Bytes, d time.Duration) {
+	var buf [32]byte
+	w := len(buf)
+
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+
+	if u < uint64(time.Second) {
+		var prec int
+		w--
+		buf[w] = 's'
+		w--
+		switch {
+		case u == 0:
+			b.AppendString("0s")
+			return
+		case u < uint64(time.Microsecond):
+			buf[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// the micro sign is 2 bytes
+			w--
+			copy(buf[w:], "µ")
+		default:
+			prec = 6
+			buf[w] = 'm'
+		}
+		w, u = fmtFrac(buf[:w], u, prec)
+		w = fmtInt(buf[:w], u)
+	} else {
+		w--
+		buf[w] = 's'
+		w, u = fmtFrac(buf[:w], u, 9)
+		w = fmtInt(buf[:w], u%60)
+		u /= 60
+		if u > 0 {
+			w--
+			buf[w] = 'm'
+			w = fmtInt(buf[:w], u%60)
+			u /= 60
+			if u > 0 {
+				w--
+				buf[w] = 'h'
+				w = fmtInt(buf[:w], u)
+			}
+		}
+	}
+
+	if neg {
+		w--
+		buf[w] = '-'
+	}
+
+	_, _ = b.Write(buf[w:])
+}
+
+// fmtFrac formats the fraction of v/10^prec into the tail of buf, omitting the trailing zeros.
+// It returns the index where the output begins and v/10^prec.
+func fmtFrac(buf []byte, v uint64, prec int) (int, uint64) {
+	w := len(buf)
+	print := false
+	for i := 0; i < prec; i++ {
+		digit := v % 10
+		print = print || digit != 0
+		if print {
+			w--
+			buf[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if print {
+		w--
+		buf[w] = '.'
+	}
+	return w, v
+}
+
+// fmtInt formats v into the tail of buf, it returns the index where the output begins.
+func fmtInt(buf []byte, v uint64) int {
+	w := len(buf)
+	if v == 0 {
+		w--
+		buf[w] = '0'
+		return w
+	}
+	for v > 0 {
+		w--
+		buf[w] = byte(v%10) + '0'
+		v /= 10
+	}
+	return w
+}
+
+// the bytes encoded in base64 at once, a multiple of 3 so only the last chunk is padded
+const base64Chunk = 48
+
+// writeBase64 writes s encoded in standard base64, as encoding/json encodes a []byte, without allocating.
+func writeBase64(b *easylog.Bytes, s string) {
+	var src [base64Chunk]byte
+	var dst [base64Chunk / 3 * 4]byte
+	for len(s) > 0 {
+		n := copy(src[:], s)
+		s = s[n:]
+
+		base64.StdEncoding.Encode(dst[:], src[:n])
+		_, _ = b.Write(dst[:base64.StdEncoding.EncodedLen(n)])
+	}
+}
+
+// appendBase64 appends s encoded in standard base64.
+func appendBase64(dst []byte, s string) []byte {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	writeBase64(b, s)
+
+	return append(dst, b.Bytes()...)
+}
@@ -0,0 +1,203 @@
+package handler
+
+import (
+	"path"
+	"reflect"
+
+	"github.com/covine/easylog"
+)
+
+// Filter decides whether an Event should be handled.
+type Filter interface {
+	Match(*easylog.Event) bool
+}
+
+// FilterFunc adapts a function to a Filter.
+type FilterFunc func(*easylog.Event) bool
+
+func (f FilterFunc) Match(e *easylog.Event) bool {
+	return f(e)
+}
+
+// LevelRange matches the Events whose level is in [min, max].
+func LevelRange(min, max easylog.Level) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		return e.GetLevel() >= min && e.GetLevel() <= max
+	})
+}
+
+// MinLevel matches the Events whose level is at least level.
+func MinLevel(level easylog.Level) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		return e.GetLevel() >= level
+	})
+}
+
+// MaxLevel matches the Events whose level is at most level.
+func MaxLevel(level easylog.Level) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		return e.GetLevel() <= level
+	})
+}
+
+// LoggerName matches the Events emitted by the Loggers whose name matches the glob pattern,
+// with the syntax of path.Match. As Logger names have no '/', '*' also matches across dots,
+// e.g. "db.*" matches "db.pool" and "db.pool.conn". The name of the root Logger is "".
+// A malformed pattern matches nothing.
+func LoggerName(pattern string) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		if e.GetLogger() == nil {
+			return false
+		}
+		ok, err := path.Match(pattern, e.GetLogger().Name())
+		return err == nil && ok
+	})
+}
+
+// HasTag matches the Events having the tag k.
+func HasTag(k interface{}) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		_, ok := e.GetTags()[k]
+		return ok
+	})
+}
+
+// TagEquals matches the Events having the tag k with the value v, see KvEquals for the numbers.
+func TagEquals(k, v interface{}) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		tv, ok := e.GetTags()[k]
+		return ok && equalValues(tv, v)
+	})
+}
+
+// HasKv matches the Events having the kv or the typed field k.
+func HasKv(k interface{}) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		_, ok := lookupKv(e, k)
+		return ok
+	})
+}
+
+// KvEquals matches the Events having the kv or the typed field k with the value v.
+// The integers are compared by value whatever their type, as the floats are, so KvEquals("n", 1) matches
+// the field set by Int("n", 1), whose value is an int64.
+func KvEquals(k, v interface{}) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		kv, ok := lookupKv(e, k)
+		return ok && equalValues(kv, v)
+	})
+}
+
+// equalValues compares a and b deeply, the integers and the floats by value whatever their type.
+func equalValues(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isIntValue(va) && isIntValue(vb):
+		return intValue(va) == intValue(vb)
+	case isFloatValue(va) && isFloatValue(vb):
+		return va.Float() == vb.Float()
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func isIntValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+func isFloatValue(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+// intValue returns the integer v, the unsigned ones above math.MaxInt64 wrapping.
+func intValue(v reflect.Value) int64 {
+	if v.CanInt() {
+		return v.Int()
+	}
+
+	return int64(v.Uint())
+}
+
+func lookupKv(e *easylog.Event, k interface{}) (interface{}, bool) {
+	if v, ok := e.GetKvs()[k]; ok {
+		return v, true
+	}
+
+	if key, ok := k.(string); ok {
+		fields := e.GetFields()
+		for i := range fields {
+			if fields[i].Key == key {
+				return fields[i].Value(), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// And matches the Events matched by all the filters.
+func And(filters ...Filter) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		for _, f := range filters {
+			if !f.Match(e) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Or matches the Events matched by any of the filters.
+func Or(filters ...Filter) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		for _, f := range filters {
+			if f.Match(e) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Not matches the Events not matched by f.
+func Not(f Filter) Filter {
+	return FilterFunc(func(e *easylog.Event) bool {
+		return !f.Match(e)
+	})
+}
+
+// Filtered passes only the Events matched by the Filter to the wrapped Handler.
+// The other Events are skipped and continue to the next Handlers.
+type Filtered struct {
+	h easylog.Handler
+	f Filter
+}
+
+func NewFiltered(h easylog.Handler, f Filter) *Filtered {
+	return &Filtered{
+		h: h,
+		f: f,
+	}
+}
+
+func (f *Filtered) Handle(e *easylog.Event) (bool, error) {
+	if !f.f.Match(e) {
+		return true, nil
+	}
+
+	return f.h.Handle(e)
+}
+
+func (f *Filtered) Flush() error {
+	return f.h.Flush()
+}
+
+func (f *Filtered) Close() error {
+	return f.h.Close()
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+func TestFiltered(t *testing.T) {
+	logger := easylog.GetLogger("filter_test.db.pool")
+	logger.SetLevel(easylog.DEBUG)
+
+	errors := &memWriter{}
+	debugs := &memWriter{}
+
+	logger.AddHandler(NewFiltered(NewWriterHandler(errors, msgFormatter), MinLevel(easylog.ERROR)))
+	logger.AddHandler(NewFiltered(NewWriterHandler(debugs, msgFormatter), MaxLevel(easylog.INFO)))
+	defer logger.ResetHandler()
+
+	logger.Debug().Logf("debug")
+	logger.Info().Logf("info")
+	logger.Warn().Logf("warn")
+	logger.Error().Logf("error")
+
+	assert.Equal(t, "error\n", errors.String())
+	assert.Equal(t, "debug\ninfo\n", debugs.String())
+
+	logger.Flush()
+	logger.Close()
+	assert.Equal(t, 1, errors.flushed)
+	assert.Equal(t, 1, debugs.closed)
+}
+
+func TestFilters(t *testing.T) {
+	logger := easylog.GetLogger("filter_test.db.pool")
+	logger.SetLevel(easylog.DEBUG)
+
+	var matched []string
+	match := func(name string, f Filter) {
+		h := NewFiltered(&funcHandler{func(e *easylog.Event) {
+			matched = append(matched, name+":"+e.GetMsg())
+		}}, f)
+		logger.AddHandler(h)
+	}
+	defer logger.ResetHandler()
+
+	match("range", LevelRange(easylog.INFO, easylog.WARN))
+	match("name", LoggerName("filter_test.db.*"))
+	match("other", LoggerName("other.*"))
+	match("bad", LoggerName("["))
+	match("tag", HasTag("t"))
+	match("tagv", TagEquals("t", "v"))
+	match("kv", HasKv("k"))
+	match("kvv", KvEquals("k", 1))
+	match("field", KvEquals("f", int64(2)))
+	// compared by value
+	match("fieldint", KvEquals("f", 2))
+	match("fieldstr", KvEquals("f", "2"))
+	match("and", And(MinLevel(easylog.WARN), HasTag("t")))
+	match("or", Or(MinLevel(easylog.ERROR), HasKv("k")))
+	match("not", Not(MinLevel(easylog.INFO)))
+	match("func", FilterFunc(func(e *easylog.Event) bool { return e.GetMsg() == "3" }))
+
+	logger.Debug().Tag("t", "v").Logf("1")
+	logger.Info().Kv("k", 1).Logf("2")
+	logger.Warn().Tag("t", "x").Int64("f", 2).Logf("3")
+	logger.Error().Logf("4")
+
+	assert.Equal(t, []string{
+		"name:1", "tag:1", "tagv:1", "not:1",
+		"range:2", "name:2", "kv:2", "kvv:2", "or:2",
+		"range:3", "name:3", "tag:3", "field:3", "fieldint:3", "and:3", "func:3",
+		"name:4", "or:4",
+	}, matched)
+}
+
+type funcHandler struct {
+	f func(*easylog.Event)
+}
+
+func (h *funcHandler) Handle(e *easylog.Event) (bool, error) {
+	h.f(e)
+	return true, nil
+}
+
+func (h *funcHandler) Flush() error {
+	return nil
+}
+
+func (h *funcHandler) Close() error {
+	return nil
+}
@@ -1,27 +1,51 @@
 package handler
 
 import (
-	"bytes"
 	"encoding/json"
-	"path"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/covine/easylog"
 )
 
 type Formatter func(e *easylog.Event) ([]byte, error)
 
+// the keys of the trace context of the Events, rendered by all the formatters, see easylog.Event.GetTrace
+const (
+	traceIDKey    = "trace_id"
+	spanIDKey     = "span_id"
+	traceFlagsKey = "trace_flags"
+)
+
+// the name written for the root Logger by all the formatters, its name being ""
+const rootLoggerName = "root"
+
+// the prefix of the kvs and fields keys the formatters rename, as they collide with the keys of the Event
+const (
+	kvKeyPrefix    = "kv."
+	fieldKeyPrefix = "field."
+)
+
+// loggerName returns the name written for the Logger named name.
+func loggerName(name string) string {
+	if name == "" {
+		return rootLoggerName
+	}
+
+	return name
+}
+
+// JsonFormatter writes an Event as a JSON object with encoding/json, see JSONEncoder for a faster layout.
 func JsonFormatter(e *easylog.Event) ([]byte, error) {
 	m := make(map[string]interface{})
-	m["logger"] = e.GetLogger().Name()
+	m["logger"] = loggerName(e.GetLogger().Name())
 	if e.GetTags() != nil {
 		m["tag"] = e.GetTags()
 	}
 	if e.GetKvs() != nil {
 		m["kvs"] = e.GetKvs()
 	}
+	if len(e.GetFields()) > 0 {
+		m["fields"] = jsonFields(e.GetFields())
+	}
 	m["time"] = e.GetTime().Format("2006-01-02 15:04:05")
 	m["level"] = e.GetLevel().String()
 	m["caller"] = map[string]interface{}{
@@ -32,6 +56,11 @@ func JsonFormatter(e *easylog.Event) ([]byte, error) {
 		"line": e.GetCaller().GetLine(),
 	}
 	m["msg"] = e.GetMsg()
+	if t := e.GetTrace(); t.IsValid() {
+		m[traceIDKey] = t.TraceIDString()
+		m[spanIDKey] = t.SpanIDString()
+		m[traceFlagsKey] = t.FlagsString()
+	}
 	m["stack"] = e.GetStack()
 	m["extra"] = e.GetExtra()
 
@@ -43,72 +72,7 @@ func JsonFormatter(e *easylog.Event) ([]byte, error) {
 	return b, nil
 }
 
+// StdFormatter writes an Event as a colored line, followed by the stack if any, see StdPattern.
 func StdFormatter(e *easylog.Event) ([]byte, error) {
-	b := make([]byte, 0, 1024)
-	buf := bytes.NewBuffer(b)
-
-	level := e.GetLevel()
-	switch level {
-	case easylog.DEBUG:
-		buf.WriteString(White + level.String() + "  " + Reset)
-	case easylog.INFO:
-		buf.WriteString(Green + level.String() + "   " + Reset)
-	case easylog.WARN:
-		buf.WriteString(Purple + level.String() + "   " + Reset)
-	case easylog.ERROR:
-		fallthrough
-	case easylog.PANIC:
-		fallthrough
-	case easylog.FATAL:
-		buf.WriteString(Red + level.String() + "  " + Reset)
-	default:
-		buf.WriteString(Gray + "UNKNOWN" + Reset)
-	}
-
-	buf.WriteString(Blue)
-	buf.WriteString(time.Now().Format("2006-01-02 15:04:05"))
-	buf.WriteString(Reset)
-
-	buf.WriteString(" ")
-
-	buf.WriteString(GrayBlack)
-	if e.GetLogger().Name() == "" {
-		buf.WriteString("root")
-	} else {
-		buf.WriteString(e.GetLogger().Name())
-	}
-	buf.WriteString(Reset)
-
-	if e.GetCaller().GetOK() {
-		buf.WriteString(" ")
-		buf.WriteString(YellowBlue)
-		buf.WriteString(path.Base(e.GetCaller().GetFile()))
-		buf.WriteString(Reset)
-		buf.WriteString(" ")
-		f := strings.Split(e.GetCaller().GetFunc(), ".")
-		if len(f) > 0 {
-			buf.WriteString(BlackGray)
-			buf.WriteString(f[len(f)-1])
-			buf.WriteString(Reset)
-			buf.WriteString(" ")
-		}
-		buf.WriteString(Red)
-		buf.WriteString("[")
-		buf.WriteString(strconv.Itoa(e.GetCaller().GetLine()))
-		buf.WriteString("]")
-		buf.WriteString(Reset)
-	}
-
-	buf.WriteString(" ")
-
-	buf.WriteString(Cyan)
-	buf.WriteString(e.GetMsg())
-	buf.WriteString(Reset)
-
-	if len(e.GetStack()) > 0 {
-		buf.WriteString("\n")
-		buf.WriteString(e.GetStack())
-	}
-
-	return buf.Bytes(), nil
+	return stdFormatter(e)
 }
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+type formatterCapture struct {
+	f Formatter
+	b []byte
+}
+
+func (c *formatterCapture) Handle(e *easylog.Event) (bool, error) {
+	b, err := c.f(e)
+	c.b = b
+	return true, err
+}
+
+func (c *formatterCapture) Flush() error {
+	return nil
+}
+
+func (c *formatterCapture) Close() error {
+	return nil
+}
+
+func TestJsonFormatterFields(t *testing.T) {
+	logger := easylog.GetLogger("formatter_test")
+	c := &formatterCapture{f: JsonFormatter}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Info().
+		Str("z", "quote \" and \n newline").
+		Int("a", 1).
+		Float64("m", 0.5).
+		Bool("b", false).
+		Dur("d", time.Millisecond).
+		Err("e", errors.New("boom")).
+		Strs("s", []string{"x", "y"}).
+		Object("o", map[string]int{"k": 1}).
+		Log()
+
+	s := string(c.b)
+	assert.True(t, strings.Contains(s,
+		`"fields":{"z":"quote \" and \n newline","a":1,"m":0.5,"b":false,"d":"1ms","e":"boom","s":["x","y"],"o":{"k":1}}`,
+	), s)
+
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(c.b, &m))
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", "\"\\", "\x00\x1f\t\r\n", "中文", "\xff"} {
+		var v string
+		assert.Nil(t, json.Unmarshal(appendJSONString(nil, s), &v))
+		if s == "\xff" {
+			assert.Equal(t, "\ufffd", v)
+		} else {
+			assert.Equal(t, s, v)
+		}
+	}
+}
+
+func TestStdFormatterFields(t *testing.T) {
+	logger := easylog.GetLogger("formatter_test")
+	c := &formatterCapture{f: StdFormatter}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Info().Str("k", "v").Int("n", 2).Logf("msg")
+
+	s := string(c.b)
+	assert.True(t, strings.Index(s, "k="+Reset+"v") < strings.Index(s, "n="+Reset+"2"), s)
+}
+
+func TestFormattersTrace(t *testing.T) {
+	tc, err := easylog.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	ctx := easylog.ContextWithTrace(context.Background(), tc)
+
+	e := easylog.NewEvent(easylog.GetLogger("trace"), easylog.INFO, time.Unix(1, 0), "hi").Ctx(ctx)
+	defer e.Put()
+
+	b, err := JsonFormatter(e)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, string(b), `"span_id":"00f067aa0ba902b7"`)
+	assert.Contains(t, string(b), `"trace_flags":"01"`)
+
+	assert.Contains(t, encode(NewJSONEncoder(), e),
+		`"msg":"hi","trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"`)
+
+	b, err = LogfmtFormatter(e)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b),
+		`msg=hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01`)
+
+	f, err := NewTextFormatter(WithTextPattern("%msg%?{ %trace}"))
+	assert.Nil(t, err)
+	b, err = f(e)
+	assert.Nil(t, err)
+	assert.Equal(t, `hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01`, string(b))
+
+	h := &SyslogHandler{kvsID: defaultSyslogKvsID}
+	assert.Contains(t, string(h.appendRFC5424(nil, e)),
+		`[kvs@32473 trace_id="4bf92f3577b34da6a3ce929d0e0e4736" span_id="00f067aa0ba902b7" trace_flags="01"]`)
+	assert.Contains(t, string(h.appendRFC3164(nil, e)),
+		`hi trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01`)
+
+	// nothing without a trace context
+	e.Trace(easylog.TraceContext{})
+	b, err = f(e)
+	assert.Nil(t, err)
+	assert.Equal(t, "hi", string(b))
+	b, err = LogfmtFormatter(e)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(b), "trace_id")
+}
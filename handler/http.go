@@ -0,0 +1,717 @@
+package handler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/covine/easylog"
+)
+
+const (
+	defaultHTTPBatchCount        = 1000
+	defaultHTTPBatchBytes        = 1 << 20
+	defaultHTTPBatchMaxAge       = time.Second
+	defaultHTTPBatchMaxBuffered  = 16 << 20
+	defaultHTTPBatchRetries      = 3
+	defaultHTTPBatchBackoff      = 500 * time.Millisecond
+	defaultHTTPBatchTimeout      = 10 * time.Second
+	defaultHTTPBatchCloseTimeout = 5 * time.Second
+	maxHTTPBatchRetryBackoff     = 30 * time.Second
+)
+
+// BatchRecord is an Event formatted by an HTTPBatchHandler, waiting in a batch. Its Logger is the name of the
+// Logger of the Event, "root" for the root Logger.
+type BatchRecord struct {
+	Time   time.Time
+	Level  easylog.Level
+	Logger string
+	// Tags is a copy of the tags of the Event.
+	Tags map[interface{}]interface{}
+	// Line is the formatted Event, without terminator.
+	Line []byte
+
+	logger *easylog.Logger
+}
+
+// BatchEncoder encodes a batch of records into the body of a request.
+type BatchEncoder interface {
+	// ContentType returns the Content-Type of the bodies.
+	ContentType() string
+	// Encode writes the body of records to w.
+	Encode(w io.Writer, records []BatchRecord) error
+}
+
+// JSONArrayEncoder encodes a batch as a JSON array of the lines, which must be JSON values.
+type JSONArrayEncoder struct{}
+
+func NewJSONArrayEncoder() *JSONArrayEncoder {
+	return &JSONArrayEncoder{}
+}
+
+func (*JSONArrayEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (*JSONArrayEncoder) Encode(w io.Writer, records []BatchRecord) error {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	b.AppendByte('[')
+	for i := range records {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+		_, _ = b.Write(records[i].Line)
+	}
+	b.AppendByte(']')
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// ElasticsearchEncoder encodes a batch for the _bulk API of Elasticsearch, as NDJSON: an index action
+// followed by the line, which must be a JSON object, for each record.
+//
+// The failures of single documents, reported in a successful response, are not detected.
+type ElasticsearchEncoder struct {
+	action []byte
+}
+
+// NewElasticsearchEncoder indexes the documents in index, or in the index of the URL if empty,
+// like http://localhost:9200/logs/_bulk.
+func NewElasticsearchEncoder(index string) *ElasticsearchEncoder {
+	action := []byte(`{"index":{}}`)
+	if index != "" {
+		action = appendJSONString([]byte(`{"index":{"_index":`), index)
+		action = append(action, "}}"...)
+	}
+
+	return &ElasticsearchEncoder{action: append(action, '\n')}
+}
+
+func (*ElasticsearchEncoder) ContentType() string {
+	return "application/x-ndjson"
+}
+
+func (es *ElasticsearchEncoder) Encode(w io.Writer, records []BatchRecord) error {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	for i := range records {
+		_, _ = b.Write(es.action)
+		_, _ = b.Write(records[i].Line)
+		b.AppendByte('\n')
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// LokiEncoder encodes a batch for the push API of Loki, like http://localhost:3100/loki/api/v1/push.
+//
+// The records are grouped in streams labeled by the name of their Logger, as logger, "root" for the root
+// Logger, and by the values of the chosen tags, when set.
+type LokiEncoder struct {
+	labels []string
+}
+
+// NewLokiEncoder labels the streams with the tags named by labels, besides the logger.
+func NewLokiEncoder(labels ...string) *LokiEncoder {
+	return &LokiEncoder{labels: labels}
+}
+
+func (*LokiEncoder) ContentType() string {
+	return "application/json"
+}
+
+func (l *LokiEncoder) Encode(w io.Writer, records []BatchRecord) error {
+	type stream struct {
+		labels  []string
+		records []int
+	}
+
+	// the streams in the order they first appear, the records keep their order in each
+	var streams []*stream
+	index := make(map[string]*stream)
+	for i := range records {
+		labels := l.streamLabels(&records[i])
+		key := fmt.Sprintf("%q", labels)
+
+		s, ok := index[key]
+		if !ok {
+			s = &stream{labels: labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+		s.records = append(s.records, i)
+	}
+
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	b.AppendString(`{"streams":[`)
+	for i, s := range streams {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+
+		b.AppendString(`{"stream":{`)
+		for j := 0; j < len(s.labels); j += 2 {
+			if j > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONString(b, s.labels[j])
+			b.AppendByte(':')
+			writeJSONString(b, s.labels[j+1])
+		}
+
+		b.AppendString(`},"values":[`)
+		for j, r := range s.records {
+			if j > 0 {
+				b.AppendByte(',')
+			}
+			b.AppendString(`["`)
+			b.AppendInt(records[r].Time.UnixNano())
+			b.AppendString(`",`)
+			writeJSONString(b, string(records[r].Line))
+			b.AppendByte(']')
+		}
+		b.AppendString(`]}`)
+	}
+	b.AppendString(`]}`)
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// streamLabels returns the names and values of the labels of the stream of r, in turn.
+func (l *LokiEncoder) streamLabels(r *BatchRecord) []string {
+	labels := []string{"logger", r.Logger}
+	for _, name := range l.labels {
+		if v, ok := r.Tags[name]; ok {
+			labels = append(labels, name, fmt.Sprint(v))
+		}
+	}
+
+	return labels
+}
+
+// HTTPBatchStats counts the Events of an HTTPBatchHandler.
+type HTTPBatchStats struct {
+	// Sent is the number of Events accepted by the endpoint.
+	Sent uint64
+	// Dropped is the number of Events lost when the buffers were full, or when Close timed out.
+	Dropped uint64
+	// Failed is the number of Events which could not be formatted, encoded or sent.
+	Failed uint64
+}
+
+// HTTPBatchHandler formats the Events and sends them in batches to an HTTP endpoint, with POST requests.
+// A batch is sent when it reaches a count of Events or a size, or when its first Event is old enough.
+// The bodies are encoded by a BatchEncoder, a JSON array of the Events formatted as JSON by default.
+//
+// The batches are sent in order by a goroutine. A request failing on the network or with the status 429 or 5xx
+// is retried with an exponential backoff, following the Retry-After header if any. The Events waiting are
+// bounded in bytes, the Events handled beyond are dropped. The failures and the counts of the dropped Events
+// are reported to the ErrorHandler of the HTTPBatchHandler, or of the Logger of the Events.
+//
+// Flush sends the Events handled before, and waits for them. Close sends the Events left, within a deadline,
+// see WithHTTPBatchCloseTimeout.
+type HTTPBatchHandler struct {
+	url          string
+	client       *http.Client
+	header       http.Header
+	encoder      BatchEncoder
+	format       Formatter
+	gzip         bool
+	maxCount     int
+	maxBytes     int
+	maxAge       time.Duration
+	maxBuffered  int
+	retries      int
+	backoff      time.Duration
+	closeTimeout time.Duration
+	errorHandler easylog.ErrorHandler
+
+	// ctx aborts the requests when Close times out
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu sync.Mutex
+	// cond signals the batches queued and sent, and the closing
+	cond *sync.Cond
+	// the batch being filled, its size, and its generation, to tell its age timer
+	batch      []BatchRecord
+	batchBytes int
+	batchGen   uint64
+	ageTimer   *time.Timer
+	// the batches waiting to be sent, and the size of all the Events not sent yet
+	queue    [][]BatchRecord
+	buffered int
+	// the number of batches queued and sent, for Flush
+	queued   uint64
+	settled  uint64
+	closing  bool
+	exited   bool
+	closeOne sync.Once
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+
+	// the Events dropped since the last report, with the Logger of the last one
+	unreported atomic.Uint64
+	dropLogger atomic.Pointer[easylog.Logger]
+	// the Events dropped as Close timed out
+	abandoned atomic.Uint64
+}
+
+// HTTPBatchOption configures an HTTPBatchHandler.
+type HTTPBatchOption func(*HTTPBatchHandler)
+
+// WithHTTPBatchEncoder encodes the bodies with enc, like a LokiEncoder or an ElasticsearchEncoder.
+func WithHTTPBatchEncoder(enc BatchEncoder) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.encoder = enc
+	}
+}
+
+// WithHTTPBatchFormatter formats the Events with f, JsonFormatter by default.
+func WithHTTPBatchFormatter(f Formatter) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.format = f
+	}
+}
+
+// WithHTTPBatchClient sends the requests with client, by default a client with a timeout of 10 seconds.
+func WithHTTPBatchClient(client *http.Client) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.client = client
+	}
+}
+
+// WithHTTPBatchHeader sets a header of the requests, like Authorization.
+func WithHTTPBatchHeader(key, value string) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.header.Set(key, value)
+	}
+}
+
+// WithHTTPBatchGzip compresses the bodies with gzip.
+func WithHTTPBatchGzip() HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.gzip = true
+	}
+}
+
+// WithHTTPBatchSize sends a batch once it has count Events, or once its formatted Events reach bytes,
+// 1000 Events and 1 MiB by default. A value of 0 or less removes the limit.
+func WithHTTPBatchSize(count, bytes int) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.maxCount = count
+		h.maxBytes = bytes
+	}
+}
+
+// WithHTTPBatchMaxAge sends a batch at the latest maxAge after its first Event, 1 second by default.
+func WithHTTPBatchMaxAge(maxAge time.Duration) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.maxAge = maxAge
+	}
+}
+
+// WithHTTPBatchMaxBuffered bounds the size of the formatted Events not sent yet, 16 MiB by default.
+func WithHTTPBatchMaxBuffered(bytes int) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.maxBuffered = bytes
+	}
+}
+
+// WithHTTPBatchRetry retries a failed request up to retries times, waiting backoff before the first retry,
+// then twice as long before each next one, up to 30 seconds. By default, 3 times from 500 milliseconds.
+func WithHTTPBatchRetry(retries int, backoff time.Duration) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.retries = retries
+		h.backoff = backoff
+	}
+}
+
+// WithHTTPBatchCloseTimeout bounds the time Close waits for the Events left to be sent, 5 seconds by default.
+// The request in progress is then canceled, and the Events still left are dropped.
+func WithHTTPBatchCloseTimeout(timeout time.Duration) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.closeTimeout = timeout
+	}
+}
+
+// WithHTTPBatchErrorHandler reports the errors to eh instead of the ErrorHandler of the Logger of the Events.
+func WithHTTPBatchErrorHandler(eh easylog.ErrorHandler) HTTPBatchOption {
+	return func(h *HTTPBatchHandler) {
+		h.errorHandler = eh
+	}
+}
+
+// NewHTTPBatchHandler starts a goroutine sending the Events to rawURL until Close.
+func NewHTTPBatchHandler(rawURL string, opts ...HTTPBatchOption) (*HTTPBatchHandler, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("http batch: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("http batch: unsupported url %q", rawURL)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h := &HTTPBatchHandler{
+		url:          rawURL,
+		client:       &http.Client{Timeout: defaultHTTPBatchTimeout},
+		header:       make(http.Header),
+		encoder:      NewJSONArrayEncoder(),
+		format:       JsonFormatter,
+		maxCount:     defaultHTTPBatchCount,
+		maxBytes:     defaultHTTPBatchBytes,
+		maxAge:       defaultHTTPBatchMaxAge,
+		maxBuffered:  defaultHTTPBatchMaxBuffered,
+		retries:      defaultHTTPBatchRetries,
+		backoff:      defaultHTTPBatchBackoff,
+		closeTimeout: defaultHTTPBatchCloseTimeout,
+		ctx:          ctx,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+	}
+	h.cond = sync.NewCond(&h.mu)
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	go h.run()
+
+	return h, nil
+}
+
+func (h *HTTPBatchHandler) Handle(e *easylog.Event) (bool, error) {
+	b, err := h.format(e)
+	if err != nil {
+		h.failed.Add(1)
+		return true, fmt.Errorf("http batch: format: %w", err)
+	}
+
+	r := BatchRecord{
+		Time:   e.GetTime(),
+		Level:  e.GetLevel(),
+		Logger: loggerName(e.GetLogger().Name()),
+		Line:   b,
+		logger: e.GetLogger(),
+	}
+	if tags := e.GetTags(); len(tags) > 0 {
+		r.Tags = make(map[interface{}]interface{}, len(tags))
+		for k, v := range tags {
+			r.Tags[k] = v
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.exited {
+		// the goroutine is gone, and could not report it
+		h.dropped.Add(1)
+		return true, errors.New("http batch: closed, event dropped")
+	}
+	if h.closing || (h.maxBuffered > 0 && h.buffered+len(b) > h.maxBuffered) {
+		h.dropped.Add(1)
+		h.unreported.Add(1)
+		h.dropLogger.Store(e.GetLogger())
+		return true, nil
+	}
+
+	if h.maxBytes > 0 && h.batchBytes+len(b) > h.maxBytes {
+		h.cut()
+	}
+
+	h.batch = append(h.batch, r)
+	h.batchBytes += len(b)
+	h.buffered += len(b)
+
+	if len(h.batch) == 1 && h.maxAge > 0 {
+		gen := h.batchGen
+		h.ageTimer = time.AfterFunc(h.maxAge, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			if h.batchGen == gen {
+				h.cut()
+			}
+		})
+	}
+
+	if (h.maxCount > 0 && len(h.batch) >= h.maxCount) || (h.maxBytes > 0 && h.batchBytes >= h.maxBytes) {
+		h.cut()
+	}
+
+	return true, nil
+}
+
+// Flush sends the Events handled before the call, and waits until they are sent or failed.
+func (h *HTTPBatchHandler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.cut()
+
+	target := h.queued
+	for h.settled < target && !h.exited {
+		h.cond.Wait()
+	}
+
+	return nil
+}
+
+// Close stops accepting Events and sends the ones left. If they are not sent within the close timeout,
+// the request in progress is canceled, and the ones left are dropped: an error wrapping
+// context.DeadlineExceeded reports their count.
+func (h *HTTPBatchHandler) Close() error {
+	h.closeOne.Do(func() {
+		h.mu.Lock()
+		h.closing = true
+		h.cut()
+		h.cond.Broadcast()
+		h.mu.Unlock()
+	})
+
+	timer := time.NewTimer(h.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-h.done:
+	case <-timer.C:
+		h.cancel()
+		<-h.done
+	}
+
+	h.cancel()
+
+	if n := h.abandoned.Swap(0); n > 0 {
+		return fmt.Errorf("http batch: close: %w, dropped %d events", context.DeadlineExceeded, n)
+	}
+
+	return nil
+}
+
+// Stats returns the counters of the Events.
+func (h *HTTPBatchHandler) Stats() HTTPBatchStats {
+	return HTTPBatchStats{
+		Sent:    h.sent.Load(),
+		Dropped: h.dropped.Load(),
+		Failed:  h.failed.Load(),
+	}
+}
+
+// cut queues the batch being filled, if any. It's called with mu held.
+func (h *HTTPBatchHandler) cut() {
+	if len(h.batch) == 0 {
+		return
+	}
+
+	h.queue = append(h.queue, h.batch)
+	h.queued++
+	h.batch = nil
+	h.batchBytes = 0
+	h.batchGen++
+	if h.ageTimer != nil {
+		h.ageTimer.Stop()
+		h.ageTimer = nil
+	}
+
+	h.cond.Broadcast()
+}
+
+func (h *HTTPBatchHandler) run() {
+	defer close(h.done)
+
+	for {
+		h.mu.Lock()
+		for len(h.queue) == 0 && !h.closing {
+			h.cond.Wait()
+		}
+		if len(h.queue) == 0 {
+			h.exited = true
+			h.cond.Broadcast()
+			h.mu.Unlock()
+
+			h.reportDropped(nil)
+			return
+		}
+		batch := h.queue[0]
+		h.queue[0] = nil
+		h.queue = h.queue[1:]
+		h.mu.Unlock()
+
+		h.ship(batch)
+
+		size := 0
+		for i := range batch {
+			size += len(batch[i].Line)
+		}
+
+		h.mu.Lock()
+		h.buffered -= size
+		h.settled++
+		h.cond.Broadcast()
+		h.mu.Unlock()
+
+		h.reportDropped(nil)
+	}
+}
+
+// ship sends a batch, with retries.
+func (h *HTTPBatchHandler) ship(batch []BatchRecord) {
+	n := uint64(len(batch))
+	logger := batch[len(batch)-1].logger
+
+	body, err := h.encode(batch)
+	if err != nil {
+		h.failed.Add(n)
+		h.report(logger, err)
+		return
+	}
+
+	backoff := h.backoff
+	for retry := 0; ; retry++ {
+		if h.ctx.Err() != nil {
+			h.dropped.Add(n)
+			h.unreported.Add(n)
+			h.abandoned.Add(n)
+			return
+		}
+
+		wait, retryable, err := h.post(body)
+		if err == nil {
+			h.sent.Add(n)
+			return
+		}
+		if !retryable || retry >= h.retries {
+			h.failed.Add(n)
+			h.report(logger, err)
+			return
+		}
+
+		if wait <= 0 {
+			wait = backoff
+			backoff = min(backoff*2, maxHTTPBatchRetryBackoff)
+		}
+
+		t := time.NewTimer(min(wait, maxHTTPBatchRetryBackoff))
+		select {
+		case <-t.C:
+		case <-h.ctx.Done():
+			t.Stop()
+		}
+	}
+}
+
+func (h *HTTPBatchHandler) encode(batch []BatchRecord) ([]byte, error) {
+	var body bytes.Buffer
+	if !h.gzip {
+		if err := h.encoder.Encode(&body, batch); err != nil {
+			return nil, fmt.Errorf("http batch: encode: %w", err)
+		}
+		return body.Bytes(), nil
+	}
+
+	zw := gzip.NewWriter(&body)
+	if err := h.encoder.Encode(zw, batch); err != nil {
+		return nil, fmt.Errorf("http batch: encode: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("http batch: encode: %w", err)
+	}
+
+	return body.Bytes(), nil
+}
+
+// post sends a body. On failure, it returns the time the server asked to wait, if any, and whether
+// the request could be retried.
+func (h *HTTPBatchHandler) post(body []byte) (time.Duration, bool, error) {
+	req, err := http.NewRequestWithContext(h.ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, fmt.Errorf("http batch: %w", err)
+	}
+	req.Header = h.header.Clone()
+	req.Header.Set("Content-Type", h.encoder.ContentType())
+	if h.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, true, fmt.Errorf("http batch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	// drain the body, to reuse the connection
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, false, nil
+	}
+
+	err = fmt.Errorf("http batch: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	return retryAfter(resp.Header.Get("Retry-After")), retryable, err
+}
+
+// retryAfter returns the time to wait a Retry-After header asks, in seconds or until an HTTP-date, if any.
+func retryAfter(v string) time.Duration {
+	if s, err := strconv.Atoi(v); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
+
+// reportDropped reports the Events dropped since the last report, if any.
+func (h *HTTPBatchHandler) reportDropped(logger *easylog.Logger) {
+	n := h.unreported.Swap(0)
+	if n == 0 {
+		return
+	}
+
+	if l := h.dropLogger.Swap(nil); l != nil {
+		logger = l
+	}
+
+	h.report(logger, fmt.Errorf("http batch: dropped %d events", n))
+}
+
+// report reports err to the errorHandler if any, otherwise to the ErrorHandler of logger.
+func (h *HTTPBatchHandler) report(logger *easylog.Logger, err error) {
+	eh := h.errorHandler
+	if eh == nil && logger != nil {
+		eh = logger.GetErrorHandler()
+	}
+	if eh == nil {
+		return
+	}
+
+	// ignore error produced by the error handler
+	_ = eh.Handle(err)
+}
@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// batchServer records the bodies of the requests, and answers with the statuses queued, 200 by default.
+type batchServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	statuses []int
+	block    chan struct{}
+}
+
+func newBatchServer() *batchServer {
+	s := &batchServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.block != nil {
+			select {
+			case <-s.block:
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		b, _ := io.ReadAll(body)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.bodies = append(s.bodies, string(b))
+		s.headers = append(s.headers, r.Header)
+		if len(s.statuses) > 0 {
+			if s.statuses[0] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(s.statuses[0])
+			_, _ = w.Write([]byte("try later"))
+			s.statuses = s.statuses[1:]
+		}
+	}))
+	return s
+}
+
+func (s *batchServer) Bodies() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.bodies...)
+}
+
+func (s *batchServer) Header(i int) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.headers[i]
+}
+
+func jsonMsgFormatter(e *easylog.Event) ([]byte, error) {
+	return appendJSONString(nil, e.GetMsg()), nil
+}
+
+func httpHandle(h *HTTPBatchHandler, logger, msg string) {
+	e := easylog.NewEvent(easylog.GetLogger(logger), easylog.INFO, time.Unix(1, 2), msg)
+	defer e.Put()
+	if logger == "api" {
+		e.Tag("dc", "eu")
+	}
+	_, _ = h.Handle(e)
+}
+
+func TestHTTPBatchJSONArray(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchFormatter(jsonMsgFormatter), WithHTTPBatchSize(2, 0),
+		WithHTTPBatchMaxAge(time.Hour), WithHTTPBatchHeader("Authorization", "Bearer t"))
+	assert.Nil(t, err)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		httpHandle(h, "http", msg)
+	}
+	assert.Eventually(t, func() bool {
+		return len(s.Bodies()) == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Nil(t, h.Flush())
+	assert.Equal(t, []string{`["a","b"]`, `["c"]`}, s.Bodies())
+	assert.Equal(t, "application/json", s.Header(0).Get("Content-Type"))
+	assert.Equal(t, "Bearer t", s.Header(0).Get("Authorization"))
+	assert.Equal(t, HTTPBatchStats{Sent: 3}, h.Stats())
+
+	// drained on Close
+	httpHandle(h, "http", "d")
+	assert.Nil(t, h.Close())
+	assert.Equal(t, `["d"]`, s.Bodies()[2])
+
+	httpHandle(h, "http", "e")
+	assert.Equal(t, HTTPBatchStats{Sent: 4, Dropped: 1}, h.Stats())
+}
+
+func TestHTTPBatchMaxAge(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchFormatter(jsonMsgFormatter),
+		WithHTTPBatchMaxAge(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer h.Close()
+
+	httpHandle(h, "http", "a")
+	assert.Eventually(t, func() bool {
+		return len(s.Bodies()) == 1
+	}, time.Second, time.Millisecond)
+}
+
+func TestHTTPBatchLoki(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchEncoder(NewLokiEncoder("dc")),
+		WithHTTPBatchFormatter(msgFormatter), WithHTTPBatchGzip())
+	assert.Nil(t, err)
+
+	httpHandle(h, "api", `say "hi"`)
+	httpHandle(h, "db", "b")
+	httpHandle(h, "api", "c")
+	assert.Nil(t, h.Close())
+
+	assert.Equal(t, "gzip", s.Header(0).Get("Content-Encoding"))
+	assert.Equal(t,
+		`{"streams":[`+
+			`{"stream":{"logger":"api","dc":"eu"},"values":[["1000000002","say \"hi\""],["1000000002","c"]]},`+
+			`{"stream":{"logger":"db"},"values":[["1000000002","b"]]}]}`,
+		s.Bodies()[0])
+}
+
+func TestHTTPBatchElasticsearch(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+
+	h, err := NewHTTPBatchHandler(s.URL+"/_bulk", WithHTTPBatchEncoder(NewElasticsearchEncoder("logs")),
+		WithHTTPBatchFormatter(func(e *easylog.Event) ([]byte, error) {
+			return []byte(`{"msg":"` + e.GetMsg() + `"}`), nil
+		}))
+	assert.Nil(t, err)
+
+	httpHandle(h, "http", "a")
+	httpHandle(h, "http", "b")
+	assert.Nil(t, h.Close())
+
+	assert.Equal(t, "application/x-ndjson", s.Header(0).Get("Content-Type"))
+	assert.Equal(t,
+		"{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"a\"}\n{\"index\":{\"_index\":\"logs\"}}\n{\"msg\":\"b\"}\n",
+		s.Bodies()[0])
+}
+
+func TestHTTPBatchRetry(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+	s.statuses = []int{
+		http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK, http.StatusBadRequest,
+	}
+
+	errs := &errorCollector{}
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchFormatter(jsonMsgFormatter),
+		WithHTTPBatchRetry(3, time.Millisecond), WithHTTPBatchErrorHandler(errs))
+	assert.Nil(t, err)
+	defer h.Close()
+
+	// retried on 503 and 429
+	httpHandle(h, "http", "a")
+	assert.Nil(t, h.Flush())
+	assert.Equal(t, 3, len(s.Bodies()))
+	assert.Equal(t, HTTPBatchStats{Sent: 1}, h.Stats())
+
+	// not on 400
+	httpHandle(h, "http", "b")
+	assert.Nil(t, h.Flush())
+	assert.Equal(t, 4, len(s.Bodies()))
+	assert.Equal(t, HTTPBatchStats{Sent: 1, Failed: 1}, h.Stats())
+	assert.Equal(t, 1, len(errs.Errors()))
+	assert.Equal(t, "http batch: 400 Bad Request: try later", errs.Errors()[0].Error())
+}
+
+func TestHTTPBatchBounded(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+	s.block = make(chan struct{})
+
+	errs := &errorCollector{}
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchFormatter(jsonMsgFormatter), WithHTTPBatchSize(1, 0),
+		WithHTTPBatchMaxBuffered(10), WithHTTPBatchErrorHandler(errs))
+	assert.Nil(t, err)
+
+	// 3 bytes each, the first one is being sent
+	for i := 0; i < 5; i++ {
+		httpHandle(h, "http", "a")
+	}
+	assert.Equal(t, uint64(2), h.Stats().Dropped)
+
+	close(s.block)
+	assert.Nil(t, h.Close())
+	assert.Equal(t, HTTPBatchStats{Sent: 3, Dropped: 2}, h.Stats())
+	assert.Equal(t, 1, len(errs.Errors()))
+	assert.Equal(t, "http batch: dropped 2 events", errs.Errors()[0].Error())
+}
+
+func TestHTTPBatchCloseTimeout(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+	s.block = make(chan struct{})
+	defer close(s.block)
+
+	h, err := NewHTTPBatchHandler(s.URL, WithHTTPBatchFormatter(jsonMsgFormatter), WithHTTPBatchSize(1, 0),
+		WithHTTPBatchCloseTimeout(20*time.Millisecond), WithHTTPBatchErrorHandler(&errorCollector{}))
+	assert.Nil(t, err)
+
+	httpHandle(h, "http", "a")
+	httpHandle(h, "http", "b")
+
+	start := time.Now()
+	err = h.Close()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.EqualError(t, err, "http batch: close: context deadline exceeded, dropped 2 events")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, HTTPBatchStats{Dropped: 2}, h.Stats())
+	assert.Nil(t, h.Close())
+
+	// reported at once, nothing is left to report them
+	e := easylog.NewEvent(easylog.GetLogger("http"), easylog.INFO, time.Now(), "late")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.EqualError(t, err, "http batch: closed, event dropped")
+	assert.Equal(t, HTTPBatchStats{Dropped: 3}, h.Stats())
+}
+
+func TestHTTPBatchRetryAfter(t *testing.T) {
+	assert.Equal(t, 3*time.Second, retryAfter("3"))
+	assert.Equal(t, time.Duration(0), retryAfter(""))
+	assert.Equal(t, time.Duration(0), retryAfter("soon"))
+	assert.Equal(t, time.Duration(0), retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"))
+
+	wait := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	assert.True(t, wait > 59*time.Minute && wait <= time.Hour, wait)
+}
+
+func TestHTTPBatchURL(t *testing.T) {
+	for _, u := range []string{"localhost:3100", "ftp://host/x", "http://[::1"} {
+		_, err := NewHTTPBatchHandler(u)
+		assert.NotNil(t, err, u)
+	}
+}
+
+func TestHTTPBatchConfig(t *testing.T) {
+	s := newBatchServer()
+	defer s.Close()
+
+	h, err := newHTTPBatchHandlerFromConfig(easylog.HandlerConfig{
+		Type:      "http",
+		Formatter: "logfmt",
+		Options: map[string]interface{}{
+			"url": s.URL, "encoder": "loki", "labels": "dc, zone", "gzip": true, "batch_count": 10,
+			"max_age": "1h", "retries": 0,
+		},
+	})
+	assert.Nil(t, err)
+
+	e := easylog.NewEvent(easylog.GetLogger("api"), easylog.INFO, time.Unix(1, 0), "up")
+	e.Tag("zone", "a")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Nil(t, h.Close())
+
+	assert.Equal(t, "gzip", s.Header(0).Get("Content-Encoding"))
+	assert.Contains(t, s.Bodies()[0], `{"stream":{"logger":"api","zone":"a"},"values":[["1000000000",`)
+
+	for _, opts := range []map[string]interface{}{
+		{"url": s.URL, "encoder": "xml"},
+		{"url": s.URL, "gzip": "yes"},
+		{"url": s.URL, "headers": "x"},
+		{},
+	} {
+		_, err := newHTTPBatchHandlerFromConfig(easylog.HandlerConfig{Type: "http", Options: opts})
+		assert.NotNil(t, err)
+	}
+}
@@ -0,0 +1,483 @@
+package handler
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/covine/easylog"
+)
+
+// maxJSONDepth bounds the nesting of the values, against cyclic values.
+const maxJSONDepth = 32
+
+// Encoder writes an Event into a Bytes, usually taken from the pool with easylog.NewBytes.
+// Unlike a Formatter, an Encoder needs no buffer of its own, see WithWriterEncoder.
+type Encoder interface {
+	Encode(b *easylog.Bytes, e *easylog.Event) error
+}
+
+// JSONKeys names the members of the objects written by a JSONEncoder.
+// An empty name keeps the default name, "-" omits the member.
+type JSONKeys struct {
+	Time   string
+	Level  string
+	Logger string
+	Caller string
+	Msg    string
+	// TraceID, SpanID and TraceFlags name the trace context.
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+	Tags       string
+	Kvs        string
+	// Fields nests the typed fields in an object, by default they are members of the top level object.
+	Fields string
+	Error  string
+	Stack  string
+	Extra  string
+}
+
+var defaultJSONKeys = JSONKeys{
+	Time:       "time",
+	Level:      "level",
+	Logger:     "logger",
+	Caller:     "caller",
+	Msg:        "msg",
+	TraceID:    traceIDKey,
+	SpanID:     spanIDKey,
+	TraceFlags: traceFlagsKey,
+	Tags:       "tags",
+	Kvs:        "kvs",
+	Error:      "error",
+	Stack:      "stack",
+	Extra:      "extra",
+}
+
+// JSONEncoderOption configures a JSONEncoder.
+type JSONEncoderOption func(*JSONEncoder)
+
+// WithJSONKeys renames the members of the objects, like ts for time or lvl for level.
+func WithJSONKeys(keys JSONKeys) JSONEncoderOption {
+	return func(j *JSONEncoder) {
+		j.keys = keys
+	}
+}
+
+// WithJSONTimeLayout formats the time of the Events with layout, time.RFC3339Nano by default.
+func WithJSONTimeLayout(layout string) JSONEncoderOption {
+	return func(j *JSONEncoder) {
+		j.timeLayout = layout
+	}
+}
+
+// JSONEncoder writes an Event as a JSON object, in a single pass and without reflection for the typed fields:
+//
+//	{"time":"...","level":"INFO","logger":"db.pool","caller":"/src/pool.go:42","msg":"...",
+//	 "trace_id":"...","span_id":"...","trace_flags":"01",
+//	 "tags":{...},"kvs":{...},<fields>...,"error":"...","stack":"...","extra":...}
+//
+// The tags and kvs are sorted by key, the fields keep their order. The caller, trace context, tags, kvs, error,
+// stack and extra are written only when set, and the root Logger is named "root". A field named as one of the
+// other members is prefixed with "field.", unless the fields are nested, see JSONKeys.Fields. The Bytes fields,
+// as any []byte, are encoded in base64. The values of tags, kvs, Object fields and extra are encoded by type:
+// numbers, strings, times, errors, json.Marshaler and encoding.TextMarshaler values, maps and slices,
+// the others with encoding/json.
+//
+// Encoding an Event with typed fields of the common types allocates nothing.
+type JSONEncoder struct {
+	keys       JSONKeys
+	timeLayout string
+
+	// the members, rendered as `"name":`
+	time, level, logger, caller, msg, traceID, spanID, traceFlags, tags, kvs, fields, err, stack, extra string
+	// reserved are the names of the members, the fields can't take
+	reserved map[string]bool
+}
+
+func NewJSONEncoder(opts ...JSONEncoderOption) *JSONEncoder {
+	j := &JSONEncoder{
+		timeLayout: time.RFC3339Nano,
+		reserved:   make(map[string]bool),
+	}
+
+	for _, o := range opts {
+		o(j)
+	}
+
+	member := func(name, def string) string {
+		switch name {
+		case "":
+			name = def
+		case "-":
+			return ""
+		}
+		j.reserved[name] = true
+		return string(appendJSONString(nil, name)) + ":"
+	}
+
+	j.time = member(j.keys.Time, defaultJSONKeys.Time)
+	j.level = member(j.keys.Level, defaultJSONKeys.Level)
+	j.logger = member(j.keys.Logger, defaultJSONKeys.Logger)
+	j.caller = member(j.keys.Caller, defaultJSONKeys.Caller)
+	j.msg = member(j.keys.Msg, defaultJSONKeys.Msg)
+	j.traceID = member(j.keys.TraceID, defaultJSONKeys.TraceID)
+	j.spanID = member(j.keys.SpanID, defaultJSONKeys.SpanID)
+	j.traceFlags = member(j.keys.TraceFlags, defaultJSONKeys.TraceFlags)
+	j.tags = member(j.keys.Tags, defaultJSONKeys.Tags)
+	j.kvs = member(j.keys.Kvs, defaultJSONKeys.Kvs)
+	if j.keys.Fields != "" && j.keys.Fields != "-" {
+		j.fields = member(j.keys.Fields, "")
+	}
+	j.err = member(j.keys.Error, defaultJSONKeys.Error)
+	j.stack = member(j.keys.Stack, defaultJSONKeys.Stack)
+	j.extra = member(j.keys.Extra, defaultJSONKeys.Extra)
+
+	return j
+}
+
+// Encode writes e into b.
+func (j *JSONEncoder) Encode(b *easylog.Bytes, e *easylog.Event) error {
+	b.AppendByte('{')
+	first := true
+
+	if j.member(b, j.time, &first) {
+		b.AppendByte('"')
+		writeJSONTime(b, e.GetTime(), j.timeLayout)
+		b.AppendByte('"')
+	}
+
+	if j.member(b, j.level, &first) {
+		b.AppendByte('"')
+		b.AppendString(e.GetLevel().String())
+		b.AppendByte('"')
+	}
+
+	if j.member(b, j.logger, &first) {
+		writeJSONString(b, loggerName(e.GetLogger().Name()))
+	}
+
+	if c := e.GetCaller(); c.GetOK() && j.member(b, j.caller, &first) {
+		b.AppendByte('"')
+		writeJSONChars(b, c.GetFile())
+		b.AppendByte(':')
+		b.AppendInt(int64(c.GetLine()))
+		b.AppendByte('"')
+	}
+
+	if j.member(b, j.msg, &first) {
+		writeJSONString(b, e.GetMsg())
+	}
+
+	if t := e.GetTrace(); t.IsValid() {
+		if j.member(b, j.traceID, &first) {
+			writeJSONHex(b, t.TraceID[:])
+		}
+		if j.member(b, j.spanID, &first) {
+			writeJSONHex(b, t.SpanID[:])
+		}
+		if j.member(b, j.traceFlags, &first) {
+			writeJSONHex(b, []byte{t.Flags})
+		}
+	}
+
+	if len(e.GetTags()) > 0 && j.member(b, j.tags, &first) {
+		writeJSONMap(b, e.GetTags())
+	}
+
+	if len(e.GetKvs()) > 0 && j.member(b, j.kvs, &first) {
+		writeJSONMap(b, e.GetKvs())
+	}
+
+	if fields := e.GetFields(); len(fields) > 0 {
+		if j.fields != "" {
+			j.member(b, j.fields, &first)
+			b.AppendByte('{')
+			writeFieldsJSON(b, fields, nil)
+			b.AppendByte('}')
+		} else if j.keys.Fields != "-" {
+			if !first {
+				b.AppendByte(',')
+			}
+			first = false
+			writeFieldsJSON(b, fields, j.reserved)
+		}
+	}
+
+	if err := e.GetError(); err != nil && j.member(b, j.err, &first) {
+		writeJSONString(b, err.Error())
+	}
+
+	if stack := e.GetStack(); stack != "" && j.member(b, j.stack, &first) {
+		writeJSONString(b, stack)
+	}
+
+	if extra := e.GetExtra(); extra != nil && j.member(b, j.extra, &first) {
+		writeJSONValue(b, extra, 0)
+	}
+
+	b.AppendByte('}')
+
+	return nil
+}
+
+// Format makes a JSONEncoder usable as a Formatter, at the cost of a copy of the output.
+func (j *JSONEncoder) Format(e *easylog.Event) ([]byte, error) {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	if err := j.Encode(b, e); err != nil {
+		return nil, err
+	}
+
+	return append(make([]byte, 0, b.Len()+1), b.Bytes()...), nil
+}
+
+// writeJSONHex writes p as a JSON string of lowercase hex.
+func writeJSONHex(b *easylog.Bytes, p []byte) {
+	b.AppendByte('"')
+	for _, c := range p {
+		b.AppendByte(hex[c>>4])
+		b.AppendByte(hex[c&0xf])
+	}
+	b.AppendByte('"')
+}
+
+// writeJSONTime writes t formatted with layout, escaped for a JSON string, without the quotes.
+// The layout and the zone names rarely need it, the formatted time is only copied when they do.
+func writeJSONTime(b *easylog.Bytes, t time.Time, layout string) {
+	start := b.Len()
+	b.AppendTime(t, layout)
+
+	for _, c := range b.Bytes()[start:] {
+		if c < 0x20 || c == '"' || c == '\\' || c >= utf8.RuneSelf {
+			s := string(b.Bytes()[start:])
+			b.Truncate(start)
+			writeJSONChars(b, s)
+			return
+		}
+	}
+}
+
+// member writes the name of a member, unless it is omitted.
+func (j *JSONEncoder) member(b *easylog.Bytes, name string, first *bool) bool {
+	if name == "" {
+		return false
+	}
+
+	if !*first {
+		b.AppendByte(',')
+	}
+	*first = false
+	b.AppendString(name)
+
+	return true
+}
+
+// writeJSONMap writes the tags or kvs of an Event, sorted by key. Up to 16 string keys are sorted without allocating.
+func writeJSONMap(b *easylog.Bytes, m map[interface{}]interface{}) {
+	var arr [16]string
+	keys := arr[:0]
+	for k := range m {
+		s, ok := k.(string)
+		if !ok {
+			writeJSONAnyMap(b, reflect.ValueOf(m), 0)
+			return
+		}
+		keys = append(keys, s)
+	}
+	slices.Sort(keys)
+
+	b.AppendByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+		writeJSONString(b, k)
+		b.AppendByte(':')
+		writeJSONValue(b, m[k], 0)
+	}
+	b.AppendByte('}')
+}
+
+// writeJSONValue writes v as JSON, see JSONEncoder.
+func writeJSONValue(b *easylog.Bytes, v interface{}, depth int) {
+	if depth > maxJSONDepth {
+		b.AppendString("null")
+		return
+	}
+
+	switch v := v.(type) {
+	case nil:
+		b.AppendString("null")
+	case string:
+		writeJSONString(b, v)
+	case bool:
+		b.AppendBool(v)
+	case int:
+		b.AppendInt(int64(v))
+	case int8:
+		b.AppendInt(int64(v))
+	case int16:
+		b.AppendInt(int64(v))
+	case int32:
+		b.AppendInt(int64(v))
+	case int64:
+		b.AppendInt(v)
+	case uint:
+		b.AppendUint(uint64(v))
+	case uint8:
+		b.AppendUint(uint64(v))
+	case uint16:
+		b.AppendUint(uint64(v))
+	case uint32:
+		b.AppendUint(uint64(v))
+	case uint64:
+		b.AppendUint(v)
+	case float32:
+		writeJSONFloat(b, float64(v), 32)
+	case float64:
+		writeJSONFloat(b, v, 64)
+	case time.Time:
+		b.AppendByte('"')
+		b.AppendTime(v, time.RFC3339Nano)
+		b.AppendByte('"')
+	case time.Duration:
+		b.AppendByte('"')
+		writeDuration(b, v)
+		b.AppendByte('"')
+	case []byte:
+		b.AppendByte('"')
+		b.AppendString(base64.StdEncoding.EncodeToString(v))
+		b.AppendByte('"')
+	case []string:
+		b.AppendByte('[')
+		for i, s := range v {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONString(b, s)
+		}
+		b.AppendByte(']')
+	case []interface{}:
+		b.AppendByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONValue(b, e, depth+1)
+		}
+		b.AppendByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.AppendByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONString(b, k)
+			b.AppendByte(':')
+			writeJSONValue(b, v[k], depth+1)
+		}
+		b.AppendByte('}')
+	case json.Marshaler:
+		writeJSONMarshaler(b, v)
+	case encoding.TextMarshaler:
+		t, err := v.MarshalText()
+		if err != nil {
+			writeJSONString(b, err.Error())
+			return
+		}
+		writeJSONString(b, string(t))
+	case error:
+		writeJSONString(b, v.Error())
+	default:
+		writeJSONReflect(b, reflect.ValueOf(v), depth)
+	}
+}
+
+func writeJSONMarshaler(b *easylog.Bytes, m json.Marshaler) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		writeJSONString(b, fmt.Sprint(m))
+		return
+	}
+	_, _ = b.Write(raw)
+}
+
+// writeJSONReflect writes the maps, slices and pointers of any type, and the others with encoding/json.
+func writeJSONReflect(b *easylog.Bytes, v reflect.Value, depth int) {
+	switch v.Kind() {
+	case reflect.Map:
+		writeJSONAnyMap(b, v, depth)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			b.AppendString("null")
+			return
+		}
+		b.AppendByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			writeJSONValue(b, v.Index(i).Interface(), depth+1)
+		}
+		b.AppendByte(']')
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			b.AppendString("null")
+			return
+		}
+		writeJSONValue(b, v.Elem().Interface(), depth+1)
+	default:
+		raw, err := json.Marshal(v.Interface())
+		if err != nil {
+			writeJSONString(b, fmt.Sprint(v.Interface()))
+			return
+		}
+		_, _ = b.Write(raw)
+	}
+}
+
+// writeJSONAnyMap writes a map of any type, its keys formatted with fmt and sorted.
+func writeJSONAnyMap(b *easylog.Bytes, v reflect.Value, depth int) {
+	if v.IsNil() {
+		b.AppendString("null")
+		return
+	}
+
+	type entry struct {
+		k string
+		v reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		entries = append(entries, entry{k: fmt.Sprint(iter.Key().Interface()), v: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].k < entries[j].k
+	})
+
+	b.AppendByte('{')
+	for i, e := range entries {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+		writeJSONString(b, e.k)
+		b.AppendByte(':')
+		writeJSONValue(b, e.v.Interface(), depth+1)
+	}
+	b.AppendByte('}')
+}
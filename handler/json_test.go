@@ -0,0 +1,372 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// eventCapture keeps a clone of the last Event.
+type eventCapture struct {
+	e *easylog.Event
+}
+
+func (c *eventCapture) Handle(e *easylog.Event) (bool, error) {
+	if c.e != nil {
+		c.e.Put()
+	}
+	c.e = e.Clone()
+	return true, nil
+}
+
+func (c *eventCapture) Flush() error {
+	return nil
+}
+
+func (c *eventCapture) Close() error {
+	return nil
+}
+
+func captureEvent(name string, log func(l *easylog.Logger)) *easylog.Event {
+	l := easylog.GetLogger(name)
+	c := &eventCapture{}
+	l.AddHandler(c)
+	defer l.RemoveHandler(c)
+
+	log(l)
+
+	return c.e
+}
+
+func encode(enc *JSONEncoder, e *easylog.Event) string {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	_ = enc.Encode(b, e)
+
+	return b.String()
+}
+
+type textValue struct{}
+
+func (textValue) MarshalText() ([]byte, error) {
+	return []byte("text"), nil
+}
+
+type point struct {
+	X, Y int
+}
+
+func TestJSONEncoder(t *testing.T) {
+	e := captureEvent("json.encoder", func(l *easylog.Logger) {
+		l.Warn().
+			Tag("b", 1).
+			Tag("a", "x").
+			Kv("k", []int{1, 2}).
+			Str("s", "quote \"").
+			Int("i", -1).
+			Float64("f", 0.5).
+			Bool("ok", true).
+			Dur("d", 1500*time.Microsecond).
+			Err("err", errors.New("boom")).
+			Strs("ss", []string{"a"}).
+			Object("o", point{1, 2}).
+			E(errors.New("failed")).
+			Attach(map[string]interface{}{"z": nil, "y": textValue{}}).
+			Logf("hello")
+	})
+	defer e.Put()
+
+	s := encode(NewJSONEncoder(), e)
+	assert.True(t, strings.HasPrefix(s, `{"time":"`+e.GetTime().Format(time.RFC3339Nano)+`","level":"WARN"`), s)
+	assert.True(t, strings.HasSuffix(s,
+		`"logger":"json.encoder","msg":"hello","tags":{"a":"x","b":1},"kvs":{"k":[1,2]},`+
+			`"s":"quote \"","i":-1,"f":0.5,"ok":true,"d":"1.5ms","err":"boom","ss":["a"],"o":{"X":1,"Y":2},`+
+			`"error":"failed","extra":{"y":"text","z":null}}`,
+	), s)
+
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(s), &m))
+
+	enc := NewJSONEncoder(
+		WithJSONKeys(JSONKeys{Time: "ts", Level: "lvl", Logger: "-", Tags: "-", Kvs: "ctx", Fields: "fields", Extra: "-"}),
+		WithJSONTimeLayout(time.DateOnly),
+	)
+	s = encode(enc, e)
+	assert.Equal(t,
+		`{"ts":"`+e.GetTime().Format(time.DateOnly)+`","lvl":"WARN","msg":"hello","ctx":{"k":[1,2]},`+
+			`"fields":{"s":"quote \"","i":-1,"f":0.5,"ok":true,"d":"1.5ms","err":"boom","ss":["a"],"o":{"X":1,"Y":2}},`+
+			`"error":"failed"}`,
+		s,
+	)
+
+	enc = NewJSONEncoder(WithJSONKeys(JSONKeys{Time: "-", Level: "-", Logger: "-", Msg: "-", Tags: "-", Kvs: "-", Fields: "-", Error: "-", Extra: "-"}))
+	assert.Equal(t, "{}", encode(enc, e))
+
+	b, err := NewJSONEncoder().Format(e)
+	assert.Nil(t, err)
+	assert.Equal(t, encode(NewJSONEncoder(), e), string(b))
+}
+
+func TestJSONEncoderCallerStack(t *testing.T) {
+	e := captureEvent("json.encoder", func(l *easylog.Logger) {
+		l.EnableCaller(easylog.ERROR)
+		defer l.DisableCaller(easylog.ERROR)
+		l.EnableStack(easylog.ERROR)
+		defer l.DisableStack(easylog.ERROR)
+
+		l.Error().Log()
+	})
+	defer e.Put()
+
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(encode(NewJSONEncoder(), e)), &m))
+	assert.True(t, strings.Contains(m["caller"].(string), "handler/json_test.go:"), m["caller"])
+	assert.Equal(t, e.GetStack(), m["stack"])
+}
+
+func TestJSONEncoderRootReservedKeys(t *testing.T) {
+	e := captureEvent("", func(l *easylog.Logger) {
+		l.Info().Str("msg", "field").Str("logger", "x").Str("n", "1").Log()
+	})
+	defer e.Put()
+
+	enc := NewJSONEncoder(WithJSONKeys(JSONKeys{Time: "-"}))
+	s := encode(enc, e)
+	assert.Equal(t, `{"level":"INFO","logger":"root","msg":"","field.msg":"field","field.logger":"x","n":"1"}`, s)
+
+	enc = NewJSONEncoder(WithJSONKeys(JSONKeys{Time: "-", Logger: "-", Fields: "fields"}))
+	assert.Equal(t, `{"level":"INFO","msg":"","fields":{"msg":"field","logger":"x","n":"1"}}`, encode(enc, e))
+
+	b, err := JsonFormatter(e)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), `"logger":"root"`)
+
+	// a field named as an omitted member keeps its name
+	enc = NewJSONEncoder(WithJSONKeys(JSONKeys{Time: "-", Logger: "-"}))
+	assert.Equal(t, `{"level":"INFO","msg":"","field.msg":"field","logger":"x","n":"1"}`, encode(enc, e))
+}
+
+func TestJSONEncoderTimeEscaping(t *testing.T) {
+	loc := time.FixedZone(`A"B`, 0)
+	e := easylog.NewEvent(easylog.GetLogger("json.encoder"), easylog.INFO, time.Date(2021, 1, 2, 0, 0, 0, 0, loc), "")
+	defer e.Put()
+
+	enc := NewJSONEncoder(WithJSONTimeLayout(`2006 "01" \02 MST`),
+		WithJSONKeys(JSONKeys{Level: "-", Logger: "-", Msg: "-"}))
+	s := encode(enc, e)
+	assert.Equal(t, `{"time":"2021 \"01\" \\02 A\"B"}`, s)
+
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(s), &m))
+	assert.Equal(t, `2021 "01" \02 A"B`, m["time"])
+}
+
+func TestJSONEncoderBytes(t *testing.T) {
+	payload := make([]byte, 100)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+	e := captureEvent("json.bytes", func(l *easylog.Logger) {
+		l.Info().Bytes("b", payload).Bytes("empty", nil).Kv("k", payload).Log()
+	})
+	defer e.Put()
+
+	// the field and the kv are encoded alike
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal([]byte(encode(NewJSONEncoder(), e)), &m))
+	assert.Equal(t, base64.StdEncoding.EncodeToString(payload), m["b"])
+	assert.Equal(t, m["b"], m["kvs"].(map[string]interface{})["k"])
+	assert.Equal(t, "", m["empty"])
+
+	b, err := LogfmtFormatter(e)
+	assert.Nil(t, err)
+	assert.Contains(t, string(b), ` b="`+base64.StdEncoding.EncodeToString(payload)+`" empty=""`)
+}
+
+func TestWriteJSONValue(t *testing.T) {
+	type self struct {
+		Next *self
+	}
+	cyclic := &self{}
+	cyclic.Next = cyclic
+	var nilPtr *point
+
+	for v, expected := range map[interface{}]string{
+		int8(-1):              `-1`,
+		uint16(2):             `2`,
+		float32(0.25):         `0.25`,
+		math.NaN():            `"NaN"`,
+		math.Inf(-1):          `"-Inf"`,
+		time.Unix(0, 0).UTC(): `"1970-01-01T00:00:00Z"`,
+		time.Duration(0):      `"0s"`,
+		errors.New("e"):       `"e"`,
+		textValue{}:           `"text"`,
+		nilPtr:                `null`,
+		&point{3, 4}:          `{"X":3,"Y":4}`,
+		[2]bool{true, false}:  `[true,false]`,
+		make(chan int):        `"`,
+	} {
+		b := easylog.NewBytes()
+		writeJSONValue(b, v, 0)
+		assert.True(t, strings.HasPrefix(b.String(), expected), "%v: %s", v, b.String())
+		easylog.PutBytes(b)
+	}
+
+	for _, c := range []struct {
+		v        interface{}
+		expected string
+	}{
+		{[]byte("hi"), `"aGk="`},
+		{json.RawMessage(`{"a": 1}`), `{"a":1}`},
+		{[]interface{}{1, "a", nil}, `[1,"a",null]`},
+		{map[string]interface{}{"b": 1, "a": []string{"x"}}, `{"a":["x"],"b":1}`},
+		{map[int]string{2: "b", 1: "a"}, `{"1":"a","2":"b"}`},
+		{map[interface{}]interface{}{1: "a"}, `{"1":"a"}`},
+		{[]int(nil), `null`},
+	} {
+		b := easylog.NewBytes()
+		writeJSONValue(b, c.v, 0)
+		assert.Equal(t, c.expected, b.String())
+		easylog.PutBytes(b)
+	}
+
+	var deep interface{} = 1
+	for i := 0; i < 40; i++ {
+		deep = []interface{}{deep}
+	}
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+	writeJSONValue(b, deep, 0)
+	assert.Equal(t, strings.Repeat("[", 33)+"null"+strings.Repeat("]", 33), b.String())
+
+	b.Reset()
+	writeJSONValue(b, cyclic, 0)
+	assert.True(t, json.Valid(b.Bytes()))
+}
+
+func TestWriteDuration(t *testing.T) {
+	for _, d := range []time.Duration{
+		0, 1, 999, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond, 1234567 * time.Nanosecond,
+		time.Second, 90 * time.Second, time.Hour + time.Nanosecond, -time.Minute, math.MaxInt64, math.MinInt64,
+	} {
+		b := easylog.NewBytes()
+		writeDuration(b, d)
+		assert.Equal(t, d.String(), b.String())
+		easylog.PutBytes(b)
+	}
+}
+
+func TestWriterHandlerEncoder(t *testing.T) {
+	w := &memWriter{}
+	h := NewWriterHandler(w, nil, WithWriterEncoder(NewJSONEncoder(WithJSONKeys(JSONKeys{Time: "-"}))))
+
+	l := easylog.GetLogger("json.writer")
+	l.AddHandler(h)
+	defer l.RemoveHandler(h)
+
+	l.Info().Int("n", 1).Logf("a")
+	l.Info().Logf("b")
+
+	assert.Equal(t,
+		`{"level":"INFO","logger":"json.writer","msg":"a","n":1}`+"\n"+`{"level":"INFO","logger":"json.writer","msg":"b"}`+"\n",
+		w.String(),
+	)
+}
+
+func commonEvent() *easylog.Event {
+	now := time.Now()
+	err := errors.New("boom")
+	return captureEvent("json.bench", func(l *easylog.Logger) {
+		l.Info().
+			Tag("service", "api").
+			Kv("request_id", "abc").
+			Str("method", "GET").
+			Str("path", "/v1/users").
+			Int("status", 200).
+			Int64("bytes", 1<<20).
+			Float64("ratio", 0.75).
+			Bool("cached", false).
+			Dur("latency", 12*time.Millisecond).
+			Time("at", now).
+			Err("cause", err).
+			Bytes("body", []byte("\x00\xff payload")).
+			Logf("request served")
+	})
+}
+
+func TestJSONEncoderAllocs(t *testing.T) {
+	e := commonEvent()
+	defer e.Put()
+
+	enc := NewJSONEncoder()
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset()
+		_ = enc.Encode(b, e)
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func BenchmarkJSONEncoder(b *testing.B) {
+	e := commonEvent()
+	defer e.Put()
+
+	enc := NewJSONEncoder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf := easylog.NewBytes()
+		_ = enc.Encode(buf, e)
+		easylog.PutBytes(buf)
+	}
+}
+
+func BenchmarkJsonFormatter(b *testing.B) {
+	e := commonEvent()
+	defer e.Put()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = JsonFormatter(e)
+	}
+}
+
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discardWriter) Flush() error {
+	return nil
+}
+
+func (discardWriter) Close() error {
+	return nil
+}
+
+func BenchmarkWriterHandlerEncoder(b *testing.B) {
+	e := commonEvent()
+	defer e.Put()
+
+	h := NewWriterHandler(discardWriter{}, nil, WithWriterEncoder(NewJSONEncoder()))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = h.Handle(e)
+	}
+}
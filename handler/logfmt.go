@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/covine/easylog"
+)
+
+// LogfmtOption configures a logfmt Formatter.
+type LogfmtOption func(*logfmtFormatter)
+
+// WithLogfmtTimeLayout formats the time of the Events with layout, time.RFC3339Nano by default.
+func WithLogfmtTimeLayout(layout string) LogfmtOption {
+	return func(f *logfmtFormatter) {
+		f.timeLayout = layout
+	}
+}
+
+type logfmtFormatter struct {
+	timeLayout string
+}
+
+var defaultLogfmtFormatter = &logfmtFormatter{timeLayout: time.RFC3339Nano}
+
+// NewLogfmtFormatter returns a Formatter writing Events as logfmt lines, see LogfmtFormatter.
+func NewLogfmtFormatter(opts ...LogfmtOption) Formatter {
+	f := &logfmtFormatter{timeLayout: time.RFC3339Nano}
+	for _, o := range opts {
+		o(f)
+	}
+
+	return f.format
+}
+
+// LogfmtFormatter writes an Event as a logfmt line, in this order:
+//
+//	time=2006-01-02T15:04:05.999999999Z07:00 level=info logger=db.pool caller=pool/conn.go:42 msg="..."
+//	trace_id=... span_id=... trace_flags=01 tag.<k>=<v>... <kvs k>=<v>... <fields k>=<v>... error="..."
+//	stack="..." extra=...
+//
+// The tags and kvs are sorted by key, the fields keep their order. The caller, trace context, error, stack and
+// extra are written only when set, and the root Logger is named "root". Values are quoted and escaped when
+// needed, characters not allowed in keys are replaced by '_'. A kv or field key colliding with one of the keys
+// above is prefixed with "kv." or "field.". The Bytes fields are encoded in base64.
+func LogfmtFormatter(e *easylog.Event) ([]byte, error) {
+	return defaultLogfmtFormatter.format(e)
+}
+
+func (f *logfmtFormatter) format(e *easylog.Event) ([]byte, error) {
+	b := make([]byte, 0, 256)
+
+	b = append(b, "time="...)
+	b = appendLogfmtValue(b, e.GetTime().Format(f.timeLayout))
+
+	b = append(b, " level="...)
+	b = append(b, strings.ToLower(e.GetLevel().String())...)
+
+	b = append(b, " logger="...)
+	b = appendLogfmtValue(b, loggerName(e.GetLogger().Name()))
+
+	if c := e.GetCaller(); c.GetOK() {
+		b = append(b, " caller="...)
+		b = appendLogfmtValue(b, trimmedPath(c.GetFile())+":"+strconv.Itoa(c.GetLine()))
+	}
+
+	b = append(b, " msg="...)
+	b = appendLogfmtValue(b, e.GetMsg())
+
+	if t := e.GetTrace(); t.IsValid() {
+		b = append(b, " "+traceIDKey+"="...)
+		b = append(b, t.TraceIDString()...)
+		b = append(b, " "+spanIDKey+"="...)
+		b = append(b, t.SpanIDString()...)
+		b = append(b, " "+traceFlagsKey+"="...)
+		b = append(b, t.FlagsString()...)
+	}
+
+	b = appendLogfmtMap(b, "tag.", e.GetTags())
+	b = appendLogfmtMap(b, "", e.GetKvs())
+
+	fields := e.GetFields()
+	var text []byte
+	for i := range fields {
+		b = append(b, ' ')
+		if logfmtReserved[fields[i].Key] {
+			b = append(b, fieldKeyPrefix...)
+		}
+		b = appendLogfmtKey(b, fields[i].Key)
+		b = append(b, '=')
+		text = appendFieldText(text[:0], &fields[i])
+		b = appendLogfmtValue(b, string(text))
+	}
+
+	if err := e.GetError(); err != nil {
+		b = append(b, " error="...)
+		b = appendLogfmtValue(b, err.Error())
+	}
+
+	if stack := e.GetStack(); stack != "" {
+		b = append(b, " stack="...)
+		b = appendLogfmtValue(b, stack)
+	}
+
+	if extra := e.GetExtra(); extra != nil {
+		b = append(b, " extra="...)
+		b = appendLogfmtValue(b, fmt.Sprint(extra))
+	}
+
+	return b, nil
+}
+
+// logfmtReserved are the keys written by LogfmtFormatter, the kvs and fields keys can't take.
+var logfmtReserved = map[string]bool{
+	"time": true, "level": true, "logger": true, "caller": true, "msg": true, traceIDKey: true, spanIDKey: true,
+	traceFlagsKey: true, "error": true, "stack": true, "extra": true,
+}
+
+// appendLogfmtMap appends the pairs of m sorted by key, each key prefixed with prefix, or with "kv." when
+// there's no prefix and the key is reserved.
+func appendLogfmtMap(dst []byte, prefix string, m map[interface{}]interface{}) []byte {
+	if len(m) == 0 {
+		return dst
+	}
+
+	keys := make([]string, 0, len(m))
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		s := fmt.Sprint(k)
+		keys = append(keys, s)
+		values[s] = v
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		dst = append(dst, ' ')
+		if prefix == "" && logfmtReserved[k] {
+			dst = append(dst, kvKeyPrefix...)
+		}
+		dst = appendLogfmtKey(dst, prefix+k)
+		dst = append(dst, '=')
+		dst = appendLogfmtValue(dst, fmt.Sprint(values[k]))
+	}
+
+	return dst
+}
+
+// appendLogfmtKey appends k with the characters not allowed in a logfmt key replaced by '_'.
+func appendLogfmtKey(dst []byte, k string) []byte {
+	if k == "" {
+		return append(dst, '_')
+	}
+
+	for _, r := range k {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			dst = append(dst, '_')
+		} else {
+			dst = utf8.AppendRune(dst, r)
+		}
+	}
+
+	return dst
+}
+
+// appendLogfmtValue appends v, quoted and escaped if it is empty or holds a space, '=', '"',
+// a control character or invalid UTF-8.
+func appendLogfmtValue(dst []byte, v string) []byte {
+	if needsLogfmtQuote(v) {
+		return appendJSONString(dst, v)
+	}
+
+	return append(dst, v...)
+}
+
+func needsLogfmtQuote(v string) bool {
+	if v == "" {
+		return true
+	}
+
+	for i := 0; i < len(v); {
+		c := v[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(v[i:])
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+
+	return false
+}
+
+// trimmedPath keeps the last directory and the file name of a path.
+func trimmedPath(file string) string {
+	i := strings.LastIndexByte(file, '/')
+	if i < 0 {
+		return file
+	}
+
+	j := strings.LastIndexByte(file[:i], '/')
+	if j < 0 {
+		return file
+	}
+
+	return file[j+1:]
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	logger := easylog.GetLogger("db.pool")
+	c := &formatterCapture{f: NewLogfmtFormatter(WithLogfmtTimeLayout(time.RFC3339))}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Warn().
+		Tag("zone", "eu west").
+		Tag("app", "api").
+		Kv("b", 2).
+		Kv("a", "x=y").
+		Str("path", `C:\tmp`).
+		Str("quote", `say "hi"`).
+		Str("empty", "").
+		Str("bad key", "\xff").
+		Dur("took", 1500*time.Millisecond).
+		E(errors.New("conn\nrefused")).
+		Attach([]int{1, 2}).
+		Logf("pool %s", "exhausted")
+
+	s := string(c.b)
+	assert.True(t, strings.HasPrefix(s, "time="), s)
+	i := strings.Index(s, " level=")
+	_, err := time.Parse(time.RFC3339, s[len("time="):i])
+	assert.Nil(t, err)
+
+	assert.Equal(t,
+		` level=warn logger=db.pool msg="pool exhausted" tag.app=api tag.zone="eu west" a="x=y" b=2`+
+			` path=C:\tmp quote="say \"hi\"" empty="" bad_key="`+"\ufffd"+`" took=1.5s error="conn\nrefused" extra="[1 2]"`,
+		s[i:],
+	)
+}
+
+func TestLogfmtFormatterRootCallerStack(t *testing.T) {
+	logger := easylog.GetLogger("")
+	c := &formatterCapture{f: LogfmtFormatter}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.EnableCaller(easylog.ERROR)
+	defer logger.DisableCaller(easylog.ERROR)
+	logger.EnableStack(easylog.ERROR)
+	defer logger.DisableStack(easylog.ERROR)
+
+	logger.Error().Log()
+
+	s := string(c.b)
+	assert.Contains(t, s, " level=error logger=root caller=handler/logfmt_test.go:")
+	assert.Contains(t, s, ` msg="" stack="`)
+	assert.NotContains(t, s, "\n")
+}
+
+func TestLogfmtFormatterReservedKeys(t *testing.T) {
+	logger := easylog.GetLogger("logfmt.reserved")
+	c := &formatterCapture{f: LogfmtFormatter}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Info().Kv("msg", "kv").Kv("k", 1).Str("level", "field").Str("time", "t").Str("f", "x").Log()
+
+	s := string(c.b)
+	assert.True(t, strings.HasSuffix(s,
+		` level=info logger=logfmt.reserved msg="" k=1 kv.msg=kv field.level=field field.time=t f=x`), s)
+}
+
+func TestLogfmtQuoting(t *testing.T) {
+	for v, quoted := range map[string]bool{
+		"plain":     false,
+		"中文":        false,
+		"a-b/c:d.e": false,
+		"":          true,
+		"a b":       true,
+		"a=b":       true,
+		`a"b`:       true,
+		"a\tb":      true,
+		"a\x7fb":    true,
+		"\xff":      true,
+		"\u2028":    true,
+	} {
+		assert.Equal(t, quoted, needsLogfmtQuote(v), v)
+	}
+
+	assert.Equal(t, "a_b_c_d", string(appendLogfmtKey(nil, "a b=c\"d")))
+	assert.Equal(t, "_", string(appendLogfmtKey(nil, "")))
+	assert.Equal(t, "handler/logfmt.go", trimmedPath("/src/easylog/handler/logfmt.go"))
+	assert.Equal(t, "/logfmt.go", trimmedPath("/logfmt.go"))
+	assert.Equal(t, "logfmt.go", trimmedPath("logfmt.go"))
+}
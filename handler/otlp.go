@@ -0,0 +1,664 @@
+package handler
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/binary"
+	enchex "encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/covine/easylog"
+)
+
+// the wire types of protobuf
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// OTLPEncoding is the encoding of the OTLP/HTTP requests.
+type OTLPEncoding int
+
+const (
+	// OTLPProtobuf encodes the requests with protobuf, as application/x-protobuf.
+	OTLPProtobuf OTLPEncoding = iota
+	// OTLPJSON encodes the requests with the JSON mapping of protobuf, as application/json.
+	OTLPJSON
+)
+
+// OTLPEncoder converts the Events into the LogRecords of the OpenTelemetry logs data model, and encodes the
+// batches as ExportLogsServiceRequests of OTLP/HTTP. Its Format method is the Formatter of the batches.
+//
+// A LogRecord has the time of the Event, its severity number and text from the level, and its message as body.
+// The levels are mapped to the severities: DEBUG to DEBUG (5), INFO to INFO (9), WARN to WARN (13),
+// ERROR to ERROR (17), PANIC to FATAL (21) and FATAL to FATAL4 (24).
+// The tags, the kvs and the fields are the attributes, with the error of the Event as exception.message,
+// its stack as exception.stacktrace, and its caller as code.filepath, code.lineno and code.function.
+// The trace context of the LogRecord is the one of the Event, see easylog.Event.GetTrace, otherwise the tags,
+// kvs or string fields named trace_id, span_id and trace_flags, in hex, are the trace context instead of
+// attributes.
+//
+// The LogRecords of a batch are grouped by Logger, as the name of their instrumentation scope.
+type OTLPEncoder struct {
+	encoding OTLPEncoding
+	resource []otlpKeyValue
+}
+
+// OTLPOption configures an OTLPEncoder.
+type OTLPOption func(*OTLPEncoder)
+
+// WithOTLPEncoding encodes the requests with encoding, OTLPProtobuf by default.
+func WithOTLPEncoding(encoding OTLPEncoding) OTLPOption {
+	return func(o *OTLPEncoder) {
+		o.encoding = encoding
+	}
+}
+
+// WithOTLPResource sets the attributes of the resource emitting the logs, like service.name.
+func WithOTLPResource(attributes map[string]interface{}) OTLPOption {
+	return func(o *OTLPEncoder) {
+		keys := make([]string, 0, len(attributes))
+		for k := range attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		o.resource = o.resource[:0]
+		for _, k := range keys {
+			o.resource = append(o.resource, otlpKeyValue{key: k, value: otlpAnyValue(attributes[k], 0)})
+		}
+	}
+}
+
+func NewOTLPEncoder(opts ...OTLPOption) *OTLPEncoder {
+	o := &OTLPEncoder{}
+
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// NewOTLPHandler exports the Events to an OTLP/HTTP endpoint, like http://localhost:4318/v1/logs,
+// with an HTTPBatchHandler configured by opts.
+func NewOTLPHandler(endpoint string, enc *OTLPEncoder, opts ...HTTPBatchOption) (*HTTPBatchHandler, error) {
+	if enc == nil {
+		enc = NewOTLPEncoder()
+	}
+
+	opts = append([]HTTPBatchOption{WithHTTPBatchEncoder(enc), WithHTTPBatchFormatter(enc.Format)}, opts...)
+
+	return NewHTTPBatchHandler(endpoint, opts...)
+}
+
+// Format encodes e as a LogRecord.
+func (o *OTLPEncoder) Format(e *easylog.Event) ([]byte, error) {
+	r := newOTLPLogRecord(e)
+
+	if o.encoding == OTLPJSON {
+		b := easylog.NewBytes()
+		defer easylog.PutBytes(b)
+
+		r.writeJSON(b)
+
+		return append(make([]byte, 0, b.Len()), b.Bytes()...), nil
+	}
+
+	return r.appendProto(nil), nil
+}
+
+func (o *OTLPEncoder) ContentType() string {
+	if o.encoding == OTLPJSON {
+		return "application/json"
+	}
+	return "application/x-protobuf"
+}
+
+// Encode writes the LogRecords formatted by Format as an ExportLogsServiceRequest.
+func (o *OTLPEncoder) Encode(w io.Writer, records []BatchRecord) error {
+	type scope struct {
+		name    string
+		records []int
+	}
+
+	// the scopes in the order they first appear
+	var scopes []*scope
+	index := make(map[string]*scope)
+	for i := range records {
+		s, ok := index[records[i].Logger]
+		if !ok {
+			s = &scope{name: records[i].Logger}
+			index[s.name] = s
+			scopes = append(scopes, s)
+		}
+		s.records = append(s.records, i)
+	}
+
+	if o.encoding == OTLPJSON {
+		b := easylog.NewBytes()
+		defer easylog.PutBytes(b)
+
+		b.AppendString(`{"resourceLogs":[{"resource":{"attributes":`)
+		writeOTLPKeyValuesJSON(b, o.resource)
+		b.AppendString(`},"scopeLogs":[`)
+		for i, s := range scopes {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			b.AppendString(`{"scope":{"name":`)
+			writeJSONString(b, s.name)
+			b.AppendString(`},"logRecords":[`)
+			for j, r := range s.records {
+				if j > 0 {
+					b.AppendByte(',')
+				}
+				_, _ = b.Write(records[r].Line)
+			}
+			b.AppendString(`]}`)
+		}
+		b.AppendString(`]}]}`)
+
+		_, err := w.Write(b.Bytes())
+		return err
+	}
+
+	var resource []byte
+	for i := range o.resource {
+		resource = appendProtoBytes(resource, 1, o.resource[i].appendProto(nil))
+	}
+
+	// ResourceLogs: resource = 1, scope_logs = 2
+	resourceLogs := appendProtoBytes(nil, 1, resource)
+	for _, s := range scopes {
+		// ScopeLogs: scope = 1, log_records = 2
+		scopeLogs := appendProtoBytes(nil, 1, appendProtoString(nil, 1, s.name))
+		for _, r := range s.records {
+			scopeLogs = appendProtoBytes(scopeLogs, 2, records[r].Line)
+		}
+		resourceLogs = appendProtoBytes(resourceLogs, 2, scopeLogs)
+	}
+
+	// ExportLogsServiceRequest: resource_logs = 1
+	_, err := w.Write(appendProtoBytes(nil, 1, resourceLogs))
+	return err
+}
+
+// otlpSeverity returns the severity number of level.
+func otlpSeverity(level easylog.Level) int {
+	switch {
+	case level <= easylog.DEBUG:
+		return 5
+	case level == easylog.INFO:
+		return 9
+	case level == easylog.WARN:
+		return 13
+	case level == easylog.ERROR:
+		return 17
+	case level == easylog.PANIC:
+		return 21
+	default:
+		return 24
+	}
+}
+
+// otlpLogRecord is a LogRecord of OTLP.
+type otlpLogRecord struct {
+	time         time.Time
+	observedTime time.Time
+	severity     int
+	severityText string
+	body         otlpValue
+	attributes   []otlpKeyValue
+	flags        uint32
+	traceID      []byte
+	spanID       []byte
+}
+
+func newOTLPLogRecord(e *easylog.Event) *otlpLogRecord {
+	r := &otlpLogRecord{
+		time:         e.GetTime(),
+		observedTime: time.Now(),
+		severity:     otlpSeverity(e.GetLevel()),
+		severityText: e.GetLevel().String(),
+		body:         otlpValue{kind: otlpString, s: e.GetMsg()},
+	}
+
+	r.addMap(e.GetTags())
+	r.addMap(e.GetKvs())
+
+	fields := e.GetFields()
+	for i := range fields {
+		f := &fields[i]
+		if f.Type == easylog.StringType && r.traceContext(f.Key, f.String) {
+			continue
+		}
+		r.attributes = append(r.attributes, otlpKeyValue{key: f.Key, value: otlpFieldValue(f)})
+	}
+
+	if t := e.GetTrace(); t.IsValid() {
+		r.traceID = append([]byte(nil), t.TraceID[:]...)
+		r.spanID = append([]byte(nil), t.SpanID[:]...)
+		r.flags = uint32(t.Flags)
+	}
+
+	if err := e.GetError(); err != nil {
+		r.addString("exception.message", err.Error())
+	}
+	if stack := e.GetStack(); stack != "" {
+		r.addString("exception.stacktrace", stack)
+	}
+	if extra := e.GetExtra(); extra != nil {
+		r.attributes = append(r.attributes, otlpKeyValue{key: "extra", value: otlpAnyValue(extra, 0)})
+	}
+	if c := e.GetCaller(); c.GetOK() {
+		r.addString("code.filepath", c.GetFile())
+		r.attributes = append(r.attributes, otlpKeyValue{
+			key:   "code.lineno",
+			value: otlpValue{kind: otlpInt, i: int64(c.GetLine())},
+		})
+		r.addString("code.function", c.GetFunc())
+	}
+
+	return r
+}
+
+func (r *otlpLogRecord) addString(key, s string) {
+	r.attributes = append(r.attributes, otlpKeyValue{key: key, value: otlpValue{kind: otlpString, s: s}})
+}
+
+// addMap adds the tags or kvs of an Event, sorted by key.
+func (r *otlpLogRecord) addMap(m map[interface{}]interface{}) {
+	keys := make([]string, 0, len(m))
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		key := fmt.Sprint(k)
+		keys = append(keys, key)
+		values[key] = v
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if s, ok := values[k].(string); ok && r.traceContext(k, s) {
+			continue
+		}
+		r.attributes = append(r.attributes, otlpKeyValue{key: k, value: otlpAnyValue(values[k], 0)})
+	}
+}
+
+// traceContext sets the trace context from the attribute key, if it's one of them and valid.
+func (r *otlpLogRecord) traceContext(key, s string) bool {
+	switch key {
+	case traceIDKey:
+		id, err := enchex.DecodeString(s)
+		if err != nil || len(id) != 16 {
+			return false
+		}
+		r.traceID = id
+	case spanIDKey:
+		id, err := enchex.DecodeString(s)
+		if err != nil || len(id) != 8 {
+			return false
+		}
+		r.spanID = id
+	case traceFlagsKey:
+		flags, err := strconv.ParseUint(s, 16, 8)
+		if err != nil {
+			return false
+		}
+		r.flags = uint32(flags)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// appendProto appends r encoded with protobuf.
+func (r *otlpLogRecord) appendProto(b []byte) []byte {
+	b = appendProtoFixed64(b, 1, uint64(r.time.UnixNano()))
+	b = appendProtoVarint(b, 2, uint64(r.severity))
+	b = appendProtoString(b, 3, r.severityText)
+	b = appendProtoBytes(b, 5, r.body.appendProto(nil))
+	for i := range r.attributes {
+		b = appendProtoBytes(b, 6, r.attributes[i].appendProto(nil))
+	}
+	if r.flags != 0 {
+		b = appendProtoFixed32(b, 8, r.flags)
+	}
+	if r.traceID != nil {
+		b = appendProtoBytes(b, 9, r.traceID)
+	}
+	if r.spanID != nil {
+		b = appendProtoBytes(b, 10, r.spanID)
+	}
+	b = appendProtoFixed64(b, 11, uint64(r.observedTime.UnixNano()))
+
+	return b
+}
+
+// writeJSON writes r with the JSON mapping of protobuf.
+func (r *otlpLogRecord) writeJSON(b *easylog.Bytes) {
+	b.AppendString(`{"timeUnixNano":"`)
+	b.AppendInt(r.time.UnixNano())
+	b.AppendString(`","observedTimeUnixNano":"`)
+	b.AppendInt(r.observedTime.UnixNano())
+	b.AppendString(`","severityNumber":`)
+	b.AppendInt(int64(r.severity))
+	b.AppendString(`,"severityText":`)
+	writeJSONString(b, r.severityText)
+	b.AppendString(`,"body":`)
+	r.body.writeJSON(b)
+	b.AppendString(`,"attributes":`)
+	writeOTLPKeyValuesJSON(b, r.attributes)
+	if r.flags != 0 {
+		b.AppendString(`,"flags":`)
+		b.AppendUint(uint64(r.flags))
+	}
+	// the ids are in hex, unlike the other bytes
+	if r.traceID != nil {
+		b.AppendString(`,"traceId":"`)
+		b.AppendString(enchex.EncodeToString(r.traceID))
+		b.AppendByte('"')
+	}
+	if r.spanID != nil {
+		b.AppendString(`,"spanId":"`)
+		b.AppendString(enchex.EncodeToString(r.spanID))
+		b.AppendByte('"')
+	}
+	b.AppendByte('}')
+}
+
+type otlpKind uint8
+
+const (
+	otlpEmpty otlpKind = iota
+	otlpString
+	otlpBool
+	otlpInt
+	otlpDouble
+	otlpArray
+	otlpKvlist
+	otlpBytes
+)
+
+// otlpValue is an AnyValue of OTLP.
+type otlpValue struct {
+	kind  otlpKind
+	s     string
+	i     int64
+	f     float64
+	b     []byte
+	array []otlpValue
+	kvs   []otlpKeyValue
+}
+
+// otlpKeyValue is a KeyValue of OTLP.
+type otlpKeyValue struct {
+	key   string
+	value otlpValue
+}
+
+// otlpFieldValue converts the value of a typed field.
+func otlpFieldValue(f *easylog.Field) otlpValue {
+	switch f.Type {
+	case easylog.StringType:
+		return otlpValue{kind: otlpString, s: f.String}
+	case easylog.BytesType:
+		return otlpValue{kind: otlpBytes, b: []byte(f.String)}
+	case easylog.IntType:
+		return otlpValue{kind: otlpInt, i: f.GetInt()}
+	case easylog.Float64Type:
+		return otlpValue{kind: otlpDouble, f: f.GetFloat64()}
+	case easylog.BoolType:
+		return otlpValue{kind: otlpBool, i: f.Integer}
+	case easylog.StringsType:
+		return otlpAnyValue(f.GetStrings(), 0)
+	case easylog.ErrorType:
+		if err := f.GetError(); err != nil {
+			return otlpValue{kind: otlpString, s: err.Error()}
+		}
+		return otlpValue{}
+	default:
+		return otlpAnyValue(f.Value(), 0)
+	}
+}
+
+// otlpAnyValue converts v by type: the numbers, strings, bools and bytes as such, the times and durations
+// as strings, the slices as arrays and the maps as kvlists. The others are encoded with encoding/json.
+func otlpAnyValue(v interface{}, depth int) otlpValue {
+	if depth > maxJSONDepth {
+		return otlpValue{}
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return otlpValue{}
+	case string:
+		return otlpValue{kind: otlpString, s: v}
+	case bool:
+		if v {
+			return otlpValue{kind: otlpBool, i: 1}
+		}
+		return otlpValue{kind: otlpBool}
+	case int:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case int8:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case int16:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case int32:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case int64:
+		return otlpValue{kind: otlpInt, i: v}
+	case uint:
+		return otlpUint(uint64(v))
+	case uint8:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case uint16:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case uint32:
+		return otlpValue{kind: otlpInt, i: int64(v)}
+	case uint64:
+		return otlpUint(v)
+	case float32:
+		return otlpValue{kind: otlpDouble, f: float64(v)}
+	case float64:
+		return otlpValue{kind: otlpDouble, f: v}
+	case []byte:
+		return otlpValue{kind: otlpBytes, b: v}
+	case time.Time:
+		return otlpValue{kind: otlpString, s: v.Format(time.RFC3339Nano)}
+	case time.Duration:
+		return otlpValue{kind: otlpString, s: v.String()}
+	case json.Marshaler:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return otlpValue{kind: otlpString, s: fmt.Sprint(v)}
+		}
+		return otlpValue{kind: otlpString, s: string(raw)}
+	case encoding.TextMarshaler:
+		t, err := v.MarshalText()
+		if err != nil {
+			return otlpValue{kind: otlpString, s: err.Error()}
+		}
+		return otlpValue{kind: otlpString, s: string(t)}
+	case error:
+		return otlpValue{kind: otlpString, s: v.Error()}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return otlpValue{}
+		}
+		array := make([]otlpValue, rv.Len())
+		for i := range array {
+			array[i] = otlpAnyValue(rv.Index(i).Interface(), depth+1)
+		}
+		return otlpValue{kind: otlpArray, array: array}
+	case reflect.Map:
+		if rv.IsNil() {
+			return otlpValue{}
+		}
+		kvs := make([]otlpKeyValue, 0, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			kvs = append(kvs, otlpKeyValue{
+				key:   fmt.Sprint(iter.Key().Interface()),
+				value: otlpAnyValue(iter.Value().Interface(), depth+1),
+			})
+		}
+		sort.Slice(kvs, func(i, j int) bool {
+			return kvs[i].key < kvs[j].key
+		})
+		return otlpValue{kind: otlpKvlist, kvs: kvs}
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return otlpValue{}
+		}
+		return otlpAnyValue(rv.Elem().Interface(), depth+1)
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return otlpValue{kind: otlpString, s: fmt.Sprint(v)}
+	}
+	return otlpValue{kind: otlpString, s: string(raw)}
+}
+
+// otlpUint converts an unsigned integer, as a double beyond the range of int64.
+func otlpUint(u uint64) otlpValue {
+	if u > math.MaxInt64 {
+		return otlpValue{kind: otlpDouble, f: float64(u)}
+	}
+	return otlpValue{kind: otlpInt, i: int64(u)}
+}
+
+func (v *otlpValue) appendProto(b []byte) []byte {
+	switch v.kind {
+	case otlpString:
+		return appendProtoString(b, 1, v.s)
+	case otlpBool:
+		return appendProtoVarint(b, 2, uint64(v.i))
+	case otlpInt:
+		return appendProtoVarint(b, 3, uint64(v.i))
+	case otlpDouble:
+		return appendProtoFixed64(b, 4, math.Float64bits(v.f))
+	case otlpArray:
+		var array []byte
+		for i := range v.array {
+			array = appendProtoBytes(array, 1, v.array[i].appendProto(nil))
+		}
+		return appendProtoBytes(b, 5, array)
+	case otlpKvlist:
+		var kvs []byte
+		for i := range v.kvs {
+			kvs = appendProtoBytes(kvs, 1, v.kvs[i].appendProto(nil))
+		}
+		return appendProtoBytes(b, 6, kvs)
+	case otlpBytes:
+		return appendProtoBytes(b, 7, v.b)
+	default:
+		return b
+	}
+}
+
+func (v *otlpValue) writeJSON(b *easylog.Bytes) {
+	switch v.kind {
+	case otlpString:
+		b.AppendString(`{"stringValue":`)
+		writeJSONString(b, v.s)
+	case otlpBool:
+		b.AppendString(`{"boolValue":`)
+		b.AppendBool(v.i == 1)
+	case otlpInt:
+		b.AppendString(`{"intValue":"`)
+		b.AppendInt(v.i)
+		b.AppendByte('"')
+	case otlpDouble:
+		b.AppendString(`{"doubleValue":`)
+		writeJSONFloat(b, v.f, 64)
+	case otlpArray:
+		b.AppendString(`{"arrayValue":{"values":[`)
+		for i := range v.array {
+			if i > 0 {
+				b.AppendByte(',')
+			}
+			v.array[i].writeJSON(b)
+		}
+		b.AppendString(`]}`)
+	case otlpKvlist:
+		b.AppendString(`{"kvlistValue":{"values":`)
+		writeOTLPKeyValuesJSON(b, v.kvs)
+		b.AppendByte('}')
+	case otlpBytes:
+		b.AppendString(`{"bytesValue":"`)
+		b.AppendString(base64.StdEncoding.EncodeToString(v.b))
+		b.AppendByte('"')
+	default:
+		b.AppendByte('{')
+	}
+	b.AppendByte('}')
+}
+
+func (kv *otlpKeyValue) appendProto(b []byte) []byte {
+	b = appendProtoString(b, 1, kv.key)
+	return appendProtoBytes(b, 2, kv.value.appendProto(nil))
+}
+
+func writeOTLPKeyValuesJSON(b *easylog.Bytes, kvs []otlpKeyValue) {
+	b.AppendByte('[')
+	for i := range kvs {
+		if i > 0 {
+			b.AppendByte(',')
+		}
+		b.AppendString(`{"key":`)
+		writeJSONString(b, kvs[i].key)
+		b.AppendString(`,"value":`)
+		kvs[i].value.writeJSON(b)
+		b.AppendByte('}')
+	}
+	b.AppendByte(']')
+}
+
+func appendProtoKey(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	b = appendProtoKey(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoFixed64(b []byte, field int, v uint64) []byte {
+	b = appendProtoKey(b, field, protoFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func appendProtoFixed32(b []byte, field int, v uint32) []byte {
+	b = appendProtoKey(b, field, protoFixed32)
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+func appendProtoBytes(b []byte, field int, p []byte) []byte {
+	b = appendProtoKey(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(p)))
+	return append(b, p...)
+}
+
+func appendProtoString(b []byte, field int, s string) []byte {
+	b = appendProtoKey(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"encoding/binary"
+	enchex "encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// otlpCollector is an OTLP/HTTP collector stub, recording the requests to /v1/logs.
+type otlpCollector struct {
+	*httptest.Server
+
+	mu           sync.Mutex
+	bodies       [][]byte
+	contentTypes []string
+}
+
+func newOTLPCollector() *otlpCollector {
+	c := &otlpCollector{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.bodies = append(c.bodies, b)
+		c.contentTypes = append(c.contentTypes, r.Header.Get("Content-Type"))
+	})
+	c.Server = httptest.NewServer(mux)
+	return c
+}
+
+func (c *otlpCollector) Request(i int) ([]byte, string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bodies[i], c.contentTypes[i]
+}
+
+// protoField is a field decoded from the protobuf wire format, with its varint, fixed or bytes value.
+type protoField struct {
+	num   int
+	value uint64
+	bytes []byte
+}
+
+// decodeProto decodes the fields of a message.
+func decodeProto(t *testing.T, b []byte) []protoField {
+	var fields []protoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		assert.True(t, n > 0)
+		b = b[n:]
+
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case protoVarint:
+			f.value, n = binary.Uvarint(b)
+			b = b[n:]
+		case protoFixed64:
+			f.value = binary.LittleEndian.Uint64(b)
+			b = b[8:]
+		case protoFixed32:
+			f.value = uint64(binary.LittleEndian.Uint32(b))
+			b = b[4:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			f.bytes = b[n : n+int(l)]
+			b = b[n+int(l):]
+		default:
+			t.Fatalf("wire type %d", key&7)
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// protoGet returns the fields numbered num.
+func protoGet(fields []protoField, num int) []protoField {
+	var found []protoField
+	for _, f := range fields {
+		if f.num == num {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// protoAttributes decodes the string, int and double values of the KeyValues of a message.
+func protoAttributes(t *testing.T, fields []protoField, num int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for _, kv := range protoGet(fields, num) {
+		kvFields := decodeProto(t, kv.bytes)
+		key := string(protoGet(kvFields, 1)[0].bytes)
+		value := decodeProto(t, protoGet(kvFields, 2)[0].bytes)[0]
+		switch value.num {
+		case 1:
+			m[key] = string(value.bytes)
+		case 3:
+			m[key] = int64(value.value)
+		case 4:
+			m[key] = math.Float64frombits(value.value)
+		default:
+			m[key] = value
+		}
+	}
+	return m
+}
+
+func otlpEvent(level easylog.Level, msg string) *easylog.Event {
+	e := easylog.NewEvent(easylog.GetLogger("otlp"), level, time.Unix(1, 2), msg)
+	e.Tag("dc", "eu").Kv("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736").Kv("span_id", "00f067aa0ba902b7").
+		Kv("trace_flags", "01").Int("n", 7).Float64("f", 0.5).E(errors.New("boom"))
+	return e
+}
+
+func TestOTLPProtobuf(t *testing.T) {
+	c := newOTLPCollector()
+	defer c.Close()
+
+	enc := NewOTLPEncoder(WithOTLPResource(map[string]interface{}{"service.name": "api", "replicas": 3}))
+	h, err := NewOTLPHandler(c.URL+"/v1/logs", enc)
+	assert.Nil(t, err)
+
+	e := otlpEvent(easylog.WARN, "disk full")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Nil(t, h.Close())
+
+	body, contentType := c.Request(0)
+	assert.Equal(t, "application/x-protobuf", contentType)
+
+	resourceLogs := decodeProto(t, protoGet(decodeProto(t, body), 1)[0].bytes)
+	resource := decodeProto(t, protoGet(resourceLogs, 1)[0].bytes)
+	assert.Equal(t, map[string]interface{}{"service.name": "api", "replicas": int64(3)},
+		protoAttributes(t, resource, 1))
+
+	scopeLogs := decodeProto(t, protoGet(resourceLogs, 2)[0].bytes)
+	scope := decodeProto(t, protoGet(scopeLogs, 1)[0].bytes)
+	assert.Equal(t, "otlp", string(protoGet(scope, 1)[0].bytes))
+
+	record := decodeProto(t, protoGet(scopeLogs, 2)[0].bytes)
+	assert.Equal(t, uint64(1000000002), protoGet(record, 1)[0].value)
+	assert.Equal(t, uint64(13), protoGet(record, 2)[0].value)
+	assert.Equal(t, "WARN", string(protoGet(record, 3)[0].bytes))
+	assert.Equal(t, "disk full", string(decodeProto(t, protoGet(record, 5)[0].bytes)[0].bytes))
+	assert.Equal(t, map[string]interface{}{"dc": "eu", "n": int64(7), "f": 0.5, "exception.message": "boom"},
+		protoAttributes(t, record, 6))
+	assert.Equal(t, uint64(1), protoGet(record, 8)[0].value)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", enchex.EncodeToString(protoGet(record, 9)[0].bytes))
+	assert.Equal(t, "00f067aa0ba902b7", enchex.EncodeToString(protoGet(record, 10)[0].bytes))
+	assert.NotZero(t, protoGet(record, 11)[0].value)
+}
+
+func TestOTLPJSON(t *testing.T) {
+	c := newOTLPCollector()
+	defer c.Close()
+
+	enc := NewOTLPEncoder(WithOTLPEncoding(OTLPJSON), WithOTLPResource(map[string]interface{}{"service.name": "api"}))
+	h, err := NewOTLPHandler(c.URL+"/v1/logs", enc)
+	assert.Nil(t, err)
+
+	e := otlpEvent(easylog.FATAL, "down")
+	e.Kv("list", []interface{}{"a", true}).Kv("map", map[string]int{"x": 1}).Bytes("raw", []byte("hi"))
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Nil(t, h.Close())
+
+	body, contentType := c.Request(0)
+	assert.Equal(t, "application/json", contentType)
+
+	var req struct {
+		ResourceLogs []struct {
+			Resource  json.RawMessage
+			ScopeLogs []struct {
+				Scope      struct{ Name string }
+				LogRecords []map[string]json.RawMessage
+			}
+		}
+	}
+	assert.Nil(t, json.Unmarshal(body, &req))
+	assert.Equal(t, `{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]}`,
+		string(req.ResourceLogs[0].Resource))
+	assert.Equal(t, "otlp", req.ResourceLogs[0].ScopeLogs[0].Scope.Name)
+
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	assert.Equal(t, `"1000000002"`, string(record["timeUnixNano"]))
+	assert.Equal(t, `24`, string(record["severityNumber"]))
+	assert.Equal(t, `"FATAL"`, string(record["severityText"]))
+	assert.Equal(t, `{"stringValue":"down"}`, string(record["body"]))
+	assert.Equal(t, `1`, string(record["flags"]))
+	assert.Equal(t, `"4bf92f3577b34da6a3ce929d0e0e4736"`, string(record["traceId"]))
+	assert.Equal(t, `"00f067aa0ba902b7"`, string(record["spanId"]))
+	assert.Equal(t, `[`+
+		`{"key":"dc","value":{"stringValue":"eu"}},`+
+		`{"key":"list","value":{"arrayValue":{"values":[{"stringValue":"a"},{"boolValue":true}]}}},`+
+		`{"key":"map","value":{"kvlistValue":{"values":[{"key":"x","value":{"intValue":"1"}}]}}},`+
+		`{"key":"n","value":{"intValue":"7"}},`+
+		`{"key":"f","value":{"doubleValue":0.5}},`+
+		`{"key":"raw","value":{"bytesValue":"aGk="}},`+
+		`{"key":"exception.message","value":{"stringValue":"boom"}}]`,
+		string(record["attributes"]))
+}
+
+func TestOTLPSeverity(t *testing.T) {
+	for level, severity := range map[easylog.Level]int{
+		easylog.DEBUG: 5, easylog.INFO: 9, easylog.WARN: 13, easylog.ERROR: 17, easylog.PANIC: 21, easylog.FATAL: 24,
+	} {
+		assert.Equal(t, severity, otlpSeverity(level), level.String())
+	}
+
+	// invalid trace ids are attributes
+	e := easylog.NewEvent(easylog.GetLogger("otlp"), easylog.INFO, time.Unix(1, 0), "")
+	defer e.Put()
+	e.Str("trace_id", "nope")
+	r := newOTLPLogRecord(e)
+	assert.Nil(t, r.traceID)
+	assert.Equal(t, []otlpKeyValue{{key: "trace_id", value: otlpValue{kind: otlpString, s: "nope"}}}, r.attributes)
+}
+
+func TestOTLPConfig(t *testing.T) {
+	c := newOTLPCollector()
+	defer c.Close()
+
+	h, err := newOTLPHandlerFromConfig(easylog.HandlerConfig{
+		Type: "otlp",
+		Options: map[string]interface{}{
+			"url": c.URL + "/v1/logs", "encoding": "json", "resource": map[string]interface{}{"service.name": "api"},
+			"max_age": "1h",
+		},
+	})
+	assert.Nil(t, err)
+
+	e := easylog.NewEvent(easylog.GetLogger("otlp"), easylog.INFO, time.Unix(1, 0), "up")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Nil(t, h.Close())
+
+	body, contentType := c.Request(0)
+	assert.Equal(t, "application/json", contentType)
+	assert.Contains(t, string(body), `"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"api"}}]}`)
+
+	for _, opts := range []map[string]interface{}{
+		{"url": c.URL, "encoding": "thrift"},
+		{"url": c.URL, "resource": "api"},
+		{"url": c.URL, "labels": "dc"},
+	} {
+		_, err := newOTLPHandlerFromConfig(easylog.HandlerConfig{Type: "otlp", Options: opts})
+		assert.NotNil(t, err)
+	}
+}
@@ -2,6 +2,9 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/covine/easylog"
@@ -18,6 +21,82 @@ type Puller interface {
 	Next() diode.GenericDataType
 }
 
+// Overflow is what a RingBufferHandler does with an Event when its buffer is full.
+type Overflow int
+
+const (
+	// OverflowDropOldest overwrites the oldest Events not written yet, it never blocks.
+	OverflowDropOldest Overflow = iota
+	// OverflowDropNewest drops the Event, it never blocks.
+	OverflowDropNewest
+	// OverflowBlock blocks until there's space for the Event, or the handler is closed.
+	OverflowBlock
+	// OverflowBlockTimeout blocks until there's space for the Event, then drops it after a timeout.
+	OverflowBlockTimeout
+)
+
+var overflowNames = map[Overflow]string{
+	OverflowDropOldest:   "drop_oldest",
+	OverflowDropNewest:   "drop_newest",
+	OverflowBlock:        "block",
+	OverflowBlockTimeout: "block_timeout",
+}
+
+func (o Overflow) String() string {
+	if name, ok := overflowNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseOverflow parses the name of an Overflow, like "drop_newest".
+func ParseOverflow(name string) (Overflow, error) {
+	for o, n := range overflowNames {
+		if n == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow %q", name)
+}
+
+const (
+	defaultRingBlockTimeout = time.Second
+	defaultRingCloseTimeout = 5 * time.Second
+	maxRingRetryBackoff     = 30 * time.Second
+)
+
+// RingStats counts the Events of a RingBufferHandler.
+type RingStats struct {
+	// Enqueued is the number of Events put in the buffer.
+	Enqueued uint64
+	// Written is the number of Events written to the writer.
+	Written uint64
+	// Dropped is the number of Events lost on overflow, overwritten or not enqueued.
+	Dropped uint64
+	// Blocked is the number of Events which waited for space in the buffer, enqueued or not.
+	Blocked uint64
+	// Failed is the number of Events which could not be formatted or written.
+	Failed uint64
+}
+
+// DroppedError reports the Events a RingBufferHandler dropped on overflow.
+type DroppedError struct {
+	Count uint64
+}
+
+func (e *DroppedError) Error() string {
+	return fmt.Sprintf("ring buffer: dropped %d events", e.Count)
+}
+
+// RingBufferHandler formats and writes the Events to w in a goroutine, through a ring buffer of size Events.
+// By default, the oldest Events are overwritten when the buffer is full, see WithRingOverflow.
+//
+// As Handle returns before the Events are written, the failures to format or write them are reported to
+// the ErrorHandler of the RingBufferHandler, or of their Logger, and so are the counts of the dropped Events,
+// as DroppedError.
+//
+// Flush waits until the Events handled before are written, then flushes w.
+// Close writes the Events left, then closes w, within a deadline, see WithRingCloseTimeout.
 type RingBufferHandler struct {
 	diode  diode.Diode
 	puller Puller
@@ -25,21 +104,147 @@ type RingBufferHandler struct {
 	done   chan struct{}
 	format Formatter
 	w      *writer.BufWriter
+
+	overflow Overflow
+	timeout  time.Duration
+	alert    diode.AlertFunc
+	// slots holds a token per buffered Event, unless the oldest Events are overwritten
+	slots     chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// closing fences the Events handled while closing, under the write lock
+	closing      sync.RWMutex
+	isClosing    bool
+	closeTimeout time.Duration
+	abort        chan struct{}
+	abortOnce    sync.Once
+
+	// wmu serializes the use of w by the goroutine and by Flush and Close
+	wmu          sync.Mutex
+	writerClosed bool
+
+	flushInterval time.Duration
+	autoFlush     *writer.AutoFlush
+	flushOnLevel  bool
+	flushLevel    easylog.Level
+
+	// settled counts the Events enqueued which were written, failed or dropped, for Flush
+	settleMu sync.Mutex
+	settle   *sync.Cond
+	settled  uint64
+	exited   bool
+
+	errorHandler easylog.ErrorHandler
+	retries      int
+	backoff      time.Duration
+
+	enqueued atomic.Uint64
+	written  atomic.Uint64
+	dropped  atomic.Uint64
+	blocked  atomic.Uint64
+	failed   atomic.Uint64
+
+	// the Events dropped since the last report, with the Logger of the last one not enqueued
+	unreported atomic.Uint64
+	dropLogger atomic.Pointer[easylog.Logger]
+}
+
+// RingOption configures a RingBufferHandler.
+type RingOption func(*RingBufferHandler)
+
+// WithRingOverflow sets what is done with an Event when the buffer is full, OverflowDropOldest by default.
+func WithRingOverflow(o Overflow) RingOption {
+	return func(r *RingBufferHandler) {
+		r.overflow = o
+	}
 }
 
+// WithRingBlockTimeout blocks up to timeout for space in the full buffer, then drops the Event.
+// It implies OverflowBlockTimeout.
+func WithRingBlockTimeout(timeout time.Duration) RingOption {
+	return func(r *RingBufferHandler) {
+		r.overflow = OverflowBlockTimeout
+		r.timeout = timeout
+	}
+}
+
+// WithRingErrorHandler reports the errors to h instead of the ErrorHandler of the Logger of the Events.
+func WithRingErrorHandler(h easylog.ErrorHandler) RingOption {
+	return func(r *RingBufferHandler) {
+		r.errorHandler = h
+	}
+}
+
+// WithRingRetry retries a failed write up to retries times, waiting backoff before the first retry,
+// then twice as long before each next one, up to 30 seconds.
+// The writer must keep what it could not write, as writer.BufWriter does.
+func WithRingRetry(retries int, backoff time.Duration) RingOption {
+	return func(r *RingBufferHandler) {
+		r.retries = retries
+		r.backoff = backoff
+	}
+}
+
+// WithRingCloseTimeout bounds the time Close waits for the Events left to be written, 5 seconds by default.
+// The Events still left then are dropped.
+func WithRingCloseTimeout(timeout time.Duration) RingOption {
+	return func(r *RingBufferHandler) {
+		r.closeTimeout = timeout
+	}
+}
+
+// WithRingFlushInterval flushes w every interval, until Close, see writer.AutoFlush.
+// The errors are reported to the ErrorHandler set by WithRingErrorHandler, if any.
+// It's ignored if w is flushed periodically itself, see writer.WithBufFlushInterval.
+func WithRingFlushInterval(interval time.Duration) RingOption {
+	return func(r *RingBufferHandler) {
+		r.flushInterval = interval
+	}
+}
+
+// WithRingFlushLevel flushes w after writing each Event at level or above.
+func WithRingFlushLevel(level easylog.Level) RingOption {
+	return func(r *RingBufferHandler) {
+		r.flushOnLevel = true
+		r.flushLevel = level
+	}
+}
+
+// NewRingBufferHandler starts a goroutine writing the Events formatted by f to w until Close.
+// A nil f formats with StdFormatter.
+// alert is called with the number of Events overwritten, it may be nil.
 func NewRingBufferHandler(
-	w *writer.BufWriter, f Formatter, size int, alert diode.AlertFunc, pullInterval time.Duration,
+	w *writer.BufWriter, f Formatter, size int, alert diode.AlertFunc, pullInterval time.Duration, opts ...RingOption,
 ) *RingBufferHandler {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	r := &RingBufferHandler{
-		cancel: cancel,
-		done:   make(chan struct{}),
-		w:      w,
-		format: f,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		w:       w,
+		format:  f,
+		timeout: defaultRingBlockTimeout,
+		alert:   alert,
+		closed:  make(chan struct{}),
+
+		closeTimeout: defaultRingCloseTimeout,
+		abort:        make(chan struct{}),
 	}
+	r.settle = sync.NewCond(&r.settleMu)
 
-	d := diode.NewManyToOne(size, alert)
+	for _, o := range opts {
+		o(r)
+	}
+	if r.format == nil {
+		r.format = StdFormatter
+	}
+
+	if r.overflow != OverflowDropOldest {
+		r.slots = make(chan struct{}, size)
+	}
+
+	d := diode.NewManyToOne(size, diode.AlertFunc(r.overwritten))
 
 	if pullInterval > 0 {
 		r.puller = diode.NewPoller(
@@ -56,55 +261,303 @@ func NewRingBufferHandler(
 
 	go r.pull()
 
+	if !flushedPeriodically(w) {
+		r.autoFlush = writer.NewAutoFlush(r.flushInterval, func() {
+			// a periodic flush never waits behind a stuck write, so Close could stop them in time
+			if !r.wmu.TryLock() {
+				return
+			}
+			defer r.wmu.Unlock()
+
+			if err := r.flushLocked(); err != nil {
+				r.report(nil, err)
+			}
+		})
+	}
+
 	return r
 }
 
 func (r *RingBufferHandler) Handle(e *easylog.Event) (bool, error) {
+	r.closing.RLock()
+	defer r.closing.RUnlock()
+
+	if r.isClosing || !r.acquire() {
+		r.dropped.Add(1)
+		r.unreported.Add(1)
+		r.dropLogger.Store(e.GetLogger())
+		return true, nil
+	}
+
+	r.enqueued.Add(1)
 	r.puller.Set(diode.GenericDataType(e.Clone()))
+
 	return true, nil
 }
 
+// Flush waits until the Events handled before the call are written or dropped, then flushes w.
 func (r *RingBufferHandler) Flush() error {
+	target := r.enqueued.Load()
+
+	r.settleMu.Lock()
+	for r.settled < target && !r.exited {
+		r.settle.Wait()
+	}
+	r.settleMu.Unlock()
+
+	return r.flush()
+}
+
+// flush flushes w, unless it's closed.
+func (r *RingBufferHandler) flush() error {
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+
+	return r.flushLocked()
+}
+
+// flushLocked flushes w, it must be called with wmu held.
+func (r *RingBufferHandler) flushLocked() error {
+	if r.writerClosed {
+		return nil
+	}
+	if err := r.w.Flush(); err != nil {
+		return fmt.Errorf("ring buffer: flush: %w", err)
+	}
+
 	return nil
 }
 
+// Close stops accepting Events, writes the ones left, then closes w.
+// If they are not written within the close timeout, the ones left are dropped, and if the goroutine is still
+// stuck writing, w is left open and an error wrapping context.DeadlineExceeded is returned.
+// Close could be called again to wait once more.
 func (r *RingBufferHandler) Close() error {
-	r.cancel()
-	<-r.done
+	r.closeOnce.Do(func() {
+		// wake up the Events blocked on overflow, then wait for the ones being enqueued
+		close(r.closed)
+		r.closing.Lock()
+		r.isClosing = true
+		r.closing.Unlock()
+
+		r.cancel()
+		r.autoFlush.Stop()
+	})
+
+	timer := time.NewTimer(r.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-r.done:
+	case <-timer.C:
+		r.abortOnce.Do(func() {
+			close(r.abort)
+		})
+
+		timer.Reset(r.closeTimeout)
+		select {
+		case <-r.done:
+		case <-timer.C:
+			return fmt.Errorf("ring buffer: close: %w", context.DeadlineExceeded)
+		}
+	}
+
+	r.wmu.Lock()
+	defer r.wmu.Unlock()
+
+	if r.writerClosed {
+		return nil
+	}
+	r.writerClosed = true
+
+	if err := r.w.Close(); err != nil {
+		return fmt.Errorf("ring buffer: close: %w", err)
+	}
 
 	return nil
 }
 
+// Stats returns the counters of the Events.
+func (r *RingBufferHandler) Stats() RingStats {
+	return RingStats{
+		Enqueued: r.enqueued.Load(),
+		Written:  r.written.Load(),
+		Dropped:  r.dropped.Load(),
+		Blocked:  r.blocked.Load(),
+		Failed:   r.failed.Load(),
+	}
+}
+
+// acquire reports whether there's space for an Event in the buffer, according to the overflow policy.
+func (r *RingBufferHandler) acquire() bool {
+	if r.slots == nil {
+		return true
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if r.overflow == OverflowDropNewest {
+		return false
+	}
+
+	r.blocked.Add(1)
+
+	var timeout <-chan time.Time
+	if r.overflow == OverflowBlockTimeout {
+		t := time.NewTimer(r.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-r.closed:
+		return false
+	}
+}
+
+// release frees the space of an Event taken out of the buffer.
+func (r *RingBufferHandler) release() {
+	if r.slots != nil {
+		<-r.slots
+	}
+}
+
+func (r *RingBufferHandler) overwritten(missed int) {
+	r.dropped.Add(uint64(missed))
+	r.unreported.Add(uint64(missed))
+	r.settleN(uint64(missed))
+	if r.alert != nil {
+		r.alert(missed)
+	}
+}
+
 func (r *RingBufferHandler) pull() {
 	defer close(r.done)
+	defer func() {
+		r.settleMu.Lock()
+		r.exited = true
+		r.settle.Broadcast()
+		r.settleMu.Unlock()
+	}()
 
 	for {
 		d := r.puller.Next()
 		if d == nil {
+			r.reportDropped(nil)
 			return
 		}
 
+		r.release()
+
 		e := (*easylog.Event)(d)
 
-		b, err := r.format(e)
-		if err != nil {
-			// TODO err handle
-			e.Put()
-			continue
+		if r.aborted() {
+			r.dropped.Add(1)
+			r.unreported.Add(1)
+		} else if err := r.writeEvent(e); err != nil {
+			r.failed.Add(1)
+			r.report(e.GetLogger(), err)
+		} else {
+			r.written.Add(1)
+
+			if r.flushOnLevel && e.GetLevel() >= r.flushLevel {
+				if err := r.flush(); err != nil {
+					r.report(e.GetLogger(), err)
+				}
+			}
 		}
 
-		if _, err := r.w.Write(b); err != nil {
-			// TODO err handle
-			e.Put()
-			continue
+		r.settleN(1)
+		r.reportDropped(e.GetLogger())
+
+		e.Put()
+	}
+}
+
+func (r *RingBufferHandler) settleN(n uint64) {
+	r.settleMu.Lock()
+	r.settled += n
+	r.settle.Broadcast()
+	r.settleMu.Unlock()
+}
+
+// aborted reports whether Close timed out.
+func (r *RingBufferHandler) aborted() bool {
+	select {
+	case <-r.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r *RingBufferHandler) writeEvent(e *easylog.Event) error {
+	b, err := r.format(e)
+	if err != nil {
+		return fmt.Errorf("ring buffer: format: %w", err)
+	}
+
+	// b is owned by the handler, write it with the newline at once.
+	b = append(b, '\n')
+
+	backoff := r.backoff
+	for retry := 0; ; retry++ {
+		r.wmu.Lock()
+		n, err := r.w.Write(b)
+		r.wmu.Unlock()
+		if err == nil {
+			return nil
+		}
+		if retry >= r.retries {
+			return fmt.Errorf("ring buffer: write: %w", err)
 		}
 
-		if _, err := r.w.WriteString("\n"); err != nil {
-			// TODO err handle
-			e.Put()
-			continue
+		b = b[n:]
+
+		t := time.NewTimer(backoff)
+		select {
+		case <-t.C:
+		case <-r.abort:
+			t.Stop()
+			return fmt.Errorf("ring buffer: write: %w", err)
 		}
+		backoff = min(backoff*2, maxRingRetryBackoff)
+	}
+}
 
-		e.Put()
+// reportDropped reports the Events dropped since the last report, if any.
+// logger is the one of the Event just written, if any.
+func (r *RingBufferHandler) reportDropped(logger *easylog.Logger) {
+	n := r.unreported.Swap(0)
+	if n == 0 {
+		return
 	}
+
+	if l := r.dropLogger.Swap(nil); l != nil {
+		logger = l
+	}
+
+	r.report(logger, &DroppedError{Count: n})
+}
+
+// report reports err to the errorHandler if any, otherwise to the ErrorHandler of logger.
+func (r *RingBufferHandler) report(logger *easylog.Logger, err error) {
+	eh := r.errorHandler
+	if eh == nil && logger != nil {
+		eh = logger.GetErrorHandler()
+	}
+	if eh == nil {
+		return
+	}
+
+	// ignore error produced by the error handler
+	_ = eh.Handle(err)
 }
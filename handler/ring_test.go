@@ -1,6 +1,10 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,3 +35,340 @@ func BenchmarkRingBufferHandler(b *testing.B) {
 		}
 	})
 }
+
+// gateWriter blocks the writes until gate is closed, signaling the first one on entered.
+type gateWriter struct {
+	memWriter
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{entered: make(chan struct{}, 1), gate: make(chan struct{})}
+}
+
+func (g *gateWriter) Write(p []byte) (int, error) {
+	select {
+	case g.entered <- struct{}{}:
+	default:
+	}
+	<-g.gate
+	return g.memWriter.Write(p)
+}
+
+// newGatedRing returns a RingBufferHandler of 2 Events, whose goroutine is blocked writing the Event "event-0".
+func newGatedRing(t *testing.T, opts ...RingOption) (*RingBufferHandler, *gateWriter) {
+	w := newGateWriter()
+	// a buffer of 1 byte writes each formatted Event through
+	bw, err := writer.NewBufWriter(1, w)
+	assert.Nil(t, err)
+
+	r := NewRingBufferHandler(bw, msgFormatter, 2, nil, 0, opts...)
+	ringHandle(r, 0)
+	<-w.entered
+
+	return r, w
+}
+
+func ringHandle(r *RingBufferHandler, i int) {
+	e := easylog.NewEvent(easylog.GetLogger("ring"), easylog.INFO, time.Now(), fmt.Sprintf("event-%d", i))
+	_, _ = r.Handle(e)
+	e.Put()
+}
+
+func ringWritten(t *testing.T, r *RingBufferHandler, n uint64) {
+	assert.Eventually(t, func() bool {
+		return r.Stats().Written == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestRingOverflowDropOldest(t *testing.T) {
+	var missed int
+	w := newGateWriter()
+	bw, err := writer.NewBufWriter(1, w)
+	assert.Nil(t, err)
+	r := NewRingBufferHandler(bw, msgFormatter, 2, func(n int) { missed += n }, 0)
+	defer r.Close()
+
+	ringHandle(r, 0)
+	<-w.entered
+	for i := 1; i <= 4; i++ {
+		ringHandle(r, i)
+	}
+	close(w.gate)
+
+	ringWritten(t, r, 3)
+	assert.Equal(t, RingStats{Enqueued: 5, Written: 3, Dropped: 2}, r.Stats())
+	assert.Equal(t, 2, missed)
+	assert.Equal(t, "event-0\nevent-3\nevent-4\n", w.String())
+}
+
+func TestRingOverflowDropNewest(t *testing.T) {
+	r, w := newGatedRing(t, WithRingOverflow(OverflowDropNewest))
+	defer r.Close()
+
+	for i := 1; i <= 4; i++ {
+		ringHandle(r, i)
+	}
+	close(w.gate)
+
+	ringWritten(t, r, 3)
+	assert.Equal(t, RingStats{Enqueued: 3, Written: 3, Dropped: 2}, r.Stats())
+	assert.Equal(t, "event-0\nevent-1\nevent-2\n", w.String())
+}
+
+func TestRingOverflowBlock(t *testing.T) {
+	r, w := newGatedRing(t, WithRingOverflow(OverflowBlock))
+	defer r.Close()
+
+	ringHandle(r, 1)
+	ringHandle(r, 2)
+
+	handled := make(chan struct{})
+	go func() {
+		ringHandle(r, 3)
+		close(handled)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return r.Stats().Blocked == 1
+	}, time.Second, time.Millisecond)
+	select {
+	case <-handled:
+		t.Fatal("not blocked")
+	default:
+	}
+
+	close(w.gate)
+	<-handled
+
+	ringWritten(t, r, 4)
+	assert.Equal(t, RingStats{Enqueued: 4, Written: 4, Blocked: 1}, r.Stats())
+	assert.Equal(t, 4, strings.Count(w.String(), "event-"))
+}
+
+func TestRingOverflowBlockTimeout(t *testing.T) {
+	r, w := newGatedRing(t, WithRingBlockTimeout(10*time.Millisecond))
+	defer r.Close()
+
+	ringHandle(r, 1)
+	ringHandle(r, 2)
+
+	start := time.Now()
+	ringHandle(r, 3)
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	close(w.gate)
+
+	ringWritten(t, r, 3)
+	assert.Equal(t, RingStats{Enqueued: 3, Written: 3, Dropped: 1, Blocked: 1}, r.Stats())
+}
+
+func TestParseOverflow(t *testing.T) {
+	for _, o := range []Overflow{OverflowDropOldest, OverflowDropNewest, OverflowBlock, OverflowBlockTimeout} {
+		parsed, err := ParseOverflow(o.String())
+		assert.Nil(t, err)
+		assert.Equal(t, o, parsed)
+	}
+
+	_, err := ParseOverflow("spill")
+	assert.NotNil(t, err)
+}
+
+// flakyWriter fails the first writes.
+type flakyWriter struct {
+	memWriter
+	fails int
+}
+
+func (f *flakyWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	if f.fails > 0 {
+		f.fails--
+		f.mu.Unlock()
+		return 0, errors.New("unavailable")
+	}
+	f.mu.Unlock()
+
+	return f.memWriter.Write(p)
+}
+
+func TestRingErrors(t *testing.T) {
+	w := &flakyWriter{fails: 1}
+	bw, err := writer.NewBufWriter(1, w)
+	assert.Nil(t, err)
+
+	eh := &errorCollector{}
+	format := func(e *easylog.Event) ([]byte, error) {
+		if e.GetMsg() == "bad" {
+			return nil, errors.New("unformattable")
+		}
+		return msgFormatter(e)
+	}
+	r := NewRingBufferHandler(bw, format, 4, nil, 0, WithRingErrorHandler(eh))
+	defer r.Close()
+
+	l := easylog.GetLogger("ring.errors")
+	l.AddHandler(r)
+	defer l.ResetHandler()
+
+	l.Info().Logf("lost")
+	l.Info().Logf("bad")
+	l.Info().Logf("ok")
+
+	assert.Eventually(t, func() bool {
+		return r.Stats().Written == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, RingStats{Enqueued: 3, Written: 1, Failed: 2}, r.Stats())
+	assert.Equal(t, "ok\n", w.String())
+
+	errs := eh.Errors()
+	if assert.Equal(t, 2, len(errs)) {
+		assert.Equal(t, "ring buffer: write: unavailable", errs[0].Error())
+		assert.Equal(t, "ring buffer: format: unformattable", errs[1].Error())
+	}
+}
+
+func TestRingErrorsLogger(t *testing.T) {
+	r, w := newGatedRing(t, WithRingOverflow(OverflowDropNewest))
+	defer r.Close()
+
+	eh := &errorCollector{}
+	l := easylog.GetLogger("ring")
+	defer l.SetErrorHandler(l.GetErrorHandler())
+	l.SetErrorHandler(eh)
+
+	for i := 1; i <= 4; i++ {
+		ringHandle(r, i)
+	}
+	close(w.gate)
+
+	ringWritten(t, r, 3)
+	assert.Eventually(t, func() bool {
+		return len(eh.Errors()) > 0
+	}, time.Second, time.Millisecond)
+
+	var dropped *DroppedError
+	assert.True(t, errors.As(eh.Errors()[0], &dropped))
+	assert.Equal(t, uint64(2), dropped.Count)
+}
+
+func TestRingRetry(t *testing.T) {
+	w := &flakyWriter{fails: 2}
+	bw, err := writer.NewBufWriter(1, w)
+	assert.Nil(t, err)
+
+	eh := &errorCollector{}
+	r := NewRingBufferHandler(bw, msgFormatter, 4, nil, 0, WithRingErrorHandler(eh), WithRingRetry(2, time.Millisecond))
+	defer r.Close()
+
+	ringHandle(r, 0)
+	ringWritten(t, r, 1)
+	assert.Equal(t, "event-0\n", w.String())
+	assert.Equal(t, 0, len(eh.Errors()))
+
+	// too many failures
+	w.mu.Lock()
+	w.fails = 3
+	w.mu.Unlock()
+	ringHandle(r, 1)
+	assert.Eventually(t, func() bool {
+		return r.Stats().Failed == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, len(eh.Errors()))
+}
+
+func TestRingFlush(t *testing.T) {
+	r, w := newGatedRing(t, WithRingOverflow(OverflowBlock))
+	defer r.Close()
+
+	ringHandle(r, 1)
+	ringHandle(r, 2)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(w.gate)
+	}()
+
+	assert.Nil(t, r.Flush())
+	assert.Equal(t, uint64(3), r.Stats().Written)
+	assert.Equal(t, "event-0\nevent-1\nevent-2\n", w.String())
+	assert.Equal(t, 1, w.flushed)
+}
+
+func TestRingClose(t *testing.T) {
+	w := &memWriter{}
+	// the buffered bytes are written on Close
+	bw, err := writer.NewBufWriter(1024, w)
+	assert.Nil(t, err)
+	r := NewRingBufferHandler(bw, msgFormatter, 16, nil, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ringHandle(r, i)
+	}
+	assert.Nil(t, r.Close())
+	assert.Equal(t, "event-0\nevent-1\nevent-2\n", w.String())
+	assert.Equal(t, 1, w.closed)
+
+	// the Events handled once closed are dropped
+	ringHandle(r, 3)
+	assert.Equal(t, RingStats{Enqueued: 3, Written: 3, Dropped: 1}, r.Stats())
+	assert.Nil(t, r.Close())
+	assert.Nil(t, r.Flush())
+	assert.Equal(t, 1, w.closed)
+}
+
+func TestRingCloseTimeout(t *testing.T) {
+	r, w := newGatedRing(t, WithRingCloseTimeout(10*time.Millisecond), WithRingFlushInterval(time.Millisecond))
+
+	ringHandle(r, 1)
+	ringHandle(r, 2)
+
+	// stuck writing event-0
+	err := r.Close()
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, w.closed)
+
+	// the Events left are dropped once unstuck, and the periodic flushes are stopped
+	close(w.gate)
+	assert.Eventually(t, func() bool {
+		return r.Stats().Dropped == 2
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	w.mu.Lock()
+	assert.Equal(t, 0, w.flushed)
+	w.mu.Unlock()
+	assert.Nil(t, r.Close())
+	assert.Equal(t, "event-0\n", w.String())
+	assert.Equal(t, RingStats{Enqueued: 3, Written: 1, Dropped: 2}, r.Stats())
+	assert.Equal(t, 1, w.closed)
+}
+
+func TestRingAutoFlush(t *testing.T) {
+	w := &memWriter{}
+	bw, err := writer.NewBufWriter(1024, w)
+	assert.Nil(t, err)
+	r := NewRingBufferHandler(bw, msgFormatter, 16, nil, 0, WithRingFlushLevel(easylog.ERROR))
+
+	l := easylog.GetLogger("ring.flush")
+	l.AddHandler(r)
+	defer l.ResetHandler()
+
+	l.Info().Logf("info")
+	l.Error().Logf("error")
+	assert.Eventually(t, func() bool {
+		return w.String() == "info\nerror\n"
+	}, time.Second, time.Millisecond)
+	assert.Nil(t, r.Close())
+
+	w = &memWriter{}
+	bw, err = writer.NewBufWriter(1024, w)
+	assert.Nil(t, err)
+	r = NewRingBufferHandler(bw, msgFormatter, 16, nil, 0, WithRingFlushInterval(time.Millisecond))
+	defer r.Close()
+
+	ringHandle(r, 0)
+	assert.Eventually(t, func() bool {
+		return w.String() == "event-0\n"
+	}, time.Second, time.Millisecond)
+}
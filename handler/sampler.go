@@ -0,0 +1,298 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/covine/easylog"
+)
+
+const (
+	defaultSampleTick       = time.Second
+	defaultSampleFirst      = 100
+	defaultSampleThereafter = 100
+	defaultSampleSummary    = 10 * time.Second
+)
+
+// Sampler caps the volume of the Events passed to the wrapped Handler, per key.
+//
+// By default, within each tick of one second, the first 100 Events of a key are passed, then every 100th.
+// With WithSampleTokenBucket, each key has its own token bucket instead.
+// The key is the level and the message of the Event, or the value of a kv with WithSampleKv.
+//
+// The Events sampled out are skipped and continue to the next Handlers, like with Filtered.
+// Every 10 seconds, the number of Events sampled out for each key is passed to the wrapped Handler
+// as a summary Event, at the highest level of the sampled out Events, with the fields "sample_key",
+// "dropped" (the count) and "last_msg" (the message of the last one). The errors of the summaries are reported
+// to the ErrorHandler of their Logger.
+type Sampler struct {
+	h easylog.Handler
+
+	tick       time.Duration
+	first      uint64
+	thereafter uint64
+
+	rate  float64
+	burst float64
+
+	kv      string
+	summary time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	entries map[sampleKey]*sampleEntry
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type sampleKey struct {
+	level easylog.Level
+	key   string
+}
+
+type sampleEntry struct {
+	// the current tick
+	resetAt time.Time
+	n       uint64
+
+	// the token bucket
+	tokens float64
+	last   time.Time
+
+	// the Events sampled out since the last summary
+	dropped uint64
+	level   easylog.Level
+	logger  *easylog.Logger
+	msg     string
+}
+
+// SamplerOption configures a Sampler.
+type SamplerOption func(*Sampler)
+
+// WithSampleRate passes, within each tick, the first Events of a key, then every thereafter-th.
+// A thereafter of 0 drops all the Events after the first ones.
+func WithSampleRate(tick time.Duration, first, thereafter int) SamplerOption {
+	return func(s *Sampler) {
+		s.tick = tick
+		s.first = uint64(max(first, 0))
+		s.thereafter = uint64(max(thereafter, 0))
+	}
+}
+
+// WithSampleTokenBucket passes the Events of a key while its token bucket is not empty.
+// The bucket holds up to burst tokens, refills rate tokens per second, and each Event takes a token.
+func WithSampleTokenBucket(rate float64, burst int) SamplerOption {
+	return func(s *Sampler) {
+		s.rate = rate
+		s.burst = float64(max(burst, 1))
+	}
+}
+
+// WithSampleKv keys the Events by the value of the kv or the typed field named key, regardless of their level.
+// The Events without it are keyed by level and message.
+func WithSampleKv(key string) SamplerOption {
+	return func(s *Sampler) {
+		s.kv = key
+	}
+}
+
+// WithSampleSummary passes the summaries of the Events sampled out every interval. 0 disables them.
+func WithSampleSummary(interval time.Duration) SamplerOption {
+	return func(s *Sampler) {
+		s.summary = interval
+	}
+}
+
+// withSamplerClock replaces time.Now, for testing.
+func withSamplerClock(now func() time.Time) SamplerOption {
+	return func(s *Sampler) {
+		s.now = now
+	}
+}
+
+// NewSampler wraps h, it starts a goroutine summarizing the Events sampled out until Close.
+func NewSampler(h easylog.Handler, opts ...SamplerOption) *Sampler {
+	s := &Sampler{
+		h:          h,
+		tick:       defaultSampleTick,
+		first:      defaultSampleFirst,
+		thereafter: defaultSampleThereafter,
+		summary:    defaultSampleSummary,
+		now:        time.Now,
+		entries:    make(map[sampleKey]*sampleEntry),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	go s.run()
+
+	return s
+}
+
+func (s *Sampler) Handle(e *easylog.Event) (bool, error) {
+	if !s.sample(e) {
+		return true, nil
+	}
+
+	return s.h.Handle(e)
+}
+
+// Flush passes the pending summaries, then flushes the wrapped Handler.
+func (s *Sampler) Flush() error {
+	s.report()
+
+	return s.h.Flush()
+}
+
+// Close stops the summaries after passing the pending ones, then closes the wrapped Handler.
+func (s *Sampler) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+
+	return s.h.Close()
+}
+
+func (s *Sampler) key(e *easylog.Event) sampleKey {
+	if s.kv != "" {
+		if v, ok := lookupKv(e, s.kv); ok {
+			if str, ok := v.(string); ok {
+				return sampleKey{key: str}
+			}
+			return sampleKey{key: fmt.Sprint(v)}
+		}
+	}
+
+	return sampleKey{level: e.GetLevel(), key: e.GetMsg()}
+}
+
+// sample reports whether e is passed.
+func (s *Sampler) sample(e *easylog.Event) bool {
+	k := s.key(e)
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	en, ok := s.entries[k]
+	if !ok {
+		en = &sampleEntry{resetAt: now.Add(s.tick), tokens: s.burst, last: now}
+		s.entries[k] = en
+	}
+
+	if s.rate > 0 {
+		en.tokens = min(s.burst, en.tokens+now.Sub(en.last).Seconds()*s.rate)
+		en.last = now
+		if en.tokens >= 1 {
+			en.tokens--
+			return true
+		}
+	} else {
+		if !now.Before(en.resetAt) {
+			en.n = 0
+			en.resetAt = now.Add(s.tick)
+		}
+		en.n++
+		if en.n <= s.first || (s.thereafter > 0 && (en.n-s.first)%s.thereafter == 0) {
+			return true
+		}
+	}
+
+	if en.dropped == 0 || e.GetLevel() > en.level {
+		en.level = e.GetLevel()
+	}
+	en.dropped++
+	en.logger = e.GetLogger()
+	en.msg = e.GetMsg()
+
+	return false
+}
+
+type sampleSummary struct {
+	key     sampleKey
+	dropped uint64
+	level   easylog.Level
+	logger  *easylog.Logger
+	msg     string
+}
+
+// report passes the summaries of the Events sampled out, and forgets the idle keys.
+func (s *Sampler) report() {
+	now := s.now()
+
+	var summaries []sampleSummary
+
+	s.mu.Lock()
+	for k, en := range s.entries {
+		if en.dropped > 0 {
+			summaries = append(summaries, sampleSummary{
+				key: k, dropped: en.dropped, level: en.level, logger: en.logger, msg: en.msg,
+			})
+			en.dropped = 0
+			en.logger = nil
+			continue
+		}
+
+		idle := !now.Before(en.resetAt)
+		if s.rate > 0 {
+			idle = en.tokens+now.Sub(en.last).Seconds()*s.rate >= s.burst
+		}
+		if idle {
+			delete(s.entries, k)
+		}
+	}
+	s.mu.Unlock()
+
+	if s.summary <= 0 {
+		return
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].key.key != summaries[j].key.key {
+			return summaries[i].key.key < summaries[j].key.key
+		}
+		return summaries[i].key.level < summaries[j].key.level
+	})
+
+	for _, sum := range summaries {
+		e := easylog.NewEvent(sum.logger, sum.level, now, fmt.Sprintf("sampled out %d events", sum.dropped))
+		e.Str("sample_key", sum.key.key).Int64("dropped", int64(sum.dropped)).Str("last_msg", sum.msg)
+
+		if _, err := s.h.Handle(e); err != nil {
+			_ = sum.logger.GetErrorHandler().Handle(err)
+		}
+
+		e.Put()
+	}
+}
+
+func (s *Sampler) run() {
+	defer close(s.done)
+
+	interval := s.summary
+	if interval <= 0 {
+		interval = defaultSampleSummary
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			s.report()
+			return
+		case <-t.C:
+			s.report()
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// recorder keeps a description of the handled Events.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+	err    error
+	flush  int
+	closed int
+}
+
+func (r *recorder) Handle(e *easylog.Event) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := e.GetLevel().String() + " " + e.GetMsg()
+	for _, f := range e.GetFields() {
+		s += fmt.Sprintf(" %s=%v", f.Key, f.Value())
+	}
+	r.events = append(r.events, s)
+
+	return true, r.err
+}
+
+func (r *recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.flush++
+	return nil
+}
+
+func (r *recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed++
+	return nil
+}
+
+func (r *recorder) Events() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := r.events
+	r.events = nil
+	return events
+}
+
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.t
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.t = c.t.Add(d)
+}
+
+func TestSamplerRate(t *testing.T) {
+	clock := &testClock{t: time.Unix(0, 0)}
+	r := &recorder{}
+	s := NewSampler(r, WithSampleRate(time.Second, 2, 3), withSamplerClock(clock.Now), WithSampleSummary(time.Hour))
+	defer s.Close()
+
+	l := easylog.GetLogger("sampler.rate")
+	l.AddHandler(s)
+	defer l.RemoveHandler(s)
+
+	for i := 0; i < 8; i++ {
+		l.Error().Logf("down")
+	}
+	l.Warn().Logf("down")
+	l.Error().Logf("other")
+
+	// 1, 2, then every 3rd: 5, 8
+	assert.Equal(t, []string{"ERROR down", "ERROR down", "ERROR down", "ERROR down", "WARN down", "ERROR other"}, r.Events())
+
+	// a new tick starts over
+	clock.Add(time.Second)
+	l.Error().Logf("down")
+	l.Error().Logf("down")
+	l.Error().Logf("down")
+	assert.Equal(t, []string{"ERROR down", "ERROR down"}, r.Events())
+
+	assert.Nil(t, s.Flush())
+	assert.Equal(t, []string{"ERROR sampled out 5 events sample_key=down dropped=5 last_msg=down"}, r.Events())
+
+	// nothing more to report, and the idle keys are forgotten
+	clock.Add(time.Second)
+	assert.Nil(t, s.Flush())
+	assert.Equal(t, 0, len(r.Events()))
+	assert.Equal(t, 0, len(s.entries))
+}
+
+func TestSamplerTokenBucket(t *testing.T) {
+	clock := &testClock{t: time.Unix(0, 0)}
+	r := &recorder{}
+	s := NewSampler(r, WithSampleTokenBucket(2, 3), withSamplerClock(clock.Now), WithSampleSummary(time.Hour))
+	defer s.Close()
+
+	l := easylog.GetLogger("sampler.bucket")
+	l.AddHandler(s)
+	defer l.RemoveHandler(s)
+
+	for i := 0; i < 5; i++ {
+		l.Info().Logf("m")
+	}
+	assert.Equal(t, 3, len(r.Events()))
+
+	// 2 tokens per second
+	clock.Add(500 * time.Millisecond)
+	l.Info().Logf("m")
+	l.Info().Logf("m")
+	assert.Equal(t, 1, len(r.Events()))
+
+	clock.Add(10 * time.Second)
+	for i := 0; i < 5; i++ {
+		l.Info().Logf("m")
+	}
+	assert.Equal(t, 3, len(r.Events()))
+
+	assert.Nil(t, s.Flush())
+	assert.Equal(t, []string{"INFO sampled out 5 events sample_key=m dropped=5 last_msg=m"}, r.Events())
+
+	clock.Add(10 * time.Second)
+	assert.Nil(t, s.Flush())
+	assert.Equal(t, 0, len(s.entries))
+}
+
+func TestSamplerKv(t *testing.T) {
+	r := &recorder{}
+	s := NewSampler(r, WithSampleRate(time.Hour, 1, 0), WithSampleKv("peer"), WithSampleSummary(time.Hour))
+	defer s.Close()
+
+	l := easylog.GetLogger("sampler.kv")
+	l.AddHandler(s)
+	defer l.RemoveHandler(s)
+
+	l.Info().Kv("peer", "a").Logf("1")
+	l.Error().Kv("peer", "a").Logf("2")
+	l.Info().Str("peer", "b").Logf("3")
+	l.Warn().Str("peer", "b").Logf("4")
+	l.Info().Int("peer", 1).Logf("5")
+	l.Info().Logf("6")
+	l.Info().Logf("6")
+
+	assert.Equal(t, []string{"INFO 1", "INFO 3 peer=b", "INFO 5 peer=1", "INFO 6"}, r.Events())
+
+	assert.Nil(t, s.Flush())
+	assert.Equal(t, []string{
+		"INFO sampled out 1 events sample_key=6 dropped=1 last_msg=6",
+		"ERROR sampled out 1 events sample_key=a dropped=1 last_msg=2",
+		"WARN sampled out 1 events sample_key=b dropped=1 last_msg=4",
+	}, r.Events())
+}
+
+func TestSamplerSummary(t *testing.T) {
+	r := &recorder{err: errors.New("failed")}
+	s := NewSampler(r, WithSampleRate(time.Hour, 0, 0), WithSampleSummary(10*time.Millisecond))
+
+	l := easylog.GetLogger("sampler.summary")
+	eh := &errorCollector{}
+	l.SetErrorHandler(eh)
+	l.AddHandler(s)
+	defer l.ResetHandler()
+
+	l.Info().Logf("m")
+	assert.Eventually(t, func() bool {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.events) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, []error{r.err}, eh.Errors())
+
+	// the pending summaries are passed on Close
+	l.Info().Logf("m")
+	assert.Nil(t, s.Close())
+	assert.Equal(t, 2, len(r.Events()))
+	assert.Equal(t, 1, r.closed)
+
+	// without summaries
+	r = &recorder{}
+	s = NewSampler(r, WithSampleRate(time.Hour, 0, 0), WithSampleSummary(0))
+	l.ResetHandler()
+	l.AddHandler(s)
+	l.Info().Logf("m")
+	assert.Nil(t, s.Close())
+	assert.Equal(t, 0, len(r.Events()))
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/covine/easylog"
+)
+
+// SlogHandler forwards Events to a slog.Handler.
+//
+// The level is mapped by easylog.SlogLevel, the caller becomes the PC of the record,
+// tags and kvs become the groups "tags" and "kvs" with the keys sorted, typed fields become attrs
+// in insertion order, and the error, stack and extra of the Event become the attrs "error", "stack" and "extra".
+type SlogHandler struct {
+	h slog.Handler
+}
+
+func NewSlogHandler(h slog.Handler) *SlogHandler {
+	return &SlogHandler{
+		h: h,
+	}
+}
+
+func (s *SlogHandler) Handle(e *easylog.Event) (bool, error) {
+	ctx := e.GetCtx()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	level := easylog.SlogLevel(e.GetLevel())
+	if !s.h.Enabled(ctx, level) {
+		return true, nil
+	}
+
+	var pc uintptr
+	if e.GetCaller().GetOK() {
+		pc = e.GetCaller().GetPC()
+	}
+
+	r := slog.NewRecord(e.GetTime(), level, e.GetMsg(), pc)
+
+	if len(e.GetTags()) > 0 {
+		r.AddAttrs(slog.Attr{Key: "tags", Value: slog.GroupValue(mapAttrs(e.GetTags())...)})
+	}
+	if len(e.GetKvs()) > 0 {
+		r.AddAttrs(slog.Attr{Key: "kvs", Value: slog.GroupValue(mapAttrs(e.GetKvs())...)})
+	}
+
+	fields := e.GetFields()
+	for i := range fields {
+		r.AddAttrs(fieldAttr(&fields[i]))
+	}
+
+	if err := e.GetError(); err != nil {
+		r.AddAttrs(slog.Any("error", err))
+	}
+	if stack := e.GetStack(); stack != "" {
+		r.AddAttrs(slog.String("stack", stack))
+	}
+	if extra := e.GetExtra(); extra != nil {
+		r.AddAttrs(slog.Any("extra", extra))
+	}
+
+	return true, s.h.Handle(ctx, r)
+}
+
+func (s *SlogHandler) Flush() error {
+	return nil
+}
+
+func (s *SlogHandler) Close() error {
+	return nil
+}
+
+func mapAttrs(m map[interface{}]interface{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(m))
+	for k, v := range m {
+		attrs = append(attrs, slog.Any(fmt.Sprint(k), v))
+	}
+
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+
+	return attrs
+}
+
+func fieldAttr(f *easylog.Field) slog.Attr {
+	switch f.Type {
+	case easylog.StringType:
+		return slog.String(f.Key, f.String)
+	case easylog.IntType:
+		return slog.Int64(f.Key, f.GetInt())
+	case easylog.Float64Type:
+		return slog.Float64(f.Key, f.GetFloat64())
+	case easylog.BoolType:
+		return slog.Bool(f.Key, f.GetBool())
+	case easylog.DurationType:
+		return slog.Duration(f.Key, f.GetDuration())
+	case easylog.TimeType:
+		return slog.Time(f.Key, f.GetTime())
+	default:
+		return slog.Any(f.Key, f.Value())
+	}
+}
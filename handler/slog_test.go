@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+func TestSlogHandler(t *testing.T) {
+	logger := easylog.GetLogger("slog_test")
+	logger.SetLevel(easylog.DEBUG)
+	logger.EnableCaller(easylog.ERROR)
+	defer logger.DisableCaller(easylog.ERROR)
+
+	var buf bytes.Buffer
+	h := NewSlogHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		Level:     slog.LevelInfo,
+		AddSource: true,
+	}))
+	logger.AddHandler(h)
+	defer logger.RemoveHandler(h)
+
+	logger.Debug().Logf("filtered by slog")
+	assert.Equal(t, 0, buf.Len())
+
+	logger.Error().
+		Tag("b", 2).Tag("a", 1).
+		Kv("k", "v").
+		Str("s", "str").Int("i", 1).Dur("d", time.Second).Bool("ok", true).
+		E(errors.New("boom")).
+		Attach("extra").
+		Logf("hello %s", "slog")
+
+	m := make(map[string]interface{})
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &m))
+
+	assert.Equal(t, "ERROR", m["level"])
+	assert.Equal(t, "hello slog", m["msg"])
+	assert.Equal(t, map[string]interface{}{"a": 1.0, "b": 2.0}, m["tags"])
+	assert.Equal(t, map[string]interface{}{"k": "v"}, m["kvs"])
+	assert.Equal(t, "str", m["s"])
+	assert.Equal(t, 1.0, m["i"])
+	assert.Equal(t, float64(time.Second), m["d"])
+	assert.Equal(t, true, m["ok"])
+	assert.Equal(t, "boom", m["error"])
+	assert.Equal(t, "extra", m["extra"])
+	assert.Equal(t, "github.com/covine/easylog/handler.TestSlogHandler", m["source"].(map[string]interface{})["function"])
+
+	assert.Nil(t, h.Flush())
+	assert.Nil(t, h.Close())
+}
+
+func TestSlogHandlerLevels(t *testing.T) {
+	logger := easylog.GetLogger("slog_test.levels")
+	logger.SetLevel(easylog.DEBUG)
+
+	var buf bytes.Buffer
+	h := NewSlogHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	logger.AddHandler(h)
+	defer logger.RemoveHandler(h)
+
+	logger.Debug().Log()
+	logger.Info().Log()
+	logger.Warn().Log()
+	logger.Error().Log()
+	assert.Panics(t, func() {
+		logger.Panic().Log()
+	})
+
+	out := buf.String()
+	for _, level := range []string{"level=DEBUG", "level=INFO", "level=WARN", "level=ERROR", "level=ERROR+4"} {
+		assert.Contains(t, out, level)
+	}
+}
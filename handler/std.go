@@ -1,207 +1,43 @@
 package handler
 
 import (
-	"os"
-	"sync"
-
-	"github.com/covine/easylog"
 	"github.com/covine/easylog/writer"
 )
 
-type StderrHandler struct {
-	sync.Mutex
-	format Formatter
-	w      *writer.StderrWriter
-}
+type StderrHandler = WriterHandler
 
 func NewStderrHandler(format Formatter) *StderrHandler {
-	return &StderrHandler{
-		format: format,
-		w:      writer.NewStderrWriter(),
-	}
-}
-
-func (s *StderrHandler) Handle(e *easylog.Event) (bool, error) {
-	b, err := s.format(e)
-	if err != nil {
-		return true, err
-	}
-
-	s.Lock()
-	defer s.Unlock()
-
-	if _, err := s.w.Write(b); err != nil {
-		return true, err
-	}
-
-	if _, err := s.w.WriteString("\n"); err != nil {
-		return true, err
-	}
-
-	return true, nil
-}
-
-func (s *StderrHandler) Flush() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return os.Stderr.Sync()
-}
-
-func (s *StderrHandler) Close() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return os.Stderr.Close()
+	return NewWriterHandler(writer.NewStderrWriter(), format)
 }
 
-type BufStderrHandler struct {
-	sync.Mutex
-	format Formatter
-	w      *writer.BufWriter
-}
+type BufStderrHandler = WriterHandler
 
-func NewBufStderrHandler(format Formatter) (*BufStderrHandler, error) {
+// NewBufStderrHandler buffers the writes to stderr, see WithWriterFlushInterval and WithWriterFlushLevel
+// to flush them without calling Flush.
+func NewBufStderrHandler(format Formatter, opts ...WriterHandlerOption) (*BufStderrHandler, error) {
 	w, err := writer.NewBufWriter(0, writer.NewStderrWriter())
 	if err != nil {
 		return nil, err
 	}
 
-	return &BufStderrHandler{
-		format: format,
-		w:      w,
-	}, nil
-}
-
-func (s *BufStderrHandler) Handle(e *easylog.Event) (bool, error) {
-	b, err := s.format(e)
-	if err != nil {
-		return true, err
-	}
-
-	s.Lock()
-	defer s.Unlock()
-
-	if _, err := s.w.Write(b); err != nil {
-		return true, err
-	}
-
-	if _, err := s.w.WriteString("\n"); err != nil {
-		return true, err
-	}
-
-	return true, nil
+	return NewWriterHandler(w, format, opts...), nil
 }
 
-func (s *BufStderrHandler) Flush() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return s.w.Flush()
-}
-
-func (s *BufStderrHandler) Close() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return s.w.Close()
-}
-
-type StdoutHandler struct {
-	sync.Mutex
-	format Formatter
-	w      *writer.StdoutWriter
-}
+type StdoutHandler = WriterHandler
 
 func NewStdoutHandler(format Formatter) *StdoutHandler {
-	return &StdoutHandler{
-		format: format,
-		w:      writer.NewStdoutWriter(),
-	}
-}
-
-func (s *StdoutHandler) Handle(e *easylog.Event) (bool, error) {
-	b, err := s.format(e)
-	if err != nil {
-		return true, err
-	}
-
-	s.Lock()
-	defer s.Unlock()
-
-	if _, err := s.w.Write(b); err != nil {
-		return true, err
-	}
-
-	if _, err := s.w.WriteString("\n"); err != nil {
-		return true, err
-	}
-
-	return true, nil
+	return NewWriterHandler(writer.NewStdoutWriter(), format)
 }
 
-func (s *StdoutHandler) Flush() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return os.Stdout.Sync()
-}
+type BufStdoutHandler = WriterHandler
 
-func (s *StdoutHandler) Close() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return os.Stdout.Close()
-}
-
-type BufStdoutHandler struct {
-	sync.Mutex
-	format Formatter
-	w      *writer.BufWriter
-}
-
-func NewBufStdoutHandler(format Formatter) (*BufStdoutHandler, error) {
+// NewBufStdoutHandler buffers the writes to stdout, see WithWriterFlushInterval and WithWriterFlushLevel
+// to flush them without calling Flush.
+func NewBufStdoutHandler(format Formatter, opts ...WriterHandlerOption) (*BufStdoutHandler, error) {
 	w, err := writer.NewBufWriter(0, writer.NewStdoutWriter())
 	if err != nil {
 		return nil, err
 	}
 
-	return &BufStdoutHandler{
-		format: format,
-		w:      w,
-	}, nil
-}
-
-func (s *BufStdoutHandler) Handle(e *easylog.Event) (bool, error) {
-	b, err := s.format(e)
-	if err != nil {
-		return true, err
-	}
-
-	s.Lock()
-	defer s.Unlock()
-
-	if _, err := s.w.Write(b); err != nil {
-		return true, err
-	}
-
-	if _, err := s.w.WriteString("\n"); err != nil {
-		return true, err
-	}
-
-	return true, nil
-}
-
-func (s *BufStdoutHandler) Flush() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return s.w.Flush()
-}
-
-func (s *BufStdoutHandler) Close() error {
-	s.Lock()
-	defer s.Unlock()
-
-	return s.w.Close()
+	return NewWriterHandler(w, format, opts...), nil
 }
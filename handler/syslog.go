@@ -0,0 +1,682 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/covine/easylog"
+)
+
+// Facility is a syslog facility.
+type Facility int
+
+const (
+	FacilityKern Facility = iota
+	FacilityUser
+	FacilityMail
+	FacilityDaemon
+	FacilityAuth
+	FacilitySyslog
+	FacilityLPR
+	FacilityNews
+	FacilityUUCP
+	FacilityCron
+	FacilityAuthPriv
+	FacilityFTP
+)
+
+const (
+	FacilityLocal0 Facility = iota + 16
+	FacilityLocal1
+	FacilityLocal2
+	FacilityLocal3
+	FacilityLocal4
+	FacilityLocal5
+	FacilityLocal6
+	FacilityLocal7
+)
+
+var facilityNames = map[string]Facility{
+	"kern":     FacilityKern,
+	"user":     FacilityUser,
+	"mail":     FacilityMail,
+	"daemon":   FacilityDaemon,
+	"auth":     FacilityAuth,
+	"syslog":   FacilitySyslog,
+	"lpr":      FacilityLPR,
+	"news":     FacilityNews,
+	"uucp":     FacilityUUCP,
+	"cron":     FacilityCron,
+	"authpriv": FacilityAuthPriv,
+	"ftp":      FacilityFTP,
+	"local0":   FacilityLocal0,
+	"local1":   FacilityLocal1,
+	"local2":   FacilityLocal2,
+	"local3":   FacilityLocal3,
+	"local4":   FacilityLocal4,
+	"local5":   FacilityLocal5,
+	"local6":   FacilityLocal6,
+	"local7":   FacilityLocal7,
+}
+
+// ParseFacility parses the name of a Facility, like "local0", case-insensitively.
+func ParseFacility(name string) (Facility, error) {
+	f, ok := facilityNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown facility %q", name)
+	}
+	return f, nil
+}
+
+// SyslogFormat is the format of the syslog messages.
+type SyslogFormat int
+
+const (
+	// SyslogRFC5424 is the format of RFC 5424, with the tags and kvs as structured data.
+	SyslogRFC5424 SyslogFormat = iota
+	// SyslogRFC3164 is the BSD format of RFC 3164, with the tags and kvs appended to the message as logfmt.
+	SyslogRFC3164
+)
+
+// SyslogFraming is how the messages are delimited on the stream transports.
+type SyslogFraming int
+
+const (
+	// SyslogOctetCounting prefixes each message with its length, as RFC 6587 and RFC 5425 specify.
+	SyslogOctetCounting SyslogFraming = iota + 1
+	// SyslogNewline terminates each message with a newline.
+	SyslogNewline
+)
+
+const (
+	defaultSyslogDialTimeout  = 5 * time.Second
+	defaultSyslogWriteTimeout = 5 * time.Second
+	defaultSyslogMinBackoff   = 100 * time.Millisecond
+	defaultSyslogMaxBackoff   = 30 * time.Second
+	defaultSyslogTagsID       = "tags@32473"
+	defaultSyslogKvsID        = "kvs@32473"
+
+	syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// errSyslogDown is returned by connect while waiting for the backoff.
+var errSyslogDown = errors.New("syslog: down")
+
+// the paths of the local syslog socket, by platform
+var syslogLocalPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogHandler sends the Events to a syslog server, over a unix socket, UDP or TCP.
+//
+// The levels are mapped to the severities: DEBUG to debug, INFO to info, WARN to warning, ERROR to err,
+// PANIC to crit and FATAL to alert. The message is the one of the Event, followed by its error if any.
+// The tags, and the kvs with the fields and the trace context, are encoded as the structured data elements
+// tags@32473 and kvs@32473, see WithSyslogStructuredData.
+//
+// On a stream transport, the messages are framed by octet counting, or by a newline on the local socket,
+// the newlines of the messages being escaped as \n.
+// A failed write is retried once on a new connection. If it fails again, the next connection waits for
+// an exponential backoff, see WithSyslogBackoff, and the Events are dropped meanwhile. Their count is reported
+// once connected again.
+type SyslogHandler struct {
+	network string
+	addr    string
+	local   bool
+
+	format       SyslogFormat
+	framing      SyslogFraming
+	facility     Facility
+	hostname     string
+	appName      string
+	procID       string
+	msgID        string
+	tagsID       string
+	kvsID        string
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	now          func() time.Time
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+	// the network of conn, the local socket is either datagram or stream
+	connNetwork string
+	backoff     time.Duration
+	nextDial    time.Time
+	// dropped counts the Events dropped while waiting for the backoff
+	dropped uint64
+}
+
+// SyslogOption configures a SyslogHandler.
+type SyslogOption func(*SyslogHandler)
+
+// WithSyslogFormat sets the format of the messages, SyslogRFC5424 by default.
+func WithSyslogFormat(format SyslogFormat) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.format = format
+	}
+}
+
+// WithSyslogFraming sets the framing of the messages on the stream transports.
+func WithSyslogFraming(framing SyslogFraming) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.framing = framing
+	}
+}
+
+// WithSyslogFacility sets the facility of the messages, FacilityUser by default.
+func WithSyslogFacility(facility Facility) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.facility = facility
+	}
+}
+
+// WithSyslogHostname sets the hostname of the messages, the one of the host by default.
+func WithSyslogHostname(hostname string) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.hostname = hostname
+	}
+}
+
+// WithSyslogAppName sets the app-name of the messages, the name of the executable by default.
+func WithSyslogAppName(appName string) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.appName = appName
+	}
+}
+
+// WithSyslogProcID sets the procid of the messages, the pid by default.
+func WithSyslogProcID(procID string) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.procID = procID
+	}
+}
+
+// WithSyslogMsgID sets the msgid of the RFC 5424 messages, none by default.
+func WithSyslogMsgID(msgID string) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.msgID = msgID
+	}
+}
+
+// WithSyslogStructuredData sets the SD-IDs of the structured data elements of the tags and of the kvs.
+// Unless registered to the IANA, they must be of the form name@<private enterprise number>.
+func WithSyslogStructuredData(tagsID, kvsID string) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.tagsID = tagsID
+		h.kvsID = kvsID
+	}
+}
+
+// WithSyslogDialTimeout bounds the time to connect, 5 seconds by default.
+func WithSyslogDialTimeout(timeout time.Duration) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.dialTimeout = timeout
+	}
+}
+
+// WithSyslogWriteTimeout bounds the time of a write, 5 seconds by default. 0 disables the timeout.
+func WithSyslogWriteTimeout(timeout time.Duration) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.writeTimeout = timeout
+	}
+}
+
+// WithSyslogBackoff waits min after the first failed connection before connecting again, then twice as long
+// after each failure, up to max. 100 milliseconds and 30 seconds by default.
+func WithSyslogBackoff(min, max time.Duration) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.minBackoff = min
+		h.maxBackoff = max
+	}
+}
+
+// withSyslogClock replaces time.Now for the backoff, for testing.
+func withSyslogClock(now func() time.Time) SyslogOption {
+	return func(h *SyslogHandler) {
+		h.now = now
+	}
+}
+
+// NewSyslogHandler connects to the syslog server at addr over network, which is "udp", "tcp", "unix" or
+// "unixgram" and their variants. With an empty network, it connects to the local syslog socket, like /dev/log.
+func NewSyslogHandler(network, addr string, opts ...SyslogOption) (*SyslogHandler, error) {
+	h := &SyslogHandler{
+		network:      network,
+		addr:         addr,
+		local:        network == "",
+		format:       SyslogRFC5424,
+		facility:     FacilityUser,
+		appName:      filepath.Base(os.Args[0]),
+		procID:       strconv.Itoa(os.Getpid()),
+		tagsID:       defaultSyslogTagsID,
+		kvsID:        defaultSyslogKvsID,
+		dialTimeout:  defaultSyslogDialTimeout,
+		writeTimeout: defaultSyslogWriteTimeout,
+		minBackoff:   defaultSyslogMinBackoff,
+		maxBackoff:   defaultSyslogMaxBackoff,
+		now:          time.Now,
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		h.hostname = hostname
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+
+	if h.framing == 0 {
+		h.framing = SyslogOctetCounting
+		if h.local || strings.HasPrefix(h.network, "unix") {
+			h.framing = SyslogNewline
+		}
+	}
+
+	if err := h.dial(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *SyslogHandler) Handle(e *easylog.Event) (bool, error) {
+	var b []byte
+	if h.format == SyslogRFC3164 {
+		b = h.appendRFC3164(make([]byte, 0, 256), e)
+	} else {
+		b = h.appendRFC5424(make([]byte, 0, 256), e)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return true, h.write(b)
+}
+
+func (h *SyslogHandler) Flush() error {
+	return nil
+}
+
+func (h *SyslogHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	if h.conn == nil {
+		return nil
+	}
+
+	err := h.conn.Close()
+	h.conn = nil
+
+	return err
+}
+
+// dial connects to the server, it must be called with the lock held, or before the handler is shared.
+func (h *SyslogHandler) dial() error {
+	if !h.local {
+		conn, err := net.DialTimeout(h.network, h.addr, h.dialTimeout)
+		if err != nil {
+			return fmt.Errorf("syslog: %w", err)
+		}
+		h.conn = conn
+		h.connNetwork = h.network
+		return nil
+	}
+
+	paths := syslogLocalPaths
+	if h.addr != "" {
+		paths = []string{h.addr}
+	}
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range paths {
+			if conn, err := net.DialTimeout(network, path, h.dialTimeout); err == nil {
+				h.conn = conn
+				h.connNetwork = network
+				return nil
+			}
+		}
+	}
+
+	return errors.New("syslog: local syslog socket not found")
+}
+
+// connect dials the server if not connected, unless it's waiting for the backoff.
+func (h *SyslogHandler) connect() error {
+	if h.conn != nil {
+		return nil
+	}
+
+	if h.now().Before(h.nextDial) {
+		return errSyslogDown
+	}
+
+	if err := h.dial(); err != nil {
+		h.down()
+		return err
+	}
+	h.backoff = 0
+
+	return nil
+}
+
+// down closes the connection, and schedules the next one.
+func (h *SyslogHandler) down() {
+	if h.conn != nil {
+		_ = h.conn.Close()
+		h.conn = nil
+	}
+
+	h.backoff = min(max(h.backoff*2, h.minBackoff), h.maxBackoff)
+	h.nextDial = h.now().Add(h.backoff)
+}
+
+// write sends msg, on a new connection if needed. It must be called with the lock held.
+func (h *SyslogHandler) write(msg []byte) error {
+	if h.closed {
+		return errors.New("syslog: handler closed")
+	}
+
+	if err := h.connect(); err != nil {
+		if err == errSyslogDown {
+			h.dropped++
+			return nil
+		}
+		return err
+	}
+
+	var dropped error
+	if h.dropped > 0 {
+		dropped = fmt.Errorf("syslog: dropped %d events while disconnected", h.dropped)
+		h.dropped = 0
+	}
+
+	err := h.send(msg)
+	if err != nil {
+		// the server may have closed an idle connection, retried at once on a new one
+		_ = h.conn.Close()
+		h.conn = nil
+		if err = h.dial(); err == nil {
+			err = h.send(msg)
+		}
+	}
+	if err != nil {
+		h.down()
+		return errors.Join(dropped, err)
+	}
+
+	return dropped
+}
+
+// send frames msg and writes it to the connection.
+func (h *SyslogHandler) send(msg []byte) error {
+	if h.stream() {
+		if h.framing == SyslogOctetCounting {
+			framed := strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+			framed = append(framed, ' ')
+			msg = append(framed, msg...)
+		} else {
+			msg = append(escapeSyslogNewlines(msg), '\n')
+		}
+	}
+
+	if h.writeTimeout > 0 {
+		_ = h.conn.SetWriteDeadline(time.Now().Add(h.writeTimeout))
+	}
+
+	if _, err := h.conn.Write(msg); err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+
+	return nil
+}
+
+// escapeSyslogNewlines returns msg with its newlines escaped as \n, so they don't end the message when framed by
+// a newline.
+func escapeSyslogNewlines(msg []byte) []byte {
+	if bytes.IndexByte(msg, '\n') < 0 {
+		return msg
+	}
+
+	r := make([]byte, 0, len(msg)+8)
+	for _, c := range msg {
+		if c == '\n' {
+			r = append(r, '\\', 'n')
+		} else {
+			r = append(r, c)
+		}
+	}
+
+	return r
+}
+
+// stream reports whether the messages need a framing.
+func (h *SyslogHandler) stream() bool {
+	return !strings.HasPrefix(h.connNetwork, "udp") && h.connNetwork != "unixgram"
+}
+
+func (h *SyslogHandler) priority(level easylog.Level) int {
+	return int(h.facility)*8 + syslogSeverity(level)
+}
+
+func syslogSeverity(level easylog.Level) int {
+	switch {
+	case level <= easylog.DEBUG:
+		return 7
+	case level == easylog.INFO:
+		return 6
+	case level == easylog.WARN:
+		return 4
+	case level == easylog.ERROR:
+		return 3
+	case level == easylog.PANIC:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func (h *SyslogHandler) appendRFC5424(b []byte, e *easylog.Event) []byte {
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(h.priority(e.GetLevel())), 10)
+	b = append(b, ">1 "...)
+
+	if t := e.GetTime(); t.IsZero() {
+		b = append(b, '-')
+	} else {
+		b = t.AppendFormat(b, syslogTimeLayout)
+	}
+
+	b = append(b, ' ')
+	b = appendSyslogHeader(b, h.hostname, 255)
+	b = append(b, ' ')
+	b = appendSyslogHeader(b, h.appName, 48)
+	b = append(b, ' ')
+	b = appendSyslogHeader(b, h.procID, 128)
+	b = append(b, ' ')
+	b = appendSyslogHeader(b, h.msgID, 32)
+	b = append(b, ' ')
+
+	tags, kvs, fields, trace := e.GetTags(), e.GetKvs(), e.GetFields(), e.GetTrace()
+	if len(tags) == 0 && len(kvs) == 0 && len(fields) == 0 && !trace.IsValid() {
+		b = append(b, '-')
+	}
+	if len(tags) > 0 {
+		b = append(b, '[')
+		b = appendSyslogName(b, h.tagsID)
+		b = appendSyslogParams(b, tags)
+		b = append(b, ']')
+	}
+	if len(kvs) > 0 || len(fields) > 0 || trace.IsValid() {
+		b = append(b, '[')
+		b = appendSyslogName(b, h.kvsID)
+		b = appendSyslogParams(b, kvs)
+		var text []byte
+		for i := range fields {
+			text = appendFieldText(text[:0], &fields[i])
+			b = appendSyslogParam(b, fields[i].Key, string(text))
+		}
+		if trace.IsValid() {
+			b = appendSyslogParam(b, traceIDKey, trace.TraceIDString())
+			b = appendSyslogParam(b, spanIDKey, trace.SpanIDString())
+			b = appendSyslogParam(b, traceFlagsKey, trace.FlagsString())
+		}
+		b = append(b, ']')
+	}
+
+	return appendSyslogMsg(b, e)
+}
+
+func (h *SyslogHandler) appendRFC3164(b []byte, e *easylog.Event) []byte {
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(h.priority(e.GetLevel())), 10)
+	b = append(b, '>')
+
+	t := e.GetTime()
+	if t.IsZero() {
+		t = time.Now()
+	}
+	b = t.AppendFormat(b, time.Stamp)
+
+	// the local daemon adds the hostname itself
+	if !h.local && h.hostname != "" {
+		b = append(b, ' ')
+		b = appendSyslogHeader(b, h.hostname, 255)
+	}
+
+	b = append(b, ' ')
+	b = appendSyslogHeader(b, h.appName, 32)
+	if h.procID != "" {
+		b = append(b, '[')
+		b = appendSyslogHeader(b, h.procID, 128)
+		b = append(b, ']')
+	}
+	b = append(b, ':')
+
+	b = appendSyslogMsg(b, e)
+
+	b = appendLogfmtMap(b, "tag.", e.GetTags())
+	b = appendLogfmtMap(b, "", e.GetKvs())
+	fields := e.GetFields()
+	var text []byte
+	for i := range fields {
+		b = append(b, ' ')
+		b = appendLogfmtKey(b, fields[i].Key)
+		b = append(b, '=')
+		text = appendFieldText(text[:0], &fields[i])
+		b = appendLogfmtValue(b, string(text))
+	}
+	if t := e.GetTrace(); t.IsValid() {
+		b = append(b, " "+traceIDKey+"="+t.TraceIDString()+" "+spanIDKey+"="+t.SpanIDString()+
+			" "+traceFlagsKey+"="+t.FlagsString()...)
+	}
+
+	return b
+}
+
+// appendSyslogMsg appends a space and the message of e followed by its error, if any.
+func appendSyslogMsg(b []byte, e *easylog.Event) []byte {
+	msg, err := e.GetMsg(), e.GetError()
+	if msg == "" && err == nil {
+		return b
+	}
+
+	b = append(b, ' ')
+	b = append(b, msg...)
+	if err != nil {
+		if msg != "" {
+			b = append(b, ": "...)
+		}
+		b = append(b, err.Error()...)
+	}
+
+	return b
+}
+
+// appendSyslogHeader appends s truncated to max bytes, with the characters other than the printable US-ASCII
+// ones replaced by '_', or "-" if s is empty.
+func appendSyslogHeader(b []byte, s string, max int) []byte {
+	if s == "" {
+		return append(b, '-')
+	}
+
+	if len(s) > max {
+		s = s[:max]
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c > ' ' && c < 0x7f {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+
+	return b
+}
+
+// appendSyslogName appends an SD-ID or a PARAM-NAME, truncated to 32 bytes, with the characters not allowed,
+// the ones other than the printable US-ASCII ones, '=', ']' and '"', replaced by '_', or "_" if s is empty.
+func appendSyslogName(b []byte, s string) []byte {
+	if s == "" {
+		return append(b, '_')
+	}
+
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+
+	return b
+}
+
+// appendSyslogParams appends the pairs of m sorted by key as SD-PARAMs.
+func appendSyslogParams(b []byte, m map[interface{}]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		s := fmt.Sprint(k)
+		keys = append(keys, s)
+		values[s] = v
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		b = appendSyslogParam(b, k, fmt.Sprint(values[k]))
+	}
+
+	return b
+}
+
+// appendSyslogParam appends a space and the SD-PARAM k="v", see appendSyslogName for k.
+// '"', '\' and ']' are escaped in the PARAM-VALUE.
+func appendSyslogParam(b []byte, k, v string) []byte {
+	b = append(b, ' ')
+	b = appendSyslogName(b, k)
+
+	b = append(b, '=', '"')
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c == '"' || c == '\\' || c == ']' {
+			b = append(b, '\\')
+		}
+		b = append(b, v[i])
+	}
+
+	return append(b, '"')
+}
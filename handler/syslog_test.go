@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+func syslogEvent(level easylog.Level, msg string) *easylog.Event {
+	t := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	return easylog.NewEvent(easylog.GetLogger("syslog"), level, t, msg)
+}
+
+func TestSyslogRFC5424(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+
+	h, err := NewSyslogHandler("udp", pc.LocalAddr().String(),
+		WithSyslogHostname("host"), WithSyslogAppName("app"), WithSyslogProcID("42"), WithSyslogMsgID("m"),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	read := func() string {
+		buf := make([]byte, 2048)
+		_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		assert.Nil(t, err)
+		return string(buf[:n])
+	}
+
+	e := syslogEvent(easylog.WARN, "disk full")
+	e.Tag("dc", "eu").Kv("k", `a"b]`).Kv("a b", 1).Str("f", "v").E(errors.New("boom"))
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		`<12>1 2024-01-02T03:04:05.123456Z host app 42 m [tags@32473 dc="eu"][kvs@32473 a_b="1" k="a\"b\]" f="v"] `+
+			`disk full: boom`,
+		read())
+
+	e = syslogEvent(easylog.DEBUG, "")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+	assert.Equal(t, `<15>1 2024-01-02T03:04:05.123456Z host app 42 m -`, read())
+}
+
+func TestSyslogRFC3164Local(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	h, err := NewSyslogHandler("", path,
+		WithSyslogFormat(SyslogRFC3164), WithSyslogFacility(FacilityLocal0), WithSyslogAppName("app"),
+		WithSyslogProcID("42"),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	e := syslogEvent(easylog.ERROR, "failed")
+	e.Kv("k", "v w")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+
+	buf := make([]byte, 2048)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	// the local daemon adds the hostname
+	assert.Equal(t, `<131>Jan  2 03:04:05 app[42]: failed k="v w"`, string(buf[:n]))
+}
+
+// readOctetCounted reads a message framed by octet counting.
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	msg := make([]byte, n)
+	_, err = io.ReadFull(r, msg)
+	return string(msg), err
+}
+
+func TestSyslogTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- c
+		}
+	}()
+
+	h, err := NewSyslogHandler("tcp", ln.Addr().String(), WithSyslogHostname("host"), WithSyslogAppName("app"),
+		WithSyslogProcID("-"),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	handle := func(msg string) error {
+		e := syslogEvent(easylog.INFO, msg)
+		defer e.Put()
+		_, err := h.Handle(e)
+		return err
+	}
+
+	c := <-conns
+	assert.Nil(t, handle("first"))
+	msg, err := readOctetCounted(bufio.NewReader(c))
+	assert.Nil(t, err)
+	assert.Equal(t, "<14>1 2024-01-02T03:04:05.123456Z host app - - - first", msg)
+
+	// the server drops the connection, the handler reconnects once its writes fail
+	assert.Nil(t, c.Close())
+	var c2 net.Conn
+	assert.Eventually(t, func() bool {
+		_ = handle("again")
+		select {
+		case c2 = <-conns:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	defer c2.Close()
+
+	r := bufio.NewReader(c2)
+	msg, err = readOctetCounted(r)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(msg, " again"))
+
+	assert.Nil(t, h.Close())
+	assert.NotNil(t, handle("closed"))
+}
+
+func TestSyslogSanitize(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+
+	h, err := NewSyslogHandler("udp", pc.LocalAddr().String(), WithSyslogHostname("host"),
+		WithSyslogAppName("app"), WithSyslogProcID("-"), WithSyslogStructuredData(`t"]=s`, ""),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	e := syslogEvent(easylog.INFO, "m")
+	e.Tag(`a"]=b`, "v").Kv("", 1).Kv(strings.Repeat("k", 40), 2)
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, `<14>1 2024-01-02T03:04:05.123456Z host app - - [t___s a___b="v"][_ _="1" `+
+		strings.Repeat("k", 32)+`="2"] m`, string(buf[:n]))
+}
+
+func TestSyslogNewlineFraming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log")
+	ln, err := net.Listen("unix", path)
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	h, err := NewSyslogHandler("unix", path, WithSyslogFraming(SyslogNewline), WithSyslogHostname("host"),
+		WithSyslogAppName("app"), WithSyslogProcID("-"),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	c, err := ln.Accept()
+	assert.Nil(t, err)
+	defer c.Close()
+
+	for _, msg := range []string{"two\nlines", "next"} {
+		e := syslogEvent(easylog.INFO, msg)
+		_, err = h.Handle(e)
+		e.Put()
+		assert.Nil(t, err)
+	}
+
+	r := bufio.NewReader(c)
+	line, err := r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, `<14>1 2024-01-02T03:04:05.123456Z host app - - - two\nlines`+"\n", line)
+	line, err = r.ReadString('\n')
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(line, " next\n"), line)
+}
+
+func TestSyslogBackoff(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+
+	now := time.Now()
+	h, err := NewSyslogHandler("tcp", addr, WithSyslogBackoff(time.Second, 3*time.Second),
+		withSyslogClock(func() time.Time { return now }),
+	)
+	assert.Nil(t, err)
+	defer h.Close()
+
+	c, err := ln.Accept()
+	assert.Nil(t, err)
+	// nothing listens anymore
+	assert.Nil(t, c.Close())
+	assert.Nil(t, ln.Close())
+
+	handle := func() error {
+		e := syslogEvent(easylog.INFO, "m")
+		defer e.Put()
+		_, err := h.Handle(e)
+		return err
+	}
+
+	// the write may succeed once before the reset is noticed
+	assert.Eventually(t, func() bool { return handle() != nil }, 5*time.Second, 10*time.Millisecond)
+
+	// dropped without dialing while waiting for the backoff
+	assert.Nil(t, handle())
+	assert.Nil(t, handle())
+
+	// the backoff doubles after each failure, up to the max
+	now = now.Add(time.Second)
+	assert.NotNil(t, handle())
+	assert.Nil(t, handle())
+	now = now.Add(2 * time.Second)
+	assert.NotNil(t, handle())
+	now = now.Add(2 * time.Second)
+	assert.Nil(t, handle())
+	now = now.Add(time.Second)
+
+	ln, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skip("address reused:", err)
+	}
+	defer ln.Close()
+
+	err = handle()
+	assert.EqualError(t, err, "syslog: dropped 4 events while disconnected")
+
+	c, err = ln.Accept()
+	assert.Nil(t, err)
+	defer c.Close()
+	msg, err := readOctetCounted(bufio.NewReader(c))
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(msg, " m"), msg)
+	assert.Nil(t, handle())
+}
+
+func TestSyslogConfig(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer pc.Close()
+
+	h, err := newSyslogHandlerFromConfig(easylog.HandlerConfig{
+		Type: "syslog",
+		Options: map[string]interface{}{
+			"network": "udp", "address": pc.LocalAddr().String(), "format": "rfc3164", "facility": "daemon",
+			"hostname": "host", "app_name": "app", "procid": "7",
+		},
+	})
+	assert.Nil(t, err)
+	defer h.Close()
+
+	e := syslogEvent(easylog.INFO, "up")
+	_, err = h.Handle(e)
+	e.Put()
+	assert.Nil(t, err)
+
+	buf := make([]byte, 2048)
+	_ = pc.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := pc.ReadFrom(buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "<30>Jan  2 03:04:05 host app[7]: up", string(buf[:n]))
+
+	for _, opts := range []map[string]interface{}{
+		{"format": "json"},
+		{"framing": "nul"},
+		{"facility": "local9"},
+	} {
+		_, err := newSyslogHandlerFromConfig(easylog.HandlerConfig{Type: "syslog", Options: opts})
+		assert.NotNil(t, err)
+	}
+}
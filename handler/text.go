@@ -0,0 +1,547 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/covine/easylog"
+)
+
+// StdPattern is the pattern of StdFormatter.
+const StdPattern = "%levelcolor%-7level%reset%color{blue}%time%reset %color{grayblack}%logger%reset" +
+	"%?{ %color{yellowblue}%file%reset %color{blackgray}%func%reset %color{red}[%line]%reset}" +
+	" %color{cyan}%msg%reset%?{ %fields}%?{ %trace}%?{%n%stack}"
+
+// DefaultTextPattern is the pattern of NewTextFormatter without WithTextPattern.
+const DefaultTextPattern = "%time{RFC3339Nano} %level %logger%?{ %file{trimmed}:%line} %msg" +
+	"%?{ %tags}%?{ %kvs}%?{ %fields}%?{ %trace}%?{ error=%error}%?{%n%stack}"
+
+var stdFormatter = mustTextFormatter(WithTextPattern(StdPattern), WithTextKeyColor("yellow"))
+
+// TextOption configures the Formatter built by NewTextFormatter.
+type TextOption func(*textFormatter)
+
+// WithTextPattern lays the Events out according to pattern, DefaultTextPattern by default.
+//
+// The text of the pattern is copied as it is, except the verbs starting with '%':
+//
+//	%time{layout}  the time of the Event, the layout is the name of a layout constant of package time,
+//	               like RFC3339Nano or DateTime, or a layout itself. DateTime by default.
+//	%level{case}   the Level, the case is upper (default) or lower
+//	%levelcolor    the color of the Level
+//	%logger        the name of the Logger, "root" for the root Logger
+//	%file{style}   the file of the caller, the style is short (default) for the base name,
+//	               trimmed for the last directory and the base name, or full
+//	%line          the line of the caller
+//	%func{style}   the function of the caller, the style is short (default) for the last segment of the name, or full
+//	%msg           the message
+//	%tags          the tags, sorted by key
+//	%kvs           the kvs, sorted by key
+//	%fields        the typed fields, in order
+//	%trace         the trace context, as trace_id, span_id and trace_flags
+//	%error         the error
+//	%stack         the stack
+//	%extra         the attachment
+//	%color{name}   the color of package handler named in lower case, like yellow or grayblack
+//	%reset         resets the color
+//	%n             a newline
+//	%%             a percent sign
+//	%?{pattern}    pattern, only if one of its verbs other than colors and newlines writes something
+//
+// A verb may be padded to a width, aligned right as in %10logger or left as in %-10logger.
+// The caller verbs write nothing when there is no caller. Braces in the text of %?{} must be balanced.
+func WithTextPattern(pattern string) TextOption {
+	return func(f *textFormatter) {
+		f.pattern = pattern
+	}
+}
+
+// WithTextColor enables the colors, the default. Without colors, the color verbs write nothing.
+func WithTextColor(color bool) TextOption {
+	return func(f *textFormatter) {
+		f.color = color
+	}
+}
+
+// WithTextKeyColor colors the keys of the tags, kvs and fields, by the name of a color as in %color{name}.
+func WithTextKeyColor(name string) TextOption {
+	return func(f *textFormatter) {
+		f.keyColorName = name
+	}
+}
+
+// WithTextLocation writes the time in loc instead of the location of the Event.
+func WithTextLocation(loc *time.Location) TextOption {
+	return func(f *textFormatter) {
+		f.loc = loc
+	}
+}
+
+// WithTextPairSeparator separates the pairs of the tags, kvs and fields with sep, " " by default.
+func WithTextPairSeparator(sep string) TextOption {
+	return func(f *textFormatter) {
+		f.pairSep = sep
+	}
+}
+
+// WithTextKeyValueSeparator separates the keys from the values with sep, "=" by default.
+func WithTextKeyValueSeparator(sep string) TextOption {
+	return func(f *textFormatter) {
+		f.kvSep = sep
+	}
+}
+
+type textFormatter struct {
+	pattern      string
+	color        bool
+	keyColorName string
+	keyColor     *string
+	loc          *time.Location
+	pairSep      string
+	kvSep        string
+
+	segments []textSegment
+}
+
+// textSegment is either a literal text, a verb or a group of segments.
+type textSegment struct {
+	literal string
+	verb    string
+	arg     string
+	width   int
+	left    bool
+	color   *string
+	group   []textSegment
+}
+
+// NewTextFormatter returns a Formatter writing Events as text laid out by a pattern, see WithTextPattern.
+// StdFormatter is NewTextFormatter(WithTextPattern(StdPattern), WithTextKeyColor("yellow")).
+func NewTextFormatter(opts ...TextOption) (Formatter, error) {
+	f := &textFormatter{
+		pattern: DefaultTextPattern,
+		color:   true,
+		pairSep: " ",
+		kvSep:   "=",
+	}
+
+	for _, o := range opts {
+		o(f)
+	}
+
+	if f.keyColorName != "" {
+		c, ok := colorByName(f.keyColorName)
+		if !ok {
+			return nil, fmt.Errorf("text formatter: unknown color %q", f.keyColorName)
+		}
+		f.keyColor = c
+	}
+
+	segments, err := parseTextPattern(f.pattern)
+	if err != nil {
+		return nil, fmt.Errorf("text formatter: %w", err)
+	}
+	f.segments = segments
+
+	return f.format, nil
+}
+
+func mustTextFormatter(opts ...TextOption) Formatter {
+	f, err := NewTextFormatter(opts...)
+	if err != nil {
+		panic(err)
+	}
+
+	return f
+}
+
+// colorByName returns a pointer to the color variable, as the colors are disabled on windows at init.
+func colorByName(name string) (*string, bool) {
+	c, ok := map[string]*string{
+		"reset":       &Reset,
+		"bold":        &Bold,
+		"faint":       &Faint,
+		"underlined":  &Underlined,
+		"blink":       &Blink,
+		"black":       &Black,
+		"red":         &Red,
+		"green":       &Green,
+		"yellow":      &Yellow,
+		"blue":        &Blue,
+		"purple":      &Purple,
+		"cyan":        &Cyan,
+		"gray":        &Gray,
+		"white":       &White,
+		"grayblack":   &GrayBlack,
+		"cyanred":     &CyanRed,
+		"purplegreen": &PurpleGreen,
+		"blueyellow":  &BlueYellow,
+		"yellowblue":  &YellowBlue,
+		"greenpurple": &GreenPurple,
+		"redcyan":     &RedCyan,
+		"blackgray":   &BlackGray,
+		"blackwhite":  &BlackWhite,
+	}[name]
+
+	return c, ok
+}
+
+var timeLayouts = map[string]string{
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RubyDate":    time.RubyDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+	"DateTime":    time.DateTime,
+	"DateOnly":    time.DateOnly,
+	"TimeOnly":    time.TimeOnly,
+}
+
+func parseTextPattern(pattern string) ([]textSegment, error) {
+	var segments []textSegment
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			segments = append(segments, textSegment{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		if c != '%' {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+
+		i++
+		if i < len(pattern) && pattern[i] == '%' {
+			literal.WriteByte('%')
+			i++
+			continue
+		}
+
+		s := textSegment{}
+		if i < len(pattern) && pattern[i] == '-' {
+			s.left = true
+			i++
+		}
+		for i < len(pattern) && pattern[i] >= '0' && pattern[i] <= '9' {
+			s.width = s.width*10 + int(pattern[i]-'0')
+			i++
+		}
+
+		start := i
+		if i < len(pattern) && pattern[i] == '?' {
+			i++
+		} else {
+			for i < len(pattern) && pattern[i] >= 'a' && pattern[i] <= 'z' {
+				i++
+			}
+		}
+		s.verb = pattern[start:i]
+		if s.verb == "" {
+			return nil, fmt.Errorf("missing verb at %d", start)
+		}
+
+		if i < len(pattern) && pattern[i] == '{' {
+			depth := 1
+			j := i + 1
+			for ; j < len(pattern) && depth > 0; j++ {
+				switch pattern[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				}
+			}
+			if depth > 0 {
+				return nil, fmt.Errorf("unterminated %%%s{", s.verb)
+			}
+			s.arg = pattern[i+1 : j-1]
+			i = j
+		}
+
+		if err := s.compile(); err != nil {
+			return nil, err
+		}
+
+		flush()
+		segments = append(segments, s)
+	}
+	flush()
+
+	return segments, nil
+}
+
+// compile checks the verb and its argument.
+func (s *textSegment) compile() error {
+	switch s.verb {
+	case "?":
+		if s.arg == "" {
+			return errors.New("%? without pattern")
+		}
+		group, err := parseTextPattern(s.arg)
+		if err != nil {
+			return err
+		}
+		s.group = group
+	case "time":
+		if s.arg == "" {
+			s.arg = time.DateTime
+		} else if layout, ok := timeLayouts[s.arg]; ok {
+			s.arg = layout
+		}
+	case "level":
+		if s.arg != "" && s.arg != "upper" && s.arg != "lower" {
+			return fmt.Errorf("unknown case %q of %%level", s.arg)
+		}
+	case "file":
+		if s.arg != "" && s.arg != "short" && s.arg != "trimmed" && s.arg != "full" {
+			return fmt.Errorf("unknown style %q of %%file", s.arg)
+		}
+	case "func":
+		if s.arg != "" && s.arg != "short" && s.arg != "full" {
+			return fmt.Errorf("unknown style %q of %%func", s.arg)
+		}
+	case "color":
+		c, ok := colorByName(s.arg)
+		if !ok {
+			return fmt.Errorf("unknown color %q", s.arg)
+		}
+		s.color = c
+	case "reset":
+		s.color = &Reset
+	case "levelcolor", "logger", "line", "msg", "tags", "kvs", "fields", "trace", "error", "stack", "extra", "n":
+		if s.arg != "" {
+			return fmt.Errorf("unexpected argument of %%%s", s.verb)
+		}
+	default:
+		return fmt.Errorf("unknown verb %%%s", s.verb)
+	}
+
+	return nil
+}
+
+func (f *textFormatter) format(e *easylog.Event) ([]byte, error) {
+	b, _ := f.render(make([]byte, 0, 256), f.segments, e)
+	return b, nil
+}
+
+// render appends the segments, and reports whether a verb other than colors and newlines wrote something.
+func (f *textFormatter) render(dst []byte, segments []textSegment, e *easylog.Event) ([]byte, bool) {
+	content := false
+
+	for i := range segments {
+		s := &segments[i]
+		if s.verb == "" {
+			dst = append(dst, s.literal...)
+			continue
+		}
+
+		start := len(dst)
+		switch s.verb {
+		case "?":
+			var ok bool
+			if dst, ok = f.render(dst, s.group, e); !ok {
+				dst = dst[:start]
+			}
+		case "color", "reset":
+			if f.color {
+				dst = append(dst, *s.color...)
+			}
+			continue
+		case "levelcolor":
+			if f.color {
+				dst = append(dst, levelColor(e.GetLevel())...)
+			}
+			continue
+		case "n":
+			dst = append(dst, '\n')
+			continue
+		default:
+			dst = f.appendVerb(dst, s, e)
+		}
+
+		if len(dst) > start {
+			content = true
+		}
+		dst = pad(dst, start, s.width, s.left)
+	}
+
+	return dst, content
+}
+
+func (f *textFormatter) appendVerb(dst []byte, s *textSegment, e *easylog.Event) []byte {
+	caller := e.GetCaller()
+
+	switch s.verb {
+	case "time":
+		t := e.GetTime()
+		if f.loc != nil {
+			t = t.In(f.loc)
+		}
+		return t.AppendFormat(dst, s.arg)
+	case "level":
+		if s.arg == "lower" {
+			return append(dst, strings.ToLower(e.GetLevel().String())...)
+		}
+		return append(dst, e.GetLevel().String()...)
+	case "logger":
+		return append(dst, loggerName(e.GetLogger().Name())...)
+	case "file":
+		if !caller.GetOK() {
+			return dst
+		}
+		switch s.arg {
+		case "full":
+			return append(dst, caller.GetFile()...)
+		case "trimmed":
+			return append(dst, trimmedPath(caller.GetFile())...)
+		default:
+			return append(dst, path.Base(caller.GetFile())...)
+		}
+	case "line":
+		if !caller.GetOK() {
+			return dst
+		}
+		return strconv.AppendInt(dst, int64(caller.GetLine()), 10)
+	case "func":
+		if !caller.GetOK() {
+			return dst
+		}
+		if s.arg == "full" {
+			return append(dst, caller.GetFunc()...)
+		}
+		fn := caller.GetFunc()
+		return append(dst, fn[strings.LastIndexByte(fn, '.')+1:]...)
+	case "msg":
+		return append(dst, e.GetMsg()...)
+	case "tags":
+		return f.appendMap(dst, e.GetTags())
+	case "kvs":
+		return f.appendMap(dst, e.GetKvs())
+	case "fields":
+		fields := e.GetFields()
+		for i := range fields {
+			if i > 0 {
+				dst = append(dst, f.pairSep...)
+			}
+			dst = f.appendKey(dst, fields[i].Key)
+			dst = appendFieldText(dst, &fields[i])
+		}
+		return dst
+	case "trace":
+		t := e.GetTrace()
+		if !t.IsValid() {
+			return dst
+		}
+		dst = f.appendKey(dst, traceIDKey)
+		dst = append(dst, t.TraceIDString()...)
+		dst = append(dst, f.pairSep...)
+		dst = f.appendKey(dst, spanIDKey)
+		dst = append(dst, t.SpanIDString()...)
+		dst = append(dst, f.pairSep...)
+		dst = f.appendKey(dst, traceFlagsKey)
+		return append(dst, t.FlagsString()...)
+	case "error":
+		if err := e.GetError(); err != nil {
+			return append(dst, err.Error()...)
+		}
+		return dst
+	case "stack":
+		return append(dst, e.GetStack()...)
+	case "extra":
+		if extra := e.GetExtra(); extra != nil {
+			return append(dst, fmt.Sprint(extra)...)
+		}
+		return dst
+	}
+
+	return dst
+}
+
+// appendKey appends the key and the key value separator, colored with the key color.
+func (f *textFormatter) appendKey(dst []byte, k string) []byte {
+	if f.color && f.keyColor != nil {
+		dst = append(dst, *f.keyColor...)
+		dst = append(dst, k...)
+		dst = append(dst, f.kvSep...)
+		return append(dst, Reset...)
+	}
+
+	dst = append(dst, k...)
+	return append(dst, f.kvSep...)
+}
+
+func (f *textFormatter) appendMap(dst []byte, m map[interface{}]interface{}) []byte {
+	keys := make([]string, 0, len(m))
+	values := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		s := fmt.Sprint(k)
+		keys = append(keys, s)
+		values[s] = v
+	}
+	sort.Strings(keys)
+
+	for i, k := range keys {
+		if i > 0 {
+			dst = append(dst, f.pairSep...)
+		}
+		dst = f.appendKey(dst, k)
+		dst = append(dst, fmt.Sprint(values[k])...)
+	}
+
+	return dst
+}
+
+func levelColor(level easylog.Level) string {
+	switch level {
+	case easylog.DEBUG:
+		return White
+	case easylog.INFO:
+		return Green
+	case easylog.WARN:
+		return Purple
+	case easylog.ERROR, easylog.PANIC, easylog.FATAL:
+		return Red
+	default:
+		return Gray
+	}
+}
+
+// pad pads dst[start:] with spaces up to width runes.
+func pad(dst []byte, start, width int, left bool) []byte {
+	n := width - utf8.RuneCount(dst[start:])
+	if n <= 0 {
+		return dst
+	}
+
+	spaces := strings.Repeat(" ", n)
+	if left {
+		return append(dst, spaces...)
+	}
+
+	dst = append(dst, spaces...)
+	copy(dst[start+n:], dst[start:len(dst)-n])
+	copy(dst[start:], spaces)
+
+	return dst
+}
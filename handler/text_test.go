@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"errors"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+)
+
+// textCapture formats the Events with several Formatters and keeps their time.
+type textCapture struct {
+	fs   []Formatter
+	bs   [][]byte
+	time time.Time
+}
+
+func (c *textCapture) Handle(e *easylog.Event) (bool, error) {
+	c.bs = c.bs[:0]
+	for _, f := range c.fs {
+		b, err := f(e)
+		if err != nil {
+			return false, err
+		}
+		c.bs = append(c.bs, b)
+	}
+	c.time = e.GetTime()
+	return true, nil
+}
+
+func (c *textCapture) Flush() error {
+	return nil
+}
+
+func (c *textCapture) Close() error {
+	return nil
+}
+
+func TestStdFormatterPreset(t *testing.T) {
+	logger := easylog.GetLogger("text_test")
+	c := &textCapture{fs: []Formatter{StdFormatter}}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Warn().Str("k", "v").Int("n", 2).Logf("msg")
+	assert.Equal(t,
+		Purple+"WARN   "+Reset+Blue+c.time.Format("2006-01-02 15:04:05")+Reset+" "+GrayBlack+"text_test"+Reset+
+			" "+Cyan+"msg"+Reset+" "+Yellow+"k="+Reset+"v"+" "+Yellow+"n="+Reset+"2",
+		string(c.bs[0]),
+	)
+
+	logger.EnableCaller(easylog.ERROR)
+	defer logger.DisableCaller(easylog.ERROR)
+	logger.EnableStack(easylog.ERROR)
+	defer logger.DisableStack(easylog.ERROR)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Error().Log()
+	line++
+	s := string(c.bs[0])
+	prefix := Red + "ERROR  " + Reset + Blue + c.time.Format("2006-01-02 15:04:05") + Reset + " " + GrayBlack + "text_test" + Reset +
+		" " + YellowBlue + "text_test.go" + Reset + " " + BlackGray + "TestStdFormatterPreset" + Reset +
+		" " + Red + "[" + strconv.Itoa(line) + "]" + Reset + " " + Cyan + Reset + "\n\t"
+	assert.True(t, strings.HasPrefix(s, prefix), s)
+}
+
+func TestTextFormatter(t *testing.T) {
+	loc := time.FixedZone("X", 3600)
+	custom, err := NewTextFormatter(
+		WithTextPattern("%time{RFC3339} [%-5level{lower}] %8logger %?{%file{trimmed}:%line %func{full} }%msg"+
+			"%?{ tags{%tags}}%?{ kvs{%kvs}}%?{ %fields}%?{ err=%error}%?{ extra=%extra} 100%%"),
+		WithTextLocation(loc),
+		WithTextColor(false),
+		WithTextKeyColor("yellow"),
+		WithTextPairSeparator(", "),
+		WithTextKeyValueSeparator(": "),
+	)
+	assert.Nil(t, err)
+	def, err := NewTextFormatter(WithTextColor(false))
+	assert.Nil(t, err)
+
+	logger := easylog.GetLogger("text")
+	c := &textCapture{fs: []Formatter{custom, def}}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Info().Tag("b", 1).Tag("a", 2).Kv("k", "v").Str("f", "x").Dur("d", time.Second).Logf("hello")
+	ts := c.time.In(loc).Format(time.RFC3339)
+	assert.Equal(t, ts+" [info ]     text hello tags{a: 2, b: 1} kvs{k: v} f: x, d: 1s 100%", string(c.bs[0]))
+	assert.Equal(t, c.time.Format(time.RFC3339Nano)+" INFO text hello a=2 b=1 k=v f=x d=1s", string(c.bs[1]))
+
+	logger.EnableCaller(easylog.ERROR)
+	defer logger.DisableCaller(easylog.ERROR)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Error().E(errors.New("boom")).Attach(3).Logf("failed")
+	line++
+	ts = c.time.In(loc).Format(time.RFC3339)
+	assert.Equal(t,
+		ts+" [error]     text handler/text_test.go:"+strconv.Itoa(line)+" github.com/covine/easylog/handler.TestTextFormatter failed err=boom extra=3 100%",
+		string(c.bs[0]),
+	)
+	assert.Equal(t,
+		c.time.Format(time.RFC3339Nano)+" ERROR text handler/text_test.go:"+strconv.Itoa(line)+" failed error=boom",
+		string(c.bs[1]),
+	)
+}
+
+func TestTextFormatterColors(t *testing.T) {
+	f, err := NewTextFormatter(WithTextPattern("%levelcolor%level%reset %color{grayblack}%?{%color{red}%error}%reset%n"))
+	assert.Nil(t, err)
+
+	logger := easylog.GetLogger("text_colors")
+	c := &textCapture{fs: []Formatter{f}}
+	logger.AddHandler(c)
+	defer logger.RemoveHandler(c)
+
+	logger.Debug().Log()
+	logger.Info().Log()
+	assert.Equal(t, Green+"INFO"+Reset+" "+GrayBlack+Reset+"\n", string(c.bs[0]))
+}
+
+func TestTextPatternErrors(t *testing.T) {
+	for _, p := range []string{
+		"%",
+		"%-",
+		"%unknown",
+		"%time{RFC3339",
+		"%?{}",
+		"%?{%bad}",
+		"%level{title}",
+		"%file{long}",
+		"%func{medium}",
+		"%color{pink}",
+		"%msg{x}",
+	} {
+		_, err := NewTextFormatter(WithTextPattern(p))
+		assert.NotNil(t, err, p)
+	}
+
+	_, err := NewTextFormatter(WithTextKeyColor("pink"))
+	assert.NotNil(t, err)
+}
+
+func TestPad(t *testing.T) {
+	assert.Equal(t, "ab  cd", string(pad([]byte("abcd"), 2, 4, false)))
+	assert.Equal(t, "abcd  ", string(pad([]byte("abcd"), 2, 4, true)))
+	assert.Equal(t, "abcd", string(pad([]byte("abcd"), 2, 1, true)))
+	assert.Equal(t, " 中文", string(pad([]byte("中文"), 0, 3, false)))
+}
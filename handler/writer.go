@@ -0,0 +1,243 @@
+package handler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/covine/easylog"
+	"github.com/covine/easylog/writer"
+)
+
+// WriterHandler formats Events and writes them to a writer.Writer, one Event per line.
+type WriterHandler struct {
+	mu           sync.Locker
+	w            writer.Writer
+	format       Formatter
+	encoder      Encoder
+	level        easylog.Level
+	terminator   string
+	errorHandler easylog.ErrorHandler
+
+	flushInterval time.Duration
+	autoFlush     *writer.AutoFlush
+	flushOnLevel  bool
+	flushLevel    easylog.Level
+}
+
+// WriterHandlerOption configures a WriterHandler.
+type WriterHandlerOption func(*WriterHandler)
+
+// WithWriterLevel makes the WriterHandler skip the Events below level.
+// The skipped Events are still handled by the next Handlers.
+func WithWriterLevel(level easylog.Level) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.level = level
+	}
+}
+
+// WithWriterTerminator sets the string written after each Event. The default is "\n".
+func WithWriterTerminator(terminator string) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.terminator = terminator
+	}
+}
+
+// WithWriterLocker sets the lock serializing the writes. The default is a sync.Mutex.
+// A lock could be shared by several WriterHandlers writing to the same writer.Writer.
+func WithWriterLocker(l sync.Locker) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.mu = l
+	}
+}
+
+// WithoutWriterLock disables the locking, for writer.Writers which are safe for concurrent use.
+func WithoutWriterLock() WriterHandlerOption {
+	return WithWriterLocker(nopLocker{})
+}
+
+// WithWriterErrorHandler reports the errors to h instead of returning them to the Logger.
+func WithWriterErrorHandler(h easylog.ErrorHandler) WriterHandlerOption {
+	return func(wh *WriterHandler) {
+		wh.errorHandler = h
+	}
+}
+
+// WithWriterEncoder encodes the Events with enc into pooled buffers instead of formatting them,
+// the Formatter given to NewWriterHandler is ignored and could be nil.
+func WithWriterEncoder(enc Encoder) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.encoder = enc
+	}
+}
+
+// WithWriterFlushInterval flushes the writer.Writer every interval, until Close, see writer.AutoFlush.
+// The errors are reported to the ErrorHandler set by WithWriterErrorHandler, if any.
+// It's ignored for a writer.BufWriter flushed periodically itself, so a buffer is flushed by a single schedule.
+func WithWriterFlushInterval(interval time.Duration) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.flushInterval = interval
+	}
+}
+
+// WithWriterFlushLevel flushes the writer.Writer after writing each Event at level or above.
+func WithWriterFlushLevel(level easylog.Level) WriterHandlerOption {
+	return func(h *WriterHandler) {
+		h.flushOnLevel = true
+		h.flushLevel = level
+	}
+}
+
+// NewWriterHandler writes the Events formatted by f to w. A nil f formats with StdFormatter,
+// unless an Encoder is set by WithWriterEncoder.
+func NewWriterHandler(w writer.Writer, f Formatter, opts ...WriterHandlerOption) *WriterHandler {
+	h := &WriterHandler{
+		mu:         &sync.Mutex{},
+		w:          w,
+		format:     f,
+		level:      easylog.DEBUG,
+		terminator: "\n",
+	}
+
+	for _, o := range opts {
+		o(h)
+	}
+	if h.format == nil && h.encoder == nil {
+		h.format = StdFormatter
+	}
+
+	if !flushedPeriodically(w) {
+		h.autoFlush = writer.NewAutoFlush(h.flushInterval, func() {
+			_ = h.Flush()
+		})
+	}
+
+	return h
+}
+
+// flushedPeriodically reports whether w is a writer.BufWriter with periodic flushes of its own.
+func flushedPeriodically(w writer.Writer) bool {
+	bw, ok := w.(*writer.BufWriter)
+	return ok && bw != nil && bw.FlushInterval() > 0
+}
+
+func (h *WriterHandler) Handle(e *easylog.Event) (bool, error) {
+	if e.GetLevel() < h.level {
+		return true, nil
+	}
+
+	if h.encoder != nil {
+		return h.encode(e)
+	}
+
+	b, err := h.format(e)
+	if err != nil {
+		return true, h.route(err)
+	}
+
+	// b is owned by the handler, write it with the terminator at once.
+	b = append(b, h.terminator...)
+
+	return true, h.write(b, h.flushOnLevel && e.GetLevel() >= h.flushLevel)
+}
+
+func (h *WriterHandler) encode(e *easylog.Event) (bool, error) {
+	b := easylog.NewBytes()
+	defer easylog.PutBytes(b)
+
+	if err := h.encoder.Encode(b, e); err != nil {
+		return true, h.route(err)
+	}
+	b.AppendString(h.terminator)
+
+	return true, h.write(b.Bytes(), h.flushOnLevel && e.GetLevel() >= h.flushLevel)
+}
+
+func (h *WriterHandler) write(b []byte, flush bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.w.Write(b); err != nil {
+		return h.route(err)
+	}
+
+	if flush {
+		return h.route(h.w.Flush())
+	}
+
+	return nil
+}
+
+func (h *WriterHandler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.route(h.w.Flush())
+}
+
+// Close stops the periodic flushes, then closes the writer.Writer.
+func (h *WriterHandler) Close() error {
+	h.autoFlush.Stop()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.route(h.w.Close())
+}
+
+// Writer returns the underlying writer.Writer.
+func (h *WriterHandler) Writer() writer.Writer {
+	return h.w
+}
+
+// route reports err to the errorHandler if any, otherwise returns it.
+func (h *WriterHandler) route(err error) error {
+	if err == nil || h.errorHandler == nil {
+		return err
+	}
+
+	// ignore error produced by errorHandler
+	_ = h.errorHandler.Handle(err)
+
+	return nil
+}
+
+type nopLocker struct{}
+
+func (nopLocker) Lock() {}
+
+func (nopLocker) Unlock() {}
+
+// WriterErrorHandler writes errors to a writer.Writer, one error per line.
+type WriterErrorHandler struct {
+	mu sync.Mutex
+	w  writer.Writer
+}
+
+func NewWriterErrorHandler(w writer.Writer) *WriterErrorHandler {
+	return &WriterErrorHandler{
+		w: w,
+	}
+}
+
+func (h *WriterErrorHandler) Handle(err error) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, werr := h.w.Write([]byte(err.Error() + "\n"))
+
+	return werr
+}
+
+func (h *WriterErrorHandler) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.w.Flush()
+}
+
+func (h *WriterErrorHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.w.Close()
+}
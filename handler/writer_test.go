@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/covine/easylog"
+	"github.com/covine/easylog/writer"
+)
+
+type memWriter struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	err      error
+	flushed  int
+	closed   int
+	flushErr error
+}
+
+func (m *memWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.buf.Write(p)
+}
+
+func (m *memWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flushed++
+	return m.flushErr
+}
+
+func (m *memWriter) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.closed++
+	return nil
+}
+
+func (m *memWriter) String() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.buf.String()
+}
+
+func msgFormatter(e *easylog.Event) ([]byte, error) {
+	return []byte(e.GetMsg()), nil
+}
+
+type errorCollector struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (c *errorCollector) Handle(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.errs = append(c.errs, err)
+	return nil
+}
+
+func (c *errorCollector) Flush() error {
+	return nil
+}
+
+func (c *errorCollector) Close() error {
+	return nil
+}
+
+func (c *errorCollector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]error(nil), c.errs...)
+}
+
+func TestWriterHandler(t *testing.T) {
+	logger := easylog.GetLogger("writer_handler_test")
+	logger.SetLevel(easylog.DEBUG)
+
+	w := &memWriter{}
+	h := NewWriterHandler(w, msgFormatter, WithWriterLevel(easylog.INFO), WithWriterTerminator("\r\n"))
+	logger.AddHandler(h)
+	defer logger.RemoveHandler(h)
+
+	logger.Debug().Logf("debug")
+	logger.Info().Logf("info")
+	logger.Error().Logf("error")
+
+	assert.Equal(t, "info\r\nerror\r\n", w.String())
+
+	logger.Flush()
+	logger.Close()
+	assert.Equal(t, 1, w.flushed)
+	assert.Equal(t, 1, w.closed)
+	assert.Equal(t, w, h.Writer())
+}
+
+func TestWriterHandlerNilFormatter(t *testing.T) {
+	e := easylog.NewEvent(easylog.GetLogger("writer_handler_test"), easylog.INFO, time.Now(), "hello")
+	defer e.Put()
+	want, err := StdFormatter(e)
+	assert.Nil(t, err)
+
+	// formats with StdFormatter instead of panicking
+	w := &memWriter{}
+	_, err = NewWriterHandler(w, nil).Handle(e)
+	assert.Nil(t, err)
+	assert.Equal(t, string(want)+"\n", w.String())
+
+	bw, err := writer.NewBufWriter(0, &memWriter{})
+	assert.Nil(t, err)
+	r := NewRingBufferHandler(bw, nil, 2, nil, 0)
+	defer r.Close()
+	assert.NotPanics(t, func() {
+		_, _ = r.Handle(e)
+		assert.Nil(t, r.Flush())
+	})
+	assert.Equal(t, RingStats{Enqueued: 1, Written: 1}, r.Stats())
+}
+
+func TestWriterHandlerErrorRouting(t *testing.T) {
+	e := &easylog.Event{}
+	w := &memWriter{err: errors.New("write error"), flushErr: errors.New("flush error")}
+
+	h := NewWriterHandler(w, msgFormatter)
+	next, err := h.Handle(e)
+	assert.True(t, next)
+	assert.Equal(t, "write error", err.Error())
+
+	c := &errorCollector{}
+	h = NewWriterHandler(w, msgFormatter, WithWriterErrorHandler(c), WithoutWriterLock())
+	next, err = h.Handle(e)
+	assert.True(t, next)
+	assert.Nil(t, err)
+	assert.Nil(t, h.Flush())
+
+	h = NewWriterHandler(w, func(*easylog.Event) ([]byte, error) {
+		return nil, errors.New("format error")
+	}, WithWriterErrorHandler(c))
+	next, err = h.Handle(e)
+	assert.True(t, next)
+	assert.Nil(t, err)
+
+	errs := c.Errors()
+	assert.Equal(t, 3, len(errs))
+	assert.Equal(t, "write error", errs[0].Error())
+	assert.Equal(t, "flush error", errs[1].Error())
+	assert.Equal(t, "format error", errs[2].Error())
+}
+
+func TestWriterHandlerSharedLocker(t *testing.T) {
+	logger := easylog.GetLogger("writer_handler_test.shared")
+	logger.SetLevel(easylog.DEBUG)
+
+	var mu sync.Mutex
+	w := &memWriter{}
+	h1 := NewWriterHandler(w, msgFormatter, WithWriterLocker(&mu))
+	h2 := NewWriterHandler(w, msgFormatter, WithWriterLocker(&mu))
+	logger.AddHandler(h1)
+	logger.AddHandler(h2)
+	defer logger.ResetHandler()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info().Logf("line")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 20, bytes.Count([]byte(w.String()), []byte("line\n")))
+}
+
+func TestWriterHandlerWithFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file.log")
+	w, err := writer.NewFileWriter(path)
+	assert.Nil(t, err)
+
+	logger := easylog.GetLogger("writer_handler_test.file")
+	h := NewWriterHandler(w, msgFormatter)
+	logger.AddHandler(h)
+	defer logger.RemoveHandler(h)
+
+	logger.Info().Logf("to file")
+	logger.Flush()
+	logger.Close()
+
+	b, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "to file\n", string(b))
+}
+
+func TestWriterHandlerAutoFlush(t *testing.T) {
+	logger := easylog.GetLogger("writer_handler_test.flush")
+	logger.SetLevel(easylog.DEBUG)
+
+	flushed := func(w *memWriter) int {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.flushed
+	}
+
+	w := &memWriter{}
+	h := NewWriterHandler(w, msgFormatter, WithWriterFlushLevel(easylog.ERROR))
+	logger.AddHandler(h)
+	logger.Info().Logf("info")
+	assert.Equal(t, 0, flushed(w))
+	logger.Error().Logf("error")
+	assert.Equal(t, 1, flushed(w))
+	logger.ResetHandler()
+
+	w = &memWriter{}
+	h = NewWriterHandler(w, msgFormatter, WithWriterFlushInterval(time.Millisecond))
+	assert.Eventually(t, func() bool {
+		return flushed(w) > 0
+	}, time.Second, time.Millisecond)
+
+	// no more flushes once closed
+	assert.Nil(t, h.Close())
+	n := flushed(w)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, n, flushed(w))
+
+	// a BufWriter flushed periodically is not flushed by the handler too
+	bw, err := writer.NewBufWriter(0, &memWriter{}, writer.WithBufFlushInterval(time.Hour))
+	assert.Nil(t, err)
+	h = NewWriterHandler(bw, msgFormatter, WithWriterFlushInterval(time.Millisecond))
+	assert.Nil(t, h.autoFlush)
+	assert.Nil(t, h.Close())
+}
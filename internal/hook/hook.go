@@ -0,0 +1,9 @@
+// Package hook gives easylogtest access to the unexported state of easylog, without making it part of the API.
+package hook
+
+// Isolate resets the settings of the Loggers of easylog to their defaults, passes each Event logged by any of
+// them to tap, an easylog.Handler, and returns a func restoring the settings. It's set by easylog.
+var Isolate func(tap interface{}) (restore func())
+
+// StringsField returns an easylog.Field of key holding s, as Event.Strs adds it. It's set by easylog.
+var StringsField func(key string, s []string) interface{}
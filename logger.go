@@ -1,5 +1,14 @@
 package easylog
 
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
 type Level int8
 
 const (
@@ -33,59 +42,193 @@ func (l Level) String() string {
 	}
 }
 
-// Logger is not thread safe
-// Make sure to configure the Logger before emitting logs,
-// And do not reconfigure the Logger during runtime.
-type Logger struct {
-	manager     *manager
-	parent      *Logger
-	placeholder bool
-	children    map[*Logger]struct{}
+// ParseLevel parses the name of a Level, case-insensitively.
+func ParseLevel(s string) (Level, error) {
+	for l := _MIN; l <= _MAX; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown level %q", s)
+}
+
+func (l Level) MarshalText() ([]byte, error) {
+	if l < _MIN || l > _MAX {
+		return nil, fmt.Errorf("unknown level %d", l)
+	}
 
-	name      string
+	return []byte(l.String()), nil
+}
+
+func (l *Level) UnmarshalText(b []byte) error {
+	level, err := ParseLevel(string(b))
+	if err != nil {
+		return err
+	}
+
+	*l = level
+
+	return nil
+}
+
+// levels is the number of valid Levels, from _MIN to _MAX.
+const levels = int(_MAX-_MIN) + 1
+
+// loggerConfig is an immutable snapshot of the Logger's configuration.
+// A published loggerConfig must never be modified, setters clone it, apply
+// their change to the clone and publish the clone.
+type loggerConfig struct {
 	propagate bool
 	level     Level
 
 	handlers     []Handler
 	errorHandler ErrorHandler
 
-	caller map[Level]bool
-	stack  map[Level]bool
+	caller [levels]bool
+	stack  [levels]bool
 
 	tags map[interface{}]interface{}
 	kvs  map[interface{}]interface{}
 }
 
-func newLogger() *Logger {
-	return &Logger{
-		children:     make(map[*Logger]struct{}),
+// clone returns a shallow copy of the loggerConfig.
+// Slices and maps are shared, copy them before modifying.
+func (c *loggerConfig) clone() *loggerConfig {
+	r := *c
+	return &r
+}
+
+func copyMap(m map[interface{}]interface{}) map[interface{}]interface{} {
+	r := make(map[interface{}]interface{}, len(m))
+	for k, v := range m {
+		r[k] = v
+	}
+	return r
+}
+
+// Logger is safe for concurrent use.
+// The configuration is published as an immutable snapshot, so it could be
+// changed at runtime from any goroutine while other goroutines are emitting logs,
+// and emitting logs never blocks on a lock.
+type Logger struct {
+	manager     *manager
+	parent      atomic.Value // *Logger
+	placeholder bool
+	children    map[*Logger]struct{}
+
+	name string
+
+	// mu serializes the writers of config.
+	mu     sync.Mutex
+	config atomic.Value // *loggerConfig
+}
+
+// equal reports whether c and o hold the same settings and the very same Handlers.
+func (c *loggerConfig) equal(o *loggerConfig) bool {
+	if c.propagate != o.propagate || c.level != o.level || c.caller != o.caller || c.stack != o.stack {
+		return false
+	}
+
+	if !sameHandler(c.errorHandler, o.errorHandler) || len(c.handlers) != len(o.handlers) {
+		return false
+	}
+	for i := range c.handlers {
+		if !sameHandler(c.handlers[i], o.handlers[i]) {
+			return false
+		}
+	}
+
+	return reflect.DeepEqual(c.tags, o.tags) && reflect.DeepEqual(c.kvs, o.kvs)
+}
+
+// sameHandler compares a and b by identity, Handlers of non comparable types are never the same.
+func sameHandler(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	ta := reflect.TypeOf(a)
+	if ta != reflect.TypeOf(b) || !ta.Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+func newLoggerConfig() *loggerConfig {
+	return &loggerConfig{
 		handlers:     make([]Handler, 0),
 		errorHandler: NewNopErrorHandler(),
-		caller:       make(map[Level]bool),
-		stack:        make(map[Level]bool),
 		tags:         make(map[interface{}]interface{}),
 		kvs:          make(map[interface{}]interface{}),
 	}
 }
 
+func newLogger() *Logger {
+	l := &Logger{
+		children: make(map[*Logger]struct{}),
+	}
+
+	l.config.Store(newLoggerConfig())
+
+	return l
+}
+
+// load returns the current configuration snapshot.
+func (l *Logger) load() *loggerConfig {
+	return l.config.Load().(*loggerConfig)
+}
+
+// update publishes a modified copy of the current configuration.
+func (l *Logger) update(f func(c *loggerConfig)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.load().clone()
+	f(c)
+	l.config.Store(c)
+}
+
+// store publishes c as the configuration, replacing all the settings at once.
+func (l *Logger) store(c *loggerConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.config.Store(c)
+}
+
+func (l *Logger) getParent() *Logger {
+	p, _ := l.parent.Load().(*Logger)
+	return p
+}
+
+func (l *Logger) setParent(p *Logger) {
+	l.parent.Store(p)
+}
+
 func (l *Logger) Name() string {
 	return l.name
 }
 
 func (l *Logger) SetPropagate(propagate bool) {
-	l.propagate = propagate
+	l.update(func(c *loggerConfig) {
+		c.propagate = propagate
+	})
 }
 
 func (l *Logger) GetPropagate() bool {
-	return l.propagate
+	return l.load().propagate
 }
 
 func (l *Logger) SetLevel(level Level) {
-	l.level = level
+	l.update(func(c *loggerConfig) {
+		c.level = level
+	})
 }
 
 func (l *Logger) GetLevel() Level {
-	return l.level
+	return l.load().level
 }
 
 func (l *Logger) AddHandler(h Handler) {
@@ -93,13 +236,17 @@ func (l *Logger) AddHandler(h Handler) {
 		return
 	}
 
-	for _, handler := range l.handlers {
-		if handler == h {
-			return
+	l.update(func(c *loggerConfig) {
+		for _, handler := range c.handlers {
+			if handler == h {
+				return
+			}
 		}
-	}
 
-	l.handlers = append(l.handlers, h)
+		handlers := make([]Handler, 0, len(c.handlers)+1)
+		handlers = append(handlers, c.handlers...)
+		c.handlers = append(handlers, h)
+	})
 }
 
 func (l *Logger) RemoveHandler(h Handler) {
@@ -107,76 +254,122 @@ func (l *Logger) RemoveHandler(h Handler) {
 		return
 	}
 
-	for i, handler := range l.handlers {
-		if handler == h {
-			l.handlers = append(l.handlers[:i], l.handlers[i+1:]...)
-			return
+	l.update(func(c *loggerConfig) {
+		for i, handler := range c.handlers {
+			if handler == h {
+				handlers := make([]Handler, 0, len(c.handlers)-1)
+				handlers = append(handlers, c.handlers[:i]...)
+				c.handlers = append(handlers, c.handlers[i+1:]...)
+				return
+			}
 		}
-	}
+	})
 }
 
 func (l *Logger) ResetHandler() {
-	l.handlers = make([]Handler, 0)
+	l.update(func(c *loggerConfig) {
+		c.handlers = make([]Handler, 0)
+	})
+}
+
+// Handlers returns the Handlers of the Logger.
+// The returned slice is shared, do not modify it.
+func (l *Logger) Handlers() []Handler {
+	return l.load().handlers
 }
 
 func (l *Logger) SetErrorHandler(w ErrorHandler) {
-	l.errorHandler = w
+	l.update(func(c *loggerConfig) {
+		c.errorHandler = w
+	})
+}
+
+func (l *Logger) GetErrorHandler() ErrorHandler {
+	return l.load().errorHandler
 }
 
 func (l *Logger) EnableCaller(level Level) {
 	if level >= _MIN && level <= _MAX {
-		l.caller[level] = true
+		l.update(func(c *loggerConfig) {
+			c.caller[level-_MIN] = true
+		})
 	}
 }
 
 func (l *Logger) DisableCaller(level Level) {
 	if level >= _MIN && level <= _MAX {
-		l.caller[level] = false
+		l.update(func(c *loggerConfig) {
+			c.caller[level-_MIN] = false
+		})
 	}
 }
 
 func (l *Logger) EnableStack(level Level) {
 	if level >= _MIN && level <= _MAX {
-		l.stack[level] = true
+		l.update(func(c *loggerConfig) {
+			c.stack[level-_MIN] = true
+		})
 	}
 }
 
 func (l *Logger) DisableStack(level Level) {
 	if level >= _MIN && level <= _MAX {
-		l.stack[level] = false
+		l.update(func(c *loggerConfig) {
+			c.stack[level-_MIN] = false
+		})
 	}
 }
 
 func (l *Logger) SetTag(k interface{}, v interface{}) {
-	l.tags[k] = v
+	l.update(func(c *loggerConfig) {
+		c.tags = copyMap(c.tags)
+		c.tags[k] = v
+	})
 }
 
 func (l *Logger) DelTag(k interface{}) {
-	delete(l.tags, k)
+	l.update(func(c *loggerConfig) {
+		c.tags = copyMap(c.tags)
+		delete(c.tags, k)
+	})
 }
 
 func (l *Logger) ResetTag() {
-	l.tags = make(map[interface{}]interface{})
+	l.update(func(c *loggerConfig) {
+		c.tags = make(map[interface{}]interface{})
+	})
 }
 
+// Tags returns the tags of the Logger.
+// The returned map is shared, do not modify it.
 func (l *Logger) Tags() map[interface{}]interface{} {
-	return l.tags
+	return l.load().tags
 }
 
 func (l *Logger) SetKv(k interface{}, v interface{}) {
-	l.kvs[k] = v
+	l.update(func(c *loggerConfig) {
+		c.kvs = copyMap(c.kvs)
+		c.kvs[k] = v
+	})
 }
 
 func (l *Logger) DelKv(k interface{}) {
-	delete(l.kvs, k)
+	l.update(func(c *loggerConfig) {
+		c.kvs = copyMap(c.kvs)
+		delete(c.kvs, k)
+	})
 }
 
 func (l *Logger) ResetKv() {
-	l.kvs = make(map[interface{}]interface{})
+	l.update(func(c *loggerConfig) {
+		c.kvs = make(map[interface{}]interface{})
+	})
 }
 
+// Kvs returns the kvs of the Logger.
+// The returned map is shared, do not modify it.
 func (l *Logger) Kvs() map[interface{}]interface{} {
-	return l.kvs
+	return l.load().kvs
 }
 
 func (l *Logger) Debug() *Event {
@@ -211,43 +404,47 @@ func (l *Logger) Fatal() *Event {
 }
 
 func (l *Logger) Flush() {
-	for _, handler := range l.handlers {
+	c := l.load()
+
+	for _, handler := range c.handlers {
 		if err := handler.Flush(); err != nil {
 			// ignore error produced by errorHandler
-			_ = l.errorHandler.Handle(err)
+			_ = c.errorHandler.Handle(err)
 		}
 	}
 
 	// ignore error produced by errorHandler
-	_ = l.errorHandler.Flush()
+	_ = c.errorHandler.Flush()
 }
 
 func (l *Logger) Close() {
-	for _, handler := range l.handlers {
+	c := l.load()
+
+	for _, handler := range c.handlers {
 		if err := handler.Close(); err != nil {
 			// ignore error produced by errorHandler
-			_ = l.errorHandler.Handle(err)
+			_ = c.errorHandler.Handle(err)
 		}
 	}
 
 	// ignore error produced by errorHandler
-	_ = l.errorHandler.Close()
+	_ = c.errorHandler.Close()
 }
 
 func (l *Logger) logCaller(level Level) bool {
-	if need, ok := l.caller[level]; ok {
-		return need
+	if level < _MIN || level > _MAX {
+		return false
 	}
 
-	return false
+	return l.load().caller[level-_MIN]
 }
 
 func (l *Logger) logStack(level Level) bool {
-	if need, ok := l.stack[level]; ok {
-		return need
+	if level < _MIN || level > _MAX {
+		return false
 	}
 
-	return false
+	return l.load().stack[level-_MIN]
 }
 
 // couldEnd could end the Logger with panic or os.exit().
@@ -265,7 +462,7 @@ func (l *Logger) couldEnd(level Level, v interface{}) {
 }
 
 func (l *Logger) log(level Level, done func(interface{})) *Event {
-	if level < l.level {
+	if level < l.load().level {
 		if done != nil {
 			done("")
 		}
@@ -278,22 +475,58 @@ func (l *Logger) log(level Level, done func(interface{})) *Event {
 func (l *Logger) handle(event *Event) {
 	defer event.Put()
 
-	if event.level < l.level {
-		return
+	if l.manager != nil {
+		l.manager.tapped(event)
 	}
 
-	for _, handler := range l.handlers {
-		next, err := handler.Handle(event)
-		if err != nil {
-			// ignore error produced by errorHandler
-			_ = l.errorHandler.Handle(err)
-		}
-		if !next {
-			return
+	l.dispatch(event)
+}
+
+// dispatch passes the Event through the Handlers of the Logger and its ancestors.
+// The configuration snapshots of the Loggers are taken when the Event arrives, see chain.
+func (l *Logger) dispatch(event *Event) {
+	var buf [8]*loggerConfig
+	for _, c := range l.chain(event.level, buf[:0]) {
+		for _, handler := range c.handlers {
+			next, err := handler.Handle(event)
+			if err != nil {
+				// ignore error produced by errorHandler
+				_ = c.errorHandler.Handle(err)
+			}
+			if !next {
+				return
+			}
 		}
 	}
+}
 
-	if l.propagate && l.parent != nil {
-		l.parent.handle(event)
+// chain returns, reusing configs, the configuration snapshots of l and of the ancestors an Event at level
+// propagates to. A Config being applied is seen as a whole or not at all: the snapshots are taken again if one
+// was published meanwhile.
+func (l *Logger) chain(level Level, configs []*loggerConfig) []*loggerConfig {
+	for {
+		var gen uint64
+		if l.manager != nil {
+			if gen = l.manager.gen.Load(); gen&1 == 1 {
+				runtime.Gosched()
+				continue
+			}
+		}
+
+		configs = configs[:0]
+		for p := l; p != nil; p = p.getParent() {
+			c := p.load()
+			if level < c.level {
+				break
+			}
+			configs = append(configs, c)
+			if !c.propagate {
+				break
+			}
+		}
+
+		if l.manager == nil || l.manager.gen.Load() == gen {
+			return configs
+		}
 	}
 }
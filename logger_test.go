@@ -2,6 +2,8 @@ package easylog
 
 import (
 	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -123,7 +125,7 @@ func TestLogger_EnableDisableStack(t *testing.T) {
 
 func TestLogger_NopErrorHandler(t *testing.T) {
 	l := newLogger()
-	assert.Equal(t, nopErrorHandler{}, *(l.errorHandler.(*nopErrorHandler)))
+	assert.Equal(t, nopErrorHandler{}, *(l.load().errorHandler.(*nopErrorHandler)))
 
 	h := &MockHandler{}
 	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
@@ -145,13 +147,13 @@ func TestLogger_NopErrorHandler(t *testing.T) {
 
 func TestLogger_SetErrorHandler(t *testing.T) {
 	l := newLogger()
-	assert.Equal(t, nopErrorHandler{}, *(l.errorHandler.(*nopErrorHandler)))
+	assert.Equal(t, nopErrorHandler{}, *(l.load().errorHandler.(*nopErrorHandler)))
 
 	h := &MockErrorHandler{}
 
 	l.SetErrorHandler(h)
 
-	assert.Equal(t, h, l.errorHandler)
+	assert.Equal(t, h, l.load().errorHandler)
 }
 
 func TestLogger_Tag(t *testing.T) {
@@ -199,50 +201,50 @@ func TestLogger_Kv(t *testing.T) {
 func TestLogger_AddRemoveHandler(t *testing.T) {
 	l := newLogger()
 
-	assert.Equal(t, 0, len(l.handlers))
+	assert.Equal(t, 0, len(l.load().handlers))
 
 	l.AddHandler(nil)
-	assert.Equal(t, 0, len(l.handlers))
+	assert.Equal(t, 0, len(l.load().handlers))
 	l.RemoveHandler(nil)
-	assert.Equal(t, 0, len(l.handlers))
+	assert.Equal(t, 0, len(l.load().handlers))
 
 	n := NewNopHandler()
 
 	l.AddHandler(n)
-	assert.Equal(t, 1, len(l.handlers))
-	assert.Equal(t, n, l.handlers[0])
+	assert.Equal(t, 1, len(l.load().handlers))
+	assert.Equal(t, n, l.load().handlers[0])
 
 	l.AddHandler(NewNopHandler())
-	assert.Equal(t, 1, len(l.handlers))
-	assert.Equal(t, n, l.handlers[0])
+	assert.Equal(t, 1, len(l.load().handlers))
+	assert.Equal(t, n, l.load().handlers[0])
 
 	h := &MockHandler{}
 
 	l.AddHandler(h)
-	assert.Equal(t, 2, len(l.handlers))
-	assert.Equal(t, n, l.handlers[0])
-	assert.Equal(t, h, l.handlers[1])
+	assert.Equal(t, 2, len(l.load().handlers))
+	assert.Equal(t, n, l.load().handlers[0])
+	assert.Equal(t, h, l.load().handlers[1])
 
 	l.AddHandler(h)
-	assert.Equal(t, 2, len(l.handlers))
-	assert.Equal(t, n, l.handlers[0])
-	assert.Equal(t, h, l.handlers[1])
+	assert.Equal(t, 2, len(l.load().handlers))
+	assert.Equal(t, n, l.load().handlers[0])
+	assert.Equal(t, h, l.load().handlers[1])
 
 	l.RemoveHandler(h)
-	assert.Equal(t, 1, len(l.handlers))
-	assert.Equal(t, n, l.handlers[0])
+	assert.Equal(t, 1, len(l.load().handlers))
+	assert.Equal(t, n, l.load().handlers[0])
 
 	l.RemoveHandler(n)
-	assert.Equal(t, 0, len(l.handlers))
+	assert.Equal(t, 0, len(l.load().handlers))
 }
 
 func TestLogger_Log(t *testing.T) {
 	p := newLogger()
-	p.parent = nil
+	p.setParent(nil)
 	p.SetLevel(ERROR)
 
 	l := newLogger()
-	l.parent = p
+	l.setParent(p)
 
 	pe := &MockErrorHandler{}
 	pe.On("Handle", mock.MatchedBy(func(err error) bool {
@@ -500,3 +502,115 @@ func TestLogger_FatalWithHighThanINFOLevel(t *testing.T) {
 	h.AssertExpectations(t)
 	e.AssertExpectations(t)
 }
+
+func TestLogger_ConcurrentReconfigure(t *testing.T) {
+	p := newLogger()
+	p.SetLevel(DEBUG)
+
+	l := newLogger()
+	l.setParent(p)
+	l.SetLevel(DEBUG)
+	l.SetPropagate(true)
+
+	var handled int64
+	h := &countHandler{n: &handled}
+
+	stop := make(chan struct{})
+	var w sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		w.Add(1)
+		go func() {
+			defer w.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				l.Info().Tag("t", "v").Kv("k", "v").Logf("concurrent")
+				_ = l.Tags()["service"]
+				_ = l.Kvs()["host"]
+				l.Flush()
+			}
+		}()
+	}
+
+	w.Add(1)
+	go func() {
+		defer w.Done()
+		for i := 0; i < 1000; i++ {
+			l.AddHandler(h)
+			p.AddHandler(h)
+			l.SetLevel(Level(i%3) + DEBUG)
+			l.SetPropagate(i%2 == 0)
+			l.EnableCaller(INFO)
+			l.EnableStack(ERROR)
+			l.SetTag("service", i)
+			l.SetKv("host", i)
+			l.SetErrorHandler(NewNopErrorHandler())
+			l.DisableCaller(INFO)
+			l.DisableStack(ERROR)
+			l.DelTag("service")
+			l.DelKv("host")
+			l.RemoveHandler(h)
+			p.RemoveHandler(h)
+			l.ResetHandler()
+			l.ResetTag()
+			l.ResetKv()
+		}
+		close(stop)
+	}()
+
+	w.Wait()
+
+	assert.Equal(t, 0, len(l.Handlers()))
+	assert.Equal(t, 0, len(l.Tags()))
+	assert.Equal(t, 0, len(l.Kvs()))
+}
+
+func TestLogger_ConcurrentGetLogger(t *testing.T) {
+	m := &manager{
+		loggerMap: make(map[string]*Logger),
+	}
+	m.root = m.getLogger("")
+
+	abc := m.getLogger("a.b.c")
+	abc.SetPropagate(true)
+	abc.SetLevel(DEBUG)
+
+	var w sync.WaitGroup
+	w.Add(2)
+	go func() {
+		defer w.Done()
+		for i := 0; i < 1000; i++ {
+			abc.Info().Logf("concurrent")
+		}
+	}()
+	go func() {
+		defer w.Done()
+		m.getLogger("a.b")
+		m.getLogger("a")
+	}()
+	w.Wait()
+
+	assert.True(t, abc.getParent() == m.getLogger("a.b"))
+	assert.True(t, m.getLogger("a.b").getParent() == m.getLogger("a"))
+}
+
+type countHandler struct {
+	n *int64
+}
+
+func (c *countHandler) Handle(_ *Event) (bool, error) {
+	atomic.AddInt64(c.n, 1)
+	return true, nil
+}
+
+func (c *countHandler) Flush() error {
+	return nil
+}
+
+func (c *countHandler) Close() error {
+	return nil
+}
@@ -3,12 +3,27 @@ package easylog
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type manager struct {
 	mu        sync.RWMutex
 	root      *Logger
 	loggerMap map[string]*Logger
+
+	// configMu serializes the applications of Config.
+	configMu sync.Mutex
+	applied  *appliedConfig
+	// gen is odd while a Config is being published, see Logger.chain
+	gen atomic.Uint64
+
+	// tap holds a tapHandler, see isolate
+	tap atomic.Value
+}
+
+// tapHandler is the Handler passed each Event logged by the Loggers of a manager.
+type tapHandler struct {
+	h Handler
 }
 
 func (m *manager) getLogger(name string) *Logger {
@@ -74,14 +89,69 @@ func (m *manager) fixUpParents(l *Logger) {
 		rv = m.root
 	}
 
-	l.parent = rv
+	l.setParent(rv)
 }
 
 func (m *manager) fixUpChildren(placeholder *Logger, l *Logger) {
 	for c := range placeholder.children {
-		if !strings.HasPrefix(c.parent.name, l.name) {
-			l.parent = c.parent
-			c.parent = l
+		if !strings.HasPrefix(c.getParent().name, l.name) {
+			l.setParent(c.getParent())
+			c.setParent(l)
 		}
 	}
 }
+
+// isolate resets the settings of the Loggers to their defaults, the configuration applied included,
+// and passes each Event they log to tap, until the returned func restores them.
+// The Loggers created meanwhile are reset by the returned func, which closes the Handlers of the
+// configuration applied meanwhile.
+func (m *manager) isolate(tap Handler) func() {
+	m.configMu.Lock()
+	applied := m.applied
+	m.applied = nil
+	m.configMu.Unlock()
+
+	m.mu.RLock()
+	configs := make(map[*Logger]*loggerConfig, len(m.loggerMap))
+	for _, l := range m.loggerMap {
+		if !l.placeholder {
+			configs[l] = l.load()
+			l.store(newLoggerConfig())
+		}
+	}
+	m.mu.RUnlock()
+
+	prevTap, _ := m.tap.Load().(tapHandler)
+	m.tap.Store(tapHandler{h: tap})
+
+	return func() {
+		m.tap.Store(prevTap)
+
+		m.mu.RLock()
+		for _, l := range m.loggerMap {
+			if l.placeholder {
+				continue
+			}
+			if c, ok := configs[l]; ok {
+				l.store(c)
+			} else {
+				l.store(newLoggerConfig())
+			}
+		}
+		m.mu.RUnlock()
+
+		m.configMu.Lock()
+		if m.applied != nil {
+			m.release(m.applied, &appliedConfig{})
+		}
+		m.applied = applied
+		m.configMu.Unlock()
+	}
+}
+
+// tapped passes e to the tap of the manager, if any.
+func (m *manager) tapped(e *Event) {
+	if t, ok := m.tap.Load().(tapHandler); ok && t.h != nil {
+		_, _ = t.h.Handle(e)
+	}
+}
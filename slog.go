@@ -0,0 +1,199 @@
+package easylog
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime"
+	"time"
+)
+
+// SlogLevel maps the Level to a slog.Level.
+// PANIC and FATAL are mapped above slog.LevelError, with the same step of 4.
+func SlogLevel(l Level) slog.Level {
+	return slog.Level(int(l) * 4)
+}
+
+// LevelFromSlog maps the slog.Level to the nearest Level not above it.
+func LevelFromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	case l < slog.LevelError+4:
+		return ERROR
+	case l < slog.LevelError+8:
+		return PANIC
+	default:
+		return FATAL
+	}
+}
+
+// groupedAttr is an attr added by WithAttrs, with the groups opened before it.
+type groupedAttr struct {
+	prefix string
+	attr   slog.Attr
+}
+
+// SlogHandler is a slog.Handler routing the records into a Logger,
+// respecting the level, the caller and stack settings, the Handlers and the propagation of the Logger.
+//
+// Attrs are added as typed fields, with the keys of groups joined by dots.
+// An error attr keyed "err" or "error" outside any group becomes the error of the Event.
+// Unlike the PANIC and FATAL methods of Logger, records at these levels never panic or exit.
+type SlogHandler struct {
+	l      *Logger
+	prefix string
+	attrs  []groupedAttr
+}
+
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{
+		l: l,
+	}
+}
+
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return LevelFromSlog(level) >= h.l.GetLevel()
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := LevelFromSlog(r.Level)
+
+	e := h.l.log(level, nil)
+	if e == nil {
+		return nil
+	}
+
+	e.time = r.Time
+	if e.time.IsZero() {
+		e.time = time.Now()
+	}
+	e.msg = r.Message
+
+	if ctx != nil {
+		e.Ctx(ctx)
+	}
+
+	if r.PC != 0 && h.l.logCaller(level) {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.caller.ok = frame.PC != 0
+		e.caller.pc = frame.PC
+		e.caller.file = frame.File
+		e.caller.line = frame.Line
+		e.caller.fc = frame.Function
+	}
+
+	if h.l.logStack(level) {
+		if r.PC != 0 {
+			// from the call site, whatever the depth of the slog API and of the wrapping Handlers
+			e.stack = e.stacktraceFrom(r.PC)
+		} else {
+			e.stack = e.stacktrace(1)
+		}
+	}
+
+	for _, a := range h.attrs {
+		addSlogAttr(e, a.prefix, a.attr)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(e, h.prefix, a)
+		return true
+	})
+
+	e.logger.handle(e)
+
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	r := h.clone()
+	for _, a := range attrs {
+		r.attrs = append(r.attrs, groupedAttr{prefix: h.prefix, attr: a})
+	}
+
+	return r
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	r := h.clone()
+	r.prefix = h.prefix + name + "."
+
+	return r
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	return &SlogHandler{
+		l:      h.l,
+		prefix: h.prefix,
+		attrs:  append(make([]groupedAttr, 0, len(h.attrs)+1), h.attrs...),
+	}
+}
+
+func addSlogAttr(e *Event, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := prefix + a.Key
+
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return
+		}
+		// a group without key is inlined
+		if a.Key != "" {
+			prefix = key + "."
+		}
+		for _, ga := range attrs {
+			addSlogAttr(e, prefix, ga)
+		}
+	case slog.KindString:
+		e.Str(key, a.Value.String())
+	case slog.KindInt64:
+		e.Int64(key, a.Value.Int64())
+	case slog.KindUint64:
+		if u := a.Value.Uint64(); u <= math.MaxInt64 {
+			e.Int64(key, int64(u))
+		} else {
+			e.Object(key, u)
+		}
+	case slog.KindFloat64:
+		e.Float64(key, a.Value.Float64())
+	case slog.KindBool:
+		e.Bool(key, a.Value.Bool())
+	case slog.KindDuration:
+		e.Dur(key, a.Value.Duration())
+	case slog.KindTime:
+		e.Time(key, a.Value.Time())
+	default:
+		switch v := a.Value.Any().(type) {
+		case error:
+			if prefix == "" && (a.Key == "err" || a.Key == "error") && e.e == nil {
+				e.E(v)
+			} else {
+				e.Err(key, v)
+			}
+		case []string:
+			e.Strs(key, v)
+		case []byte:
+			e.Bytes(key, v)
+		default:
+			e.Object(key, v)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package easylog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSlogLevel(t *testing.T) {
+	for _, l := range []Level{DEBUG, INFO, WARN, ERROR, PANIC, FATAL} {
+		assert.Equal(t, l, LevelFromSlog(SlogLevel(l)))
+	}
+
+	assert.Equal(t, slog.LevelDebug, SlogLevel(DEBUG))
+	assert.Equal(t, slog.LevelInfo, SlogLevel(INFO))
+	assert.Equal(t, slog.LevelWarn, SlogLevel(WARN))
+	assert.Equal(t, slog.LevelError, SlogLevel(ERROR))
+
+	assert.Equal(t, DEBUG, LevelFromSlog(slog.LevelDebug-4))
+	assert.Equal(t, INFO, LevelFromSlog(slog.LevelInfo+2))
+	assert.Equal(t, FATAL, LevelFromSlog(slog.LevelError+100))
+}
+
+func TestSlogHandler(t *testing.T) {
+	p := newLogger()
+	p.SetLevel(DEBUG)
+
+	l := newLogger()
+	l.setParent(p)
+	l.SetLevel(INFO)
+	l.SetPropagate(true)
+	l.EnableCaller(WARN)
+
+	err := errors.New("boom")
+	now := time.Now()
+
+	ph := &MockHandler{}
+	ph.On("Handle", mock.MatchedBy(func(e *Event) bool {
+		fields := e.GetFields()
+		keys := make([]string, 0, len(fields))
+		for _, f := range fields {
+			keys = append(keys, f.Key)
+		}
+
+		return e.GetLogger() == l && e.GetLevel() == WARN && e.GetMsg() == "hello" &&
+			e.GetError() == nil &&
+			e.GetCaller().GetOK() && strings.HasSuffix(e.GetCaller().GetFile(), "slog_test.go") &&
+			strings.Join(keys, ",") == "service,req.id,req.user.name,req.user.admin,req.took,req.at,req.err,req.n,req.error" &&
+			fields[1].GetInt() == 7 && fields[4].GetDuration() == time.Second && fields[5].GetTime().Equal(now) &&
+			fields[6].GetError() == err && fields[8].GetError() == err
+	})).Once().Return(true, nil)
+	p.AddHandler(ph)
+
+	logger := slog.New(NewSlogHandler(l)).With("service", "api").WithGroup("req").With("id", 7)
+
+	logger.Debug("dropped")
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelDebug))
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelInfo))
+
+	logger.Warn("hello",
+		slog.Group("user", "name", "u", "admin", true, slog.Group("empty")),
+		"took", time.Second,
+		"at", now,
+		"err", err,
+		slog.Uint64("n", 1),
+		slog.Any("error", err),
+	)
+
+	ph.AssertExpectations(t)
+}
+
+func TestSlogHandlerError(t *testing.T) {
+	l := newLogger()
+
+	err := errors.New("boom")
+
+	h := &MockHandler{}
+	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
+		return e.GetLevel() == ERROR && e.GetError() == err && len(e.GetFields()) == 0 &&
+			e.GetKvs()["request_id"] == "r1"
+	})).Once().Return(true, nil)
+	l.AddHandler(h)
+
+	ctx := WithContext(context.Background(), "request_id", "r1")
+	slog.New(NewSlogHandler(l)).ErrorContext(ctx, "failed", "error", err, slog.Group(""))
+
+	h.AssertExpectations(t)
+}
+
+// wrappingSlogHandler forwards the Records to its slog.Handler, as the middlewares do.
+type wrappingSlogHandler struct {
+	slog.Handler
+}
+
+func (h wrappingSlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestSlogHandlerStack(t *testing.T) {
+	l := newLogger()
+	l.EnableStack(INFO)
+
+	var stacks []string
+	h := &MockHandler{}
+	h.On("Handle", mock.Anything).Run(func(args mock.Arguments) {
+		stacks = append(stacks, args.Get(0).(*Event).GetStack())
+	}).Return(true, nil)
+	l.AddHandler(h)
+
+	logger := slog.New(wrappingSlogHandler{NewSlogHandler(l)})
+	logger.LogAttrs(context.Background(), slog.LevelInfo, "attrs", slog.Int("n", 1))
+	logger.Log(context.Background(), slog.LevelInfo, "log")
+	logger.Info("info")
+
+	assert.Equal(t, 3, len(stacks))
+	for _, s := range stacks {
+		assert.True(t, strings.HasPrefix(s, "\tgithub.com/covine/easylog.TestSlogHandlerStack\n\t"), s)
+		assert.Contains(t, strings.SplitN(s, "\n", 3)[1], "slog_test.go:")
+	}
+
+	// without a pc, from the caller of Handle
+	stacks = nil
+	assert.Nil(t, NewSlogHandler(l).Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "no pc", 0)))
+	assert.True(t, strings.HasPrefix(stacks[0], "\tgithub.com/covine/easylog.TestSlogHandlerStack\n\t"), stacks[0])
+}
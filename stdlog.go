@@ -0,0 +1,131 @@
+package easylog
+
+import (
+	"log"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// RedirectStdLog makes the standard log package log into l at level: each line written by log.Print and the like
+// becomes an Event, see NewStdLogger. The prefix and the flags of the standard logger are kept, and stripped
+// from the lines. The returned func restores the previous output.
+func RedirectStdLog(l *Logger, level Level) func() {
+	prev := log.Writer()
+	log.SetOutput(&stdLogWriter{l: l, level: level, std: log.Default()})
+
+	return func() {
+		log.SetOutput(prev)
+	}
+}
+
+// NewStdLogger returns a log.Logger logging into l at level, for the APIs taking one, like http.Server.ErrorLog.
+//
+// Each line is an Event, whose message is the line without the prefix, the date, the time and the file:line
+// the flags of the log.Logger add, nor the trailing newline. The caller is the function calling the log.Logger,
+// respecting the caller and stack settings of l. Unlike the PANIC and FATAL methods of Logger, lines at these
+// levels never panic or exit, log.Panic and log.Fatal do it themselves.
+func NewStdLogger(l *Logger, level Level) *log.Logger {
+	w := &stdLogWriter{l: l, level: level}
+	w.std = log.New(w, "", 0)
+	return w.std
+}
+
+// stdLogWriter is the output of a log.Logger, the one reading its prefix and flags.
+type stdLogWriter struct {
+	l     *Logger
+	level Level
+	std   *log.Logger
+}
+
+func (w *stdLogWriter) Write(b []byte) (int, error) {
+	e := w.l.log(w.level, nil)
+	if e == nil {
+		return len(b), nil
+	}
+
+	e.time = time.Now()
+	e.msg = stripStdLogHeader(string(b), w.std.Prefix(), w.std.Flags())
+
+	logCaller, logStack := w.l.logCaller(w.level), w.l.logStack(w.level)
+	if logCaller || logStack {
+		// ignore callers and Write
+		p, n := callers(2)
+		frame, more, frames := stdLogCaller(p.pcs[:n])
+
+		if logCaller {
+			e.caller.ok = frame.PC != 0
+			e.caller.pc = frame.PC
+			e.caller.file = frame.File
+			e.caller.line = frame.Line
+			e.caller.fc = frame.Function
+		}
+		if logStack && frame.PC != 0 {
+			e.stack = formatStack(frame, more, frames)
+		}
+
+		putPcs(p)
+	}
+
+	w.l.handle(e)
+
+	return len(b), nil
+}
+
+// stdLogCaller returns the first frame above the log package, and the frames following it.
+// It's the first frame when the writer is not called by the log package.
+func stdLogCaller(pcs []uintptr) (runtime.Frame, bool, *runtime.Frames) {
+	skip, inLog := 0, false
+	frames := runtime.CallersFrames(pcs)
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "log.") {
+			skip, inLog = i+1, true
+		} else if inLog || !more {
+			break
+		}
+	}
+
+	frames = runtime.CallersFrames(pcs)
+	for i := 0; i < skip; i++ {
+		frames.Next()
+	}
+	frame, more := frames.Next()
+
+	return frame, more, frames
+}
+
+// stripStdLogHeader returns the message of a line formatted by a log.Logger with prefix and flags.
+// What does not look like the header the flags add is kept.
+func stripStdLogHeader(s, prefix string, flags int) string {
+	s = strings.TrimSuffix(s, "\n")
+
+	if flags&log.Lmsgprefix == 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	// 2009/01/23
+	if flags&log.Ldate != 0 && len(s) > 10 && s[4] == '/' && s[7] == '/' && s[10] == ' ' {
+		s = s[11:]
+	}
+	// 01:23:23 or 01:23:23.123123
+	if flags&(log.Ltime|log.Lmicroseconds) != 0 {
+		n := 8
+		if flags&log.Lmicroseconds != 0 {
+			n = 15
+		}
+		if len(s) > n && s[2] == ':' && s[5] == ':' && s[n] == ' ' {
+			s = s[n+1:]
+		}
+	}
+	// /a/b/c/d.go:23: or d.go:23:
+	if flags&(log.Lshortfile|log.Llongfile) != 0 {
+		if i := strings.Index(s, ": "); i >= 0 {
+			s = s[i+2:]
+		}
+	}
+	if flags&log.Lmsgprefix != 0 {
+		s = strings.TrimPrefix(s, prefix)
+	}
+
+	return s
+}
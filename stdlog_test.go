@@ -0,0 +1,81 @@
+package easylog
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStripStdLogHeader(t *testing.T) {
+	for _, c := range []struct {
+		line   string
+		prefix string
+		flags  int
+		msg    string
+	}{
+		{"hello\n", "", 0, "hello"},
+		{"2009/01/23 01:23:23 hello\n", "", log.LstdFlags, "hello"},
+		{"[p] 2009/01/23 01:23:23.123123 /a/b.go:23: hello: world\n", "[p] ",
+			log.LstdFlags | log.Lmicroseconds | log.Llongfile, "hello: world"},
+		{"01:23:23 b.go:23: [p] hello\n", "[p] ", log.Ltime | log.Lshortfile | log.Lmsgprefix, "hello"},
+		// not a header
+		{"hello world\n", "", log.Ldate | log.Ltime, "hello world"},
+	} {
+		assert.Equal(t, c.msg, stripStdLogHeader(c.line, c.prefix, c.flags), c.line)
+	}
+}
+
+func TestNewStdLogger(t *testing.T) {
+	l := newLogger()
+	l.EnableCaller(WARN)
+	l.EnableStack(WARN)
+
+	h := &MockHandler{}
+	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
+		return e.GetLevel() == WARN && e.GetMsg() == "hello 1" &&
+			e.GetCaller().GetOK() && strings.HasSuffix(e.GetCaller().GetFile(), "stdlog_test.go") &&
+			e.GetCaller().GetFunc() == "github.com/covine/easylog.TestNewStdLogger" &&
+			strings.HasPrefix(e.GetStack(), "\tgithub.com/covine/easylog.TestNewStdLogger\n")
+	})).Once().Return(true, nil)
+	l.AddHandler(h)
+
+	std := NewStdLogger(l, WARN)
+	std.SetPrefix("[p] ")
+	std.SetFlags(log.LstdFlags | log.Lshortfile)
+	std.Printf("hello %d", 1)
+
+	h.AssertExpectations(t)
+
+	// below the level of the Logger
+	l.SetLevel(ERROR)
+	std.Print("dropped")
+	h.AssertExpectations(t)
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	l := newLogger()
+	l.EnableCaller(INFO)
+
+	h := &MockHandler{}
+	h.On("Handle", mock.MatchedBy(func(e *Event) bool {
+		return e.GetLevel() == INFO && e.GetMsg() == "hello" &&
+			e.GetCaller().GetFunc() == "github.com/covine/easylog.TestRedirectStdLog"
+	})).Once().Return(true, nil)
+	l.AddHandler(h)
+
+	defer log.SetOutput(log.Writer())
+	prev := &bytes.Buffer{}
+	log.SetOutput(prev)
+
+	restore := RedirectStdLog(l, INFO)
+	log.Println("hello")
+	restore()
+	log.Println("restored")
+
+	h.AssertExpectations(t)
+	assert.True(t, strings.HasSuffix(prev.String(), " restored\n"), prev.String())
+}
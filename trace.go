@@ -0,0 +1,171 @@
+package easylog
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// TraceContext identifies the span an Event is logged in, as the W3C Trace Context does.
+// See Event.Ctx, which finds it in the context of the Event.
+type TraceContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// IsValid reports whether the trace id and the span id are set.
+func (t TraceContext) IsValid() bool {
+	return t.TraceID != [16]byte{} && t.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set.
+func (t TraceContext) Sampled() bool {
+	return t.Flags&1 == 1
+}
+
+// TraceIDString returns the trace id in lowercase hex, on 32 characters.
+func (t TraceContext) TraceIDString() string {
+	return hex.EncodeToString(t.TraceID[:])
+}
+
+// SpanIDString returns the span id in lowercase hex, on 16 characters.
+func (t TraceContext) SpanIDString() string {
+	return hex.EncodeToString(t.SpanID[:])
+}
+
+// FlagsString returns the flags in lowercase hex, on 2 characters.
+func (t TraceContext) FlagsString() string {
+	return hex.EncodeToString([]byte{t.Flags})
+}
+
+// String returns t as a traceparent header of version 00.
+func (t TraceContext) String() string {
+	return "00-" + t.TraceIDString() + "-" + t.SpanIDString() + "-" + t.FlagsString()
+}
+
+// ParseTraceparent parses the value of a W3C traceparent header, like
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (TraceContext, error) {
+	var t TraceContext
+
+	// version-traceid-spanid-flags, the versions after 00 may append fields
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return t, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	var version [1]byte
+	if err := decodeLowerHex(version[:], s[:2]); err != nil || version[0] == 0xff {
+		return t, fmt.Errorf("invalid traceparent %q: version", s)
+	}
+	if (version[0] == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return t, fmt.Errorf("invalid traceparent %q", s)
+	}
+
+	if err := decodeLowerHex(t.TraceID[:], s[3:35]); err != nil {
+		return t, fmt.Errorf("invalid traceparent %q: trace id", s)
+	}
+	if err := decodeLowerHex(t.SpanID[:], s[36:52]); err != nil {
+		return t, fmt.Errorf("invalid traceparent %q: span id", s)
+	}
+	var flags [1]byte
+	if err := decodeLowerHex(flags[:], s[53:55]); err != nil {
+		return t, fmt.Errorf("invalid traceparent %q: flags", s)
+	}
+	t.Flags = flags[0]
+
+	if !t.IsValid() {
+		return TraceContext{}, fmt.Errorf("invalid traceparent %q: zero id", s)
+	}
+
+	return t, nil
+}
+
+// decodeLowerHex decodes s into dst, which is len(s)/2 bytes long, rejecting the uppercase digits.
+func decodeLowerHex(dst []byte, s string) error {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 'A' && c <= 'F' {
+			return errors.New("uppercase hex")
+		}
+	}
+
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+type ctxTraceKey struct{}
+
+// ContextWithTrace returns a copy of ctx carrying t, for Event.Ctx.
+func ContextWithTrace(ctx context.Context, t TraceContext) context.Context {
+	return context.WithValue(ctx, ctxTraceKey{}, t)
+}
+
+// ContextWithTraceparent returns a copy of ctx carrying the trace context of a traceparent header,
+// or ctx if the header is not valid.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	t, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+
+	return ContextWithTrace(ctx, t)
+}
+
+// TraceExtractor returns the trace context of the span carried by ctx, if any.
+type TraceExtractor func(ctx context.Context) (TraceContext, bool)
+
+var (
+	traceExtractorsMu sync.Mutex
+	// traceExtractors holds a []TraceExtractor, read on each Event.Ctx without locking
+	traceExtractors atomic.Value
+)
+
+// RegisterTraceExtractor makes TraceFromContext, and so Event.Ctx, find the trace context carried by a context
+// with ex, before the ones carried by ContextWithTrace. It's the way to correlate the Events with the spans of
+// a tracing library, like OpenTelemetry:
+//
+//	easylog.RegisterTraceExtractor(func(ctx context.Context) (easylog.TraceContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return easylog.TraceContext{
+//			TraceID: sc.TraceID(),
+//			SpanID:  sc.SpanID(),
+//			Flags:   byte(sc.TraceFlags()),
+//		}, sc.IsValid()
+//	})
+//
+// The extractors are tried in the order they are registered.
+func RegisterTraceExtractor(ex TraceExtractor) {
+	traceExtractorsMu.Lock()
+	defer traceExtractorsMu.Unlock()
+
+	old, _ := traceExtractors.Load().([]TraceExtractor)
+	extractors := make([]TraceExtractor, 0, len(old)+1)
+	extractors = append(extractors, old...)
+	extractors = append(extractors, ex)
+
+	traceExtractors.Store(extractors)
+}
+
+// TraceFromContext returns the valid trace context carried by ctx, found by the registered TraceExtractors,
+// or carried by ContextWithTrace.
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	if ctx == nil {
+		return TraceContext{}, false
+	}
+
+	extractors, _ := traceExtractors.Load().([]TraceExtractor)
+	for _, ex := range extractors {
+		if t, ok := ex(ctx); ok && t.IsValid() {
+			return t, true
+		}
+	}
+
+	if t, ok := ctx.Value(ctxTraceKey{}).(TraceContext); ok && t.IsValid() {
+		return t, true
+	}
+
+	return TraceContext{}, false
+}
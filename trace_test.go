@@ -0,0 +1,94 @@
+package easylog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tc, err := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.True(t, tc.IsValid())
+	assert.True(t, tc.Sampled())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceIDString())
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanIDString())
+	assert.Equal(t, "01", tc.FlagsString())
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tc.String())
+
+	// the versions after 00 may append fields
+	tc, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra")
+	assert.Nil(t, err)
+	assert.False(t, tc.Sampled())
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01x",
+	} {
+		_, err := ParseTraceparent(s)
+		assert.NotNil(t, err, s)
+	}
+}
+
+type testTraceKey struct{}
+
+func TestTraceFromContext(t *testing.T) {
+	_, ok := TraceFromContext(nil)
+	assert.False(t, ok)
+	_, ok = TraceFromContext(context.Background())
+	assert.False(t, ok)
+
+	ctx := ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	tc, ok := TraceFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanIDString())
+
+	assert.Equal(t, ctx, ContextWithTraceparent(ctx, "invalid"))
+
+	// the extractors go first
+	RegisterTraceExtractor(func(ctx context.Context) (TraceContext, bool) {
+		tc, ok := ctx.Value(testTraceKey{}).(TraceContext)
+		return tc, ok
+	})
+	span := TraceContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}}
+	tc, ok = TraceFromContext(context.WithValue(ctx, testTraceKey{}, span))
+	assert.True(t, ok)
+	assert.Equal(t, span, tc)
+
+	// but not when they find nothing valid
+	tc, ok = TraceFromContext(context.WithValue(ctx, testTraceKey{}, TraceContext{}))
+	assert.True(t, ok)
+	assert.Equal(t, "00f067aa0ba902b7", tc.SpanIDString())
+}
+
+func TestEventTrace(t *testing.T) {
+	span := TraceContext{TraceID: [16]byte{1}, SpanID: [8]byte{2}, Flags: 1}
+	ctx := ContextWithTrace(context.Background(), span)
+
+	e := NewEvent(newLogger(), INFO, time.Now(), "").Ctx(ctx)
+	assert.Equal(t, span, e.GetTrace())
+
+	c := e.Clone()
+	assert.Equal(t, span, c.GetTrace())
+	c.Put()
+	e.Put()
+
+	// reset when pooled
+	e = NewEvent(newLogger(), INFO, time.Now(), "")
+	assert.False(t, e.GetTrace().IsValid())
+	assert.Equal(t, span, e.Trace(span).GetTrace())
+	e.Put()
+
+	var n *Event
+	assert.Nil(t, n.Trace(span))
+}
@@ -0,0 +1,163 @@
+package easylog
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const defaultWatchInterval = time.Second
+
+// ConfigWatcher reloads a config file when its content changes or when the process receives a signal,
+// SIGHUP by default.
+//
+// Each reload goes through Configure, so the unchanged Handlers keep running untouched. A config which fails
+// to load is reported to the root ErrorHandler, and the current configuration keeps running.
+type ConfigWatcher struct {
+	m        *manager
+	path     string
+	interval time.Duration
+	signals  []os.Signal
+
+	mu sync.Mutex
+	// sum is the hash of the content last loaded, the file changes whatever its modification time and size
+	sum [sha256.Size]byte
+
+	sig  chan os.Signal
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// WatchOption configures a ConfigWatcher.
+type WatchOption func(*ConfigWatcher)
+
+// WithWatchInterval checks the config file for changes every interval. 0 disables the checks.
+func WithWatchInterval(interval time.Duration) WatchOption {
+	return func(w *ConfigWatcher) {
+		w.interval = interval
+	}
+}
+
+// WithWatchSignals reloads the config file on the given signals instead of SIGHUP. No signal disables it.
+func WithWatchSignals(signals ...os.Signal) WatchOption {
+	return func(w *ConfigWatcher) {
+		w.signals = signals
+	}
+}
+
+// WatchConfigFile loads the config file at path, see LoadConfigFile, then watches it in a background goroutine
+// until Close. It fails and watches nothing if the first load fails.
+func WatchConfigFile(path string, opts ...WatchOption) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{
+		m:        m,
+		path:     path,
+		interval: defaultWatchInterval,
+		signals:  []os.Signal{syscall.SIGHUP},
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	if w.interval < 0 {
+		return nil, fmt.Errorf("watch config: negative interval")
+	}
+
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+
+	if len(w.signals) > 0 {
+		w.sig = make(chan os.Signal, 1)
+		signal.Notify(w.sig, w.signals...)
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+// Reload loads the config file now, whether it changed or not.
+func (w *ConfigWatcher) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return err
+	}
+	// record the content first, so a broken file is reported once and not on every check
+	w.sum = sha256.Sum256(data)
+
+	cfg, err := ParseConfig(data, strings.TrimPrefix(filepath.Ext(w.path), "."))
+	if err != nil {
+		return err
+	}
+
+	return w.m.configure(cfg)
+}
+
+// Close stops watching. The current configuration keeps running.
+func (w *ConfigWatcher) Close() error {
+	w.once.Do(func() {
+		if w.sig != nil {
+			signal.Stop(w.sig)
+		}
+		close(w.stop)
+	})
+	<-w.done
+
+	return nil
+}
+
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+
+	var tick <-chan time.Time
+	if w.interval > 0 {
+		t := time.NewTicker(w.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.sig:
+			w.reload()
+		case <-tick:
+			if w.changed() {
+				w.reload()
+			}
+		}
+	}
+}
+
+func (w *ConfigWatcher) changed() bool {
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		// a file being replaced may be missing for a moment, wait for the new one
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return sha256.Sum256(data) != w.sum
+}
+
+func (w *ConfigWatcher) reload() {
+	if err := w.Reload(); err != nil {
+		_ = w.m.root.GetErrorHandler().Handle(fmt.Errorf("reload config %s: %w", w.path, err))
+	}
+}
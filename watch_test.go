@@ -0,0 +1,205 @@
+package easylog
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type chanErrorHandler chan error
+
+func (c chanErrorHandler) Handle(err error) error {
+	c <- err
+	return nil
+}
+
+func (c chanErrorHandler) Flush() error {
+	return nil
+}
+
+func (c chanErrorHandler) Close() error {
+	return nil
+}
+
+type watchHandler struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (h *watchHandler) Handle(*Event) (bool, error) {
+	return true, nil
+}
+
+func (h *watchHandler) Flush() error {
+	return nil
+}
+
+func (h *watchHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	return nil
+}
+
+func (h *watchHandler) isClosed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.closed
+}
+
+func writeConfig(t *testing.T, path, data string, mtime time.Time) {
+	assert.Nil(t, os.WriteFile(path, []byte(data), 0666))
+	assert.Nil(t, os.Chtimes(path, mtime, mtime))
+}
+
+func TestWatchConfigFile(t *testing.T) {
+	defer clear()
+	defer func() {
+		assert.Nil(t, Configure(&Config{}))
+	}()
+
+	errs := make(chanErrorHandler, 10)
+	SetErrorHandler(errs)
+
+	var mu sync.Mutex
+	built := make(map[string]*watchHandler)
+	RegisterHandler("watch_test", func(cfg HandlerConfig) (Handler, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		h := &watchHandler{}
+		built[cfg.Formatter] = h
+		return h, nil
+	})
+	get := func(name string) *watchHandler {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return built[name]
+	}
+
+	path := filepath.Join(t.TempDir(), "log.yaml")
+	mtime := time.Now().Add(-time.Hour)
+	writeConfig(t, path, `
+handlers:
+  kept: {type: watch_test, formatter: kept}
+  removed: {type: watch_test, formatter: removed}
+loggers:
+  watch_test: {level: WARN, handlers: [kept, removed]}
+`, mtime)
+
+	w, err := WatchConfigFile(path, WithWatchInterval(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer w.Close()
+
+	l := GetLogger("watch_test")
+	assert.Equal(t, WARN, l.GetLevel())
+	kept := get("kept")
+
+	// a change of the file is applied, the unchanged Handler is carried over
+	mtime = mtime.Add(time.Second)
+	writeConfig(t, path, `
+handlers:
+  kept: {type: watch_test, formatter: kept}
+  added: {type: watch_test, formatter: added}
+loggers:
+  watch_test: {level: ERROR, handlers: [kept, added]}
+`, mtime)
+
+	assert.Eventually(t, func() bool {
+		return l.GetLevel() == ERROR
+	}, time.Second, 5*time.Millisecond)
+	assert.True(t, get("removed").isClosed())
+	assert.False(t, kept.isClosed())
+	assert.True(t, kept == get("kept"))
+	assert.Equal(t, []Handler{kept, get("added")}, l.Handlers())
+
+	// a rewrite of the same size, within the same modification time, is applied too
+	writeConfig(t, path, `
+handlers:
+  kept: {type: watch_test, formatter: kept}
+  added: {type: watch_test, formatter: added}
+loggers:
+  watch_test: {level: DEBUG, handlers: [kept, added]}
+`, mtime)
+
+	assert.Eventually(t, func() bool {
+		return l.GetLevel() == DEBUG
+	}, time.Second, 5*time.Millisecond)
+
+	// an invalid file is reported and the current configuration keeps running
+	mtime = mtime.Add(time.Second)
+	writeConfig(t, path, `
+loggers:
+  watch_test: {level: ERROR, handlers: [missing]}
+`, mtime)
+
+	select {
+	case err := <-errs:
+		assert.Contains(t, err.Error(), path)
+		assert.Contains(t, err.Error(), `undeclared handler "missing"`)
+	case <-time.After(time.Second):
+		t.Fatal("invalid config not reported")
+	}
+	assert.Equal(t, DEBUG, l.GetLevel())
+	assert.False(t, kept.isClosed())
+
+	// reported once, not on every check
+	select {
+	case err := <-errs:
+		t.Fatalf("reported again: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+}
+
+func TestWatchConfigFileSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGHUP on windows")
+	}
+
+	defer clear()
+	defer func() {
+		assert.Nil(t, Configure(&Config{}))
+	}()
+
+	path := filepath.Join(t.TempDir(), "log.json")
+	mtime := time.Now().Add(-time.Hour)
+	writeConfig(t, path, `{"loggers": {"watch_signal_test": {"level": "WARN"}}}`, mtime)
+
+	w, err := WatchConfigFile(path, WithWatchInterval(0))
+	assert.Nil(t, err)
+	defer w.Close()
+
+	l := GetLogger("watch_signal_test")
+	assert.Equal(t, WARN, l.GetLevel())
+
+	// same size and modification time, only the signal reloads it
+	writeConfig(t, path, `{"loggers": {"watch_signal_test": {"level": "INFO"}}}`, mtime)
+
+	p, err := os.FindProcess(os.Getpid())
+	assert.Nil(t, err)
+	assert.Nil(t, p.Signal(syscall.SIGHUP))
+
+	assert.Eventually(t, func() bool {
+		return l.GetLevel() == INFO
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestWatchConfigFileErrors(t *testing.T) {
+	_, err := WatchConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = WatchConfigFile("log.yaml", WithWatchInterval(-1))
+	assert.NotNil(t, err)
+}
@@ -1,8 +1,10 @@
 package writer
 
 import (
-	"bufio"
 	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
 )
 
 const (
@@ -10,12 +12,34 @@ const (
 	maxBufSize     int = 1000 * 1024
 )
 
+// BufWriter buffers the writes to a Writer.
+//
+// Unlike a bufio.Writer, an error is not sticky: the bytes which could not be written stay buffered,
+// and the next write or Flush tries them again, so the transient errors could be retried.
+//
+// A BufWriter is safe for concurrent use.
 type BufWriter struct {
-	w  Writer
-	bw *bufio.Writer
+	mu  sync.Mutex
+	w   Writer
+	buf []byte
+
+	interval  time.Duration
+	autoFlush *AutoFlush
 }
 
-func NewBufWriter(size int, w Writer) (*BufWriter, error) {
+// BufOption configures a BufWriter.
+type BufOption func(*BufWriter)
+
+// WithBufFlushInterval flushes the BufWriter every interval, until Close, see AutoFlush.
+// The errors are not reported, the bytes not written stay buffered for the next Flush.
+// The handlers writing to the BufWriter don't flush it periodically themselves.
+func WithBufFlushInterval(interval time.Duration) BufOption {
+	return func(b *BufWriter) {
+		b.interval = interval
+	}
+}
+
+func NewBufWriter(size int, w Writer, opts ...BufOption) (*BufWriter, error) {
 	var bs int
 	if size >= maxBufSize {
 		bs = maxBufSize
@@ -25,19 +49,65 @@ func NewBufWriter(size int, w Writer) (*BufWriter, error) {
 		bs = size
 	}
 
-	bw := bufio.NewWriterSize(w, bs)
-	return &BufWriter{
-		w:  w,
-		bw: bw,
-	}, nil
+	b := &BufWriter{
+		w:   w,
+		buf: make([]byte, 0, bs),
+	}
+
+	for _, o := range opts {
+		o(b)
+	}
+
+	b.autoFlush = NewAutoFlush(b.interval, func() {
+		_ = b.Flush()
+	})
+
+	return b, nil
 }
 
+// Write buffers p, or writes it through when it's larger than the buffer.
+// On error, it returns how many bytes of p were buffered or written.
 func (b *BufWriter) Write(p []byte) (n int, err error) {
-	return b.bw.Write(p)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(p) > b.available() && len(b.buf) > 0 {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(p) > b.available() {
+		return b.w.Write(p)
+	}
+
+	b.buf = append(b.buf, p...)
+
+	return len(p), nil
+}
+
+// flush writes the buffered bytes, keeping the ones not written on error.
+func (b *BufWriter) flush() error {
+	if len(b.buf) == 0 {
+		return nil
+	}
+
+	n, err := b.w.Write(b.buf)
+	if n > 0 {
+		b.buf = b.buf[:copy(b.buf, b.buf[n:])]
+	}
+	if err == nil && len(b.buf) > 0 {
+		err = io.ErrShortWrite
+	}
+
+	return err
 }
 
 func (b *BufWriter) Flush() error {
-	if err := b.bw.Flush(); err != nil {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.flush(); err != nil {
 		return err
 	}
 	if err := b.w.Flush(); err != nil {
@@ -47,42 +117,120 @@ func (b *BufWriter) Flush() error {
 	return nil
 }
 
+// FlushInterval returns the interval of the periodic flushes, 0 if there are none.
+func (b *BufWriter) FlushInterval() time.Duration {
+	return b.interval
+}
+
+// Close stops the periodic flushes, writes the buffered bytes, then closes the Writer, even if the write failed.
 func (b *BufWriter) Close() error {
-	if err := b.w.Close(); err != nil {
-		return err
+	b.autoFlush.Stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	err := b.flush()
+	if cerr := b.w.Close(); err == nil {
+		err = cerr
 	}
 
-	return nil
+	return err
 }
 
 func (b *BufWriter) WriteByte(c byte) error {
-	return b.bw.WriteByte(c)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.available() <= 0 {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+
+	b.buf = append(b.buf, c)
+
+	return nil
 }
 
 func (b *BufWriter) WriteString(s string) (int, error) {
-	return b.bw.WriteString(s)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(s) > b.available() && len(b.buf) > 0 {
+		if err := b.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	if len(s) > b.available() {
+		return io.WriteString(b.w, s)
+	}
+
+	b.buf = append(b.buf, s...)
+
+	return len(s), nil
 }
 
 func (b *BufWriter) ReadFrom(r io.Reader) (n int64, err error) {
-	return b.bw.ReadFrom(r)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		if b.available() == 0 {
+			if err := b.flush(); err != nil {
+				return n, err
+			}
+		}
+
+		m, err := r.Read(b.buf[len(b.buf):cap(b.buf)])
+		b.buf = b.buf[:len(b.buf)+m]
+		n += int64(m)
+
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
 }
 
 func (b *BufWriter) WriteRune(r rune) (size int, err error) {
-	return b.bw.WriteRune(r)
+	var p [utf8.UTFMax]byte
+	n := utf8.EncodeRune(p[:], r)
+
+	return b.Write(p[:n])
 }
 
 func (b *BufWriter) Available() int {
-	return b.bw.Available()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.available()
+}
+
+func (b *BufWriter) available() int {
+	return cap(b.buf) - len(b.buf)
 }
 
 func (b *BufWriter) Buffered() int {
-	return b.bw.Buffered()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.buf)
 }
 
 func (b *BufWriter) Size() int {
-	return b.bw.Size()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return cap(b.buf)
 }
 
+// Reset discards the buffered bytes.
 func (b *BufWriter) Reset() {
-	b.bw.Reset(b.w)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = b.buf[:0]
 }
@@ -0,0 +1,165 @@
+package writer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failWriter fails the writes while err is set, and writes at most limit bytes per call if positive.
+type failWriter struct {
+	buf     bytes.Buffer
+	err     error
+	limit   int
+	flushed int
+}
+
+func (f *failWriter) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	if f.limit > 0 && len(p) > f.limit {
+		n, _ := f.buf.Write(p[:f.limit])
+		return n, nil
+	}
+	return f.buf.Write(p)
+}
+
+func (f *failWriter) Flush() error {
+	f.flushed++
+	return nil
+}
+
+func (f *failWriter) Close() error {
+	return nil
+}
+
+func TestBufWriter(t *testing.T) {
+	w := &failWriter{}
+	b, err := NewBufWriter(8, w)
+	assert.Nil(t, err)
+	assert.Equal(t, 8, b.Size())
+
+	_, _ = b.WriteString("abc")
+	_ = b.WriteByte('d')
+	_, _ = b.WriteRune('é')
+	assert.Equal(t, 6, b.Buffered())
+	assert.Equal(t, 2, b.Available())
+	assert.Equal(t, "", w.buf.String())
+
+	// larger than the buffer, written through after the buffered bytes
+	n, err := b.Write([]byte("0123456789"))
+	assert.Nil(t, err)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, "abcdé0123456789", w.buf.String())
+
+	n64, err := b.ReadFrom(strings.NewReader("0123456789"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(10), n64)
+	assert.Nil(t, b.Flush())
+	assert.Equal(t, "abcdé01234567890123456789", w.buf.String())
+	assert.Equal(t, 1, w.flushed)
+
+	_, _ = b.WriteString("x")
+	b.Reset()
+	assert.Nil(t, b.Flush())
+	assert.Equal(t, "abcdé01234567890123456789", w.buf.String())
+}
+
+func TestBufWriterErrors(t *testing.T) {
+	w := &failWriter{err: errors.New("unavailable")}
+	b, err := NewBufWriter(8, w)
+	assert.Nil(t, err)
+
+	_, err = b.WriteString("abcdef")
+	assert.Nil(t, err)
+
+	// the buffered bytes are kept on error
+	n, err := b.Write([]byte("ghi"))
+	assert.Equal(t, w.err, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, w.err, b.Flush())
+	assert.Equal(t, 6, b.Buffered())
+
+	// and retried once the writer recovers
+	w.err = nil
+	n, err = b.Write([]byte("ghi"))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+	assert.Nil(t, b.Flush())
+	assert.Equal(t, "abcdefghi", w.buf.String())
+
+	// short writes keep the remaining bytes
+	w.limit = 2
+	_, _ = b.WriteString("jklm")
+	assert.Equal(t, io.ErrShortWrite, b.Flush())
+	assert.Equal(t, 2, b.Buffered())
+	w.limit = 0
+	assert.Nil(t, b.Flush())
+	assert.Equal(t, "abcdefghijklm", w.buf.String())
+}
+
+// lockedWriter is a Writer safe for concurrent use.
+type lockedWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	flushed int
+	closed  bool
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *lockedWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushed++
+	return nil
+}
+
+func (l *lockedWriter) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+func (l *lockedWriter) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.String()
+}
+
+func TestBufWriterFlushInterval(t *testing.T) {
+	w := &lockedWriter{}
+	b, err := NewBufWriter(1024, w, WithBufFlushInterval(time.Millisecond))
+	assert.Nil(t, err)
+
+	_, _ = b.WriteString("abc")
+	assert.Eventually(t, func() bool {
+		return w.String() == "abc"
+	}, time.Second, time.Millisecond)
+
+	// the buffered bytes are written on Close, and the flushes stop
+	_, _ = b.WriteString("def")
+	assert.Nil(t, b.Close())
+	assert.Equal(t, "abcdef", w.String())
+
+	w.mu.Lock()
+	flushed := w.flushed
+	w.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	w.mu.Lock()
+	assert.Equal(t, flushed, w.flushed)
+	assert.True(t, w.closed)
+	w.mu.Unlock()
+}
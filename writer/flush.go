@@ -0,0 +1,174 @@
+package writer
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// AutoFlush calls a function periodically until Stop. It's used to flush the buffered writers and handlers.
+//
+// All the AutoFlushes share a single scheduling goroutine, which runs each flush in a goroutine of its own, so a
+// slow flush delays nothing else. A flush still running when it's due again is skipped.
+type AutoFlush struct {
+	interval time.Duration
+	flush    func()
+
+	// the members below are guarded by the mutex of the scheduler
+	next    time.Time
+	index   int
+	busy    bool
+	stopped bool
+	// running counts the running flush, Stop waits for it
+	running sync.WaitGroup
+}
+
+// NewAutoFlush calls flush every interval until Stop. It returns nil if interval is not positive.
+func NewAutoFlush(interval time.Duration, flush func()) *AutoFlush {
+	if interval <= 0 {
+		return nil
+	}
+
+	a := &AutoFlush{
+		interval: interval,
+		flush:    flush,
+	}
+	_scheduler.add(a)
+
+	return a
+}
+
+// Stop stops the periodic calls and waits for a running flush to return. It could be called several times,
+// or on nil.
+func (a *AutoFlush) Stop() {
+	if a == nil {
+		return
+	}
+
+	_scheduler.remove(a)
+	a.running.Wait()
+}
+
+// flushScheduler calls the due AutoFlushes from a goroutine started with the first one, and exiting with
+// the last one.
+type flushScheduler struct {
+	mu      sync.Mutex
+	queue   flushQueue
+	running bool
+	// wake interrupts the wait of the goroutine when the queue changes
+	wake chan struct{}
+}
+
+var _scheduler = &flushScheduler{wake: make(chan struct{}, 1)}
+
+func (s *flushScheduler) add(a *AutoFlush) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.next = time.Now().Add(a.interval)
+	heap.Push(&s.queue, a)
+
+	if !s.running {
+		s.running = true
+		go s.run()
+	} else {
+		s.notify()
+	}
+}
+
+func (s *flushScheduler) remove(a *AutoFlush) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if a.stopped {
+		return
+	}
+	a.stopped = true
+
+	heap.Remove(&s.queue, a.index)
+	s.notify()
+}
+
+func (s *flushScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *flushScheduler) run() {
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+
+		now := time.Now()
+		for !s.queue[0].next.After(now) {
+			a := s.queue[0]
+			if a.next = a.next.Add(a.interval); !a.next.After(now) {
+				// late, the missed flushes are not caught up
+				a.next = now.Add(a.interval)
+			}
+			heap.Fix(&s.queue, 0)
+
+			if !a.busy {
+				a.busy = true
+				a.running.Add(1)
+				go s.call(a)
+			}
+		}
+		timer := time.NewTimer(s.queue[0].next.Sub(now))
+		s.mu.Unlock()
+
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+func (s *flushScheduler) call(a *AutoFlush) {
+	defer a.running.Done()
+
+	a.flush()
+
+	s.mu.Lock()
+	a.busy = false
+	s.mu.Unlock()
+}
+
+// flushQueue is a heap of AutoFlushes, by their next flush.
+type flushQueue []*AutoFlush
+
+func (q flushQueue) Len() int {
+	return len(q)
+}
+
+func (q flushQueue) Less(i, j int) bool {
+	return q[i].next.Before(q[j].next)
+}
+
+func (q flushQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *flushQueue) Push(x interface{}) {
+	a := x.(*AutoFlush)
+	a.index = len(*q)
+	*q = append(*q, a)
+}
+
+func (q *flushQueue) Pop() interface{} {
+	old := *q
+	a := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+
+	return a
+}
@@ -0,0 +1,63 @@
+package writer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoFlush(t *testing.T) {
+	assert.Nil(t, NewAutoFlush(0, func() {}))
+	var none *AutoFlush
+	none.Stop()
+
+	var fast, slow, blocked int64
+	release := make(chan struct{})
+	a := NewAutoFlush(time.Millisecond, func() { atomic.AddInt64(&fast, 1) })
+	b := NewAutoFlush(5*time.Millisecond, func() { atomic.AddInt64(&slow, 1) })
+	// a flush still running is not called again, nor does it delay the others
+	c := NewAutoFlush(time.Millisecond, func() {
+		atomic.AddInt64(&blocked, 1)
+		<-release
+	})
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&slow) >= 2 && atomic.LoadInt64(&fast) > atomic.LoadInt64(&slow)
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&blocked))
+
+	// Stop waits for the running flush
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned while flushing")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+
+	a.Stop()
+	a.Stop()
+	n := atomic.LoadInt64(&fast)
+	time.Sleep(5 * time.Millisecond)
+	assert.Equal(t, n, atomic.LoadInt64(&fast))
+	b.Stop()
+
+	// the scheduler exits with the last AutoFlush, and starts again with the next one
+	assert.Eventually(t, func() bool {
+		_scheduler.mu.Lock()
+		defer _scheduler.mu.Unlock()
+		return !_scheduler.running
+	}, time.Second, time.Millisecond)
+	d := NewAutoFlush(time.Millisecond, func() { atomic.AddInt64(&fast, 1) })
+	defer d.Stop()
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&fast) > n
+	}, time.Second, time.Millisecond)
+}
@@ -0,0 +1,370 @@
+package writer
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultNetDialTimeout  = 5 * time.Second
+	defaultNetWriteTimeout = 10 * time.Second
+	defaultNetMinBackoff   = 100 * time.Millisecond
+	defaultNetMaxBackoff   = 30 * time.Second
+
+	// maxNetSpoolRecord bounds the size of a spooled write, the spool being possibly unbounded
+	maxNetSpoolRecord = 64 << 20
+)
+
+// ErrSpoolFull is returned by NetWriter.Write when the endpoint is down and the spool has no room left.
+var ErrSpoolFull = errors.New("net writer: spool full")
+
+// NetWriter writes to a TCP, UDP or unix socket, each Write being a message, a datagram on UDP.
+//
+// When the endpoint is down, it reconnects on the next writes and flushes, with an exponential backoff.
+// Meanwhile, with WithNetSpool, the writes are appended to a bounded file, which is replayed in order once
+// reconnected, before any new write. The spool survives restarts, its records are sent at least once.
+// Without a spool, the writes fail while the endpoint is down.
+type NetWriter struct {
+	mu sync.Mutex
+
+	network      string
+	addr         string
+	tlsConfig    *tls.Config
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+	now          func() time.Time
+
+	conn     net.Conn
+	backoff  time.Duration
+	nextDial time.Time
+	closed   bool
+
+	spoolPath string
+	spoolMax  int64
+	spool     *os.File
+	// the size of the spool, and the offset of the first record not replayed yet
+	spoolSize int64
+	spoolRead int64
+}
+
+// NetOption configures a NetWriter.
+type NetOption func(*NetWriter)
+
+// WithNetTLS connects with TLS, over TCP.
+func WithNetTLS(config *tls.Config) NetOption {
+	return func(w *NetWriter) {
+		w.tlsConfig = config
+	}
+}
+
+// WithNetDialTimeout bounds the time to connect, 5 seconds by default.
+func WithNetDialTimeout(timeout time.Duration) NetOption {
+	return func(w *NetWriter) {
+		w.dialTimeout = timeout
+	}
+}
+
+// WithNetWriteTimeout bounds the time of a write, 10 seconds by default. 0 disables the timeout.
+func WithNetWriteTimeout(timeout time.Duration) NetOption {
+	return func(w *NetWriter) {
+		w.writeTimeout = timeout
+	}
+}
+
+// WithNetBackoff waits min after the first failed connection before connecting again, then twice as long
+// after each next failure, up to max. By default, from 100 milliseconds up to 30 seconds.
+func WithNetBackoff(min, max time.Duration) NetOption {
+	return func(w *NetWriter) {
+		w.minBackoff = min
+		w.maxBackoff = max
+	}
+}
+
+// WithNetSpool spools the writes to the file at path while the endpoint is down, up to maxBytes, 0 meaning
+// unbounded. A write larger than maxBytes, or than 64 MiB, is never spooled.
+func WithNetSpool(path string, maxBytes int64) NetOption {
+	return func(w *NetWriter) {
+		w.spoolPath = path
+		w.spoolMax = maxBytes
+	}
+}
+
+// withNetClock replaces time.Now, for testing.
+func withNetClock(now func() time.Time) NetOption {
+	return func(w *NetWriter) {
+		w.now = now
+	}
+}
+
+// NewNetWriter writes to addr over network, as net.Dial takes them. It connects at once, but the endpoint
+// being down is not an error, the writes reconnect.
+func NewNetWriter(network, addr string, opts ...NetOption) (*NetWriter, error) {
+	w := &NetWriter{
+		network:      network,
+		addr:         addr,
+		dialTimeout:  defaultNetDialTimeout,
+		writeTimeout: defaultNetWriteTimeout,
+		minBackoff:   defaultNetMinBackoff,
+		maxBackoff:   defaultNetMaxBackoff,
+		now:          time.Now,
+	}
+
+	for _, o := range opts {
+		o(w)
+	}
+
+	if w.spoolPath != "" {
+		if err := w.openSpool(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the spool of a previous run is replayed first
+	_ = w.connect()
+	if w.conn != nil {
+		_ = w.replay()
+	}
+
+	return w, nil
+}
+
+// Write sends p, after the spooled writes. While the endpoint is down, p is spooled if possible.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, errors.New("net writer: closed")
+	}
+
+	err := w.connect()
+	if err == nil {
+		if err = w.replay(); err == nil {
+			if err = w.send(p); err == nil {
+				return len(p), nil
+			}
+		}
+	}
+
+	if w.spool == nil {
+		return 0, err
+	}
+	if err := w.appendSpool(p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush reconnects if needed to replay the spooled writes. The writes are not buffered otherwise.
+func (w *NetWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed || w.spoolSize == w.spoolRead {
+		return nil
+	}
+
+	if err := w.connect(); err != nil {
+		// the writes are safe in the spool
+		return nil
+	}
+
+	return w.replay()
+}
+
+// Close tries to replay the spooled writes once more, then closes the connection and the spool.
+// The writes left in the spool are replayed by the next NetWriter using it.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	var errs []error
+	if w.conn != nil {
+		_ = w.replay()
+	}
+	if w.conn != nil {
+		errs = append(errs, w.conn.Close())
+		w.conn = nil
+	}
+	if w.spool != nil {
+		if w.spoolRead == w.spoolSize {
+			errs = append(errs, w.spool.Truncate(0))
+		}
+		errs = append(errs, w.spool.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// Spooled returns the number of bytes of the writes waiting in the spool.
+func (w *NetWriter) Spooled() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.spoolSize - w.spoolRead
+}
+
+// connect dials the endpoint if not connected, unless it's waiting for the backoff.
+func (w *NetWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+
+	if now := w.now(); now.Before(w.nextDial) {
+		return fmt.Errorf("net writer: %s %s down, reconnecting in %v", w.network, w.addr, w.nextDial.Sub(now))
+	}
+
+	var conn net.Conn
+	var err error
+	if w.tlsConfig != nil {
+		dialer := &net.Dialer{Timeout: w.dialTimeout}
+		conn, err = tls.DialWithDialer(dialer, w.network, w.addr, w.tlsConfig)
+	} else {
+		conn, err = net.DialTimeout(w.network, w.addr, w.dialTimeout)
+	}
+	if err != nil {
+		w.down()
+		return fmt.Errorf("net writer: %w", err)
+	}
+
+	w.conn = conn
+	w.backoff = 0
+
+	return nil
+}
+
+// down closes the connection, and schedules the next one.
+func (w *NetWriter) down() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+
+	w.backoff = min(max(w.backoff*2, w.minBackoff), w.maxBackoff)
+	w.nextDial = w.now().Add(w.backoff)
+}
+
+// send writes p to the connection, which is closed on error.
+func (w *NetWriter) send(p []byte) error {
+	if w.writeTimeout > 0 {
+		_ = w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+	}
+
+	if _, err := w.conn.Write(p); err != nil {
+		w.down()
+		return fmt.Errorf("net writer: %w", err)
+	}
+
+	return nil
+}
+
+func (w *NetWriter) openSpool() error {
+	if err := os.MkdirAll(filepath.Dir(w.spoolPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(w.spoolPath, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	w.spool = f
+	w.spoolSize = info.Size()
+
+	return nil
+}
+
+// appendSpool appends p to the spool as a record, its length on 4 bytes followed by its bytes.
+func (w *NetWriter) appendSpool(p []byte) error {
+	if len(p) > maxNetSpoolRecord {
+		return fmt.Errorf("net writer: spool: write of %d bytes too large", len(p))
+	}
+
+	size := int64(4 + len(p))
+	if w.spoolMax > 0 && w.spoolSize-w.spoolRead+size > w.spoolMax {
+		return ErrSpoolFull
+	}
+
+	record := make([]byte, 4, size)
+	binary.BigEndian.PutUint32(record, uint32(len(p)))
+	record = append(record, p...)
+
+	if _, err := w.spool.WriteAt(record, w.spoolSize); err != nil {
+		return fmt.Errorf("net writer: spool: %w", err)
+	}
+	w.spoolSize += size
+
+	return nil
+}
+
+// replay sends the spooled records in order, then empties the spool. It stops at the first failure, the records
+// not sent yet are kept. A record longer than what could be spooled is corrupt, it's discarded with the rest of
+// the spool, as is a record cut by a crash.
+func (w *NetWriter) replay() error {
+	if w.spool == nil || w.spoolRead == w.spoolSize {
+		return nil
+	}
+
+	var corrupt error
+	var header [4]byte
+	for w.spoolRead < w.spoolSize {
+		if _, err := w.spool.ReadAt(header[:], w.spoolRead); err != nil {
+			// a record cut by a crash
+			break
+		}
+
+		size := int64(binary.BigEndian.Uint32(header[:]))
+		if size > maxNetSpoolRecord || w.spoolMax > 0 && 4+size > w.spoolMax {
+			corrupt = fmt.Errorf("net writer: spool: corrupt record of %d bytes, %d bytes discarded", size,
+				w.spoolSize-w.spoolRead)
+			break
+		}
+		if w.spoolRead+4+size > w.spoolSize {
+			// a record cut by a crash
+			break
+		}
+
+		p := make([]byte, size)
+		if _, err := w.spool.ReadAt(p, w.spoolRead+4); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("net writer: spool: %w", err)
+		}
+
+		if err := w.send(p); err != nil {
+			return err
+		}
+		w.spoolRead += int64(4 + len(p))
+	}
+
+	if err := w.spool.Truncate(0); err != nil {
+		return fmt.Errorf("net writer: spool: %w", err)
+	}
+	w.spoolSize = 0
+	w.spoolRead = 0
+
+	return corrupt
+}
@@ -0,0 +1,276 @@
+package writer
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lineServer collects the lines received on a TCP address, it could be stopped and restarted.
+type lineServer struct {
+	t    *testing.T
+	addr string
+
+	mu    sync.Mutex
+	ln    net.Listener
+	conns []net.Conn
+	lines []string
+}
+
+func newLineServer(t *testing.T) *lineServer {
+	s := &lineServer{t: t, addr: "127.0.0.1:0"}
+	s.start()
+	s.addr = s.ln.Addr().String()
+	return s
+}
+
+func (s *lineServer) start() {
+	ln, err := net.Listen("tcp", s.addr)
+	assert.Nil(s.t, err)
+
+	s.mu.Lock()
+	s.ln = ln
+	s.mu.Unlock()
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, c)
+			s.mu.Unlock()
+
+			go func() {
+				sc := bufio.NewScanner(c)
+				for sc.Scan() {
+					s.mu.Lock()
+					s.lines = append(s.lines, sc.Text())
+					s.mu.Unlock()
+				}
+			}()
+		}
+	}()
+}
+
+func (s *lineServer) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_ = s.ln.Close()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *lineServer) Lines() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.lines...)
+}
+
+func TestNetWriter(t *testing.T) {
+	s := newLineServer(t)
+	defer s.stop()
+
+	w, err := NewNetWriter("tcp", s.addr)
+	assert.Nil(t, err)
+
+	for _, line := range []string{"a\n", "b\n"} {
+		n, err := w.Write([]byte(line))
+		assert.Nil(t, err)
+		assert.Equal(t, 2, n)
+	}
+	assert.Nil(t, w.Flush())
+	assert.Nil(t, w.Close())
+
+	assert.Eventually(t, func() bool {
+		return strings.Join(s.Lines(), ",") == "a,b"
+	}, time.Second, time.Millisecond)
+
+	_, err = w.Write([]byte("c\n"))
+	assert.NotNil(t, err)
+}
+
+func TestNetWriterSpool(t *testing.T) {
+	s := newLineServer(t)
+	defer s.stop()
+
+	spool := filepath.Join(t.TempDir(), "spool")
+	w, err := NewNetWriter("tcp", s.addr, WithNetBackoff(time.Millisecond, time.Millisecond), WithNetSpool(spool, 1024))
+	assert.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("0\n"))
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return len(s.Lines()) == 1
+	}, time.Second, time.Millisecond)
+
+	// the writes to a dead connection may be lost until it fails, then they are spooled
+	s.stop()
+	assert.Eventually(t, func() bool {
+		_, err := w.Write([]byte("probe\n"))
+		assert.Nil(t, err)
+		return w.Spooled() > 0
+	}, 5*time.Second, time.Millisecond)
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		_, err := w.Write([]byte(line))
+		assert.Nil(t, err)
+	}
+
+	// replayed in order once restarted
+	s.start()
+	time.Sleep(2 * time.Millisecond)
+	_, err = w.Write([]byte("4\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), w.Spooled())
+
+	assert.Eventually(t, func() bool {
+		lines := s.Lines()
+		return len(lines) > 0 && lines[len(lines)-1] == "4"
+	}, time.Second, time.Millisecond)
+
+	var replayed []string
+	for _, line := range s.Lines()[1:] {
+		if line != "probe" {
+			replayed = append(replayed, line)
+		}
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4"}, replayed)
+}
+
+func TestNetWriterSpoolBound(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	spool := filepath.Join(t.TempDir(), "spool")
+
+	// nothing listens there
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close())
+
+	w, err := NewNetWriter("tcp", addr, WithNetSpool(spool, 20), withNetClock(clock.now))
+	assert.Nil(t, err)
+
+	// 4 bytes of length per record
+	_, err = w.Write([]byte("12345678"))
+	assert.Nil(t, err)
+	_, err = w.Write([]byte("12345678"))
+	assert.Equal(t, ErrSpoolFull, err)
+	assert.Equal(t, int64(12), w.Spooled())
+	assert.Nil(t, w.Close())
+
+	// the spool of a previous run is replayed
+	s := &lineServer{t: t, addr: addr}
+	s.start()
+	defer s.stop()
+
+	w, err = NewNetWriter("tcp", addr, WithNetSpool(spool, 20))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), w.Spooled())
+	_, err = w.Write([]byte("\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Eventually(t, func() bool {
+		return strings.Join(s.Lines(), ",") == "12345678"
+	}, time.Second, time.Millisecond)
+}
+
+func TestNetWriterSpoolCorrupt(t *testing.T) {
+	s := newLineServer(t)
+	defer s.stop()
+
+	for _, header := range []uint32{0xffffffff, 100} {
+		spool := filepath.Join(t.TempDir(), "spool")
+		record := func(p string) []byte {
+			return append(binary.BigEndian.AppendUint32(nil, uint32(len(p))), p...)
+		}
+		data := append(record("ok\n"), binary.BigEndian.AppendUint32(nil, header)...)
+		data = append(data, record("lost\n")...)
+		assert.Nil(t, os.WriteFile(spool, data, 0666))
+
+		// the records after the corrupt one are discarded, whatever they look like
+		w, err := NewNetWriter("tcp", s.addr, WithNetSpool(spool, 20))
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), w.Spooled())
+		assert.Nil(t, w.Close())
+
+		info, err := os.Stat(spool)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), info.Size())
+	}
+
+	assert.Eventually(t, func() bool {
+		return strings.Join(s.Lines(), ",") == "ok,ok"
+	}, time.Second, time.Millisecond)
+}
+
+func TestNetWriterBackoff(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	addr := ln.Addr().String()
+	assert.Nil(t, ln.Close())
+
+	w, err := NewNetWriter("tcp", addr, WithNetBackoff(time.Second, 3*time.Second), withNetClock(clock.now))
+	assert.Nil(t, err)
+	defer w.Close()
+
+	// failed at start, the next dial waits 1s, then 2s, then 3s
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second} {
+		_, err = w.Write([]byte("x"))
+		assert.Contains(t, err.Error(), "reconnecting in "+backoff.String())
+		clock.add(backoff)
+		_, err = w.Write([]byte("x"))
+		assert.Contains(t, err.Error(), "connection refused")
+	}
+}
+
+func TestNetWriterTLS(t *testing.T) {
+	// borrow the certificate of a test server
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", srv.TLS)
+	assert.Nil(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		lines <- line
+	}()
+
+	w, err := NewNetWriter("tcp", ln.Addr().String(), WithNetTLS(&tls.Config{RootCAs: pool}))
+	assert.Nil(t, err)
+	defer w.Close()
+
+	_, err = w.Write([]byte("secret\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, "secret\n", <-lines)
+}
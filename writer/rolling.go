@@ -0,0 +1,525 @@
+package writer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	tmpSuffix        = ".tmp"
+
+	rotateRetryDelay = time.Second
+)
+
+// RollingFileWriter writes to a file and rotates it by size and/or time interval.
+//
+// Rotated files are named <name>-<time><ext> in the directory of the file, where time is the UTC
+// time of rotation. They are optionally gzip compressed, and removed by count and/or age, in a background
+// goroutine.
+//
+// Every step of a rotation is a rename, so a crash in the middle of a rotation never loses data:
+// an interrupted compression is redone and stale temporary files are removed on the next start.
+// A failed rotation is returned, and the writes go on in the file, reopened if needed, until the rotation
+// is retried after rotateRetryDelay. A failed compression or cleanup is returned by the next Write, or by Close.
+type RollingFileWriter struct {
+	mu sync.Mutex
+
+	path       string
+	dir        string
+	prefix     string
+	ext        string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	now        func() time.Time
+
+	f          *os.File
+	closed     bool
+	size       int64
+	nextRotate time.Time
+	// retryRotate delays the rotation after a failed one
+	retryRotate time.Time
+
+	mill     chan struct{}
+	millDone chan struct{}
+	// millErr is the error of the last compression and cleanup, until returned, it's guarded by mu
+	millErr error
+}
+
+// RollingOption configures a RollingFileWriter.
+type RollingOption func(*RollingFileWriter)
+
+// WithMaxSize rotates the file before it grows over size bytes. 0 disables rotation by size.
+func WithMaxSize(size int64) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.maxSize = size
+	}
+}
+
+// WithInterval rotates the file at every multiple of interval since the unix epoch, whatever the local zone,
+// e.g. every hour on the hour, or every day at midnight UTC. 0 disables rotation by time.
+func WithInterval(interval time.Duration) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.interval = interval
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files. 0 keeps all of them.
+func WithMaxBackups(n int) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.maxBackups = n
+	}
+}
+
+// WithMaxAge removes rotated files older than age. 0 keeps all of them.
+func WithMaxAge(age time.Duration) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.maxAge = age
+	}
+}
+
+// WithCompress gzip compresses rotated files.
+func WithCompress(compress bool) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.compress = compress
+	}
+}
+
+// withClock replaces time.Now, for testing.
+func withClock(now func() time.Time) RollingOption {
+	return func(r *RollingFileWriter) {
+		r.now = now
+	}
+}
+
+func NewRollingFileWriter(path string, opts ...RollingOption) (*RollingFileWriter, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+
+	r := &RollingFileWriter{
+		path:     path,
+		dir:      dir,
+		prefix:   strings.TrimSuffix(base, ext) + "-",
+		ext:      ext,
+		now:      time.Now,
+		mill:     make(chan struct{}, 1),
+		millDone: make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	if r.maxSize < 0 || r.interval < 0 || r.maxBackups < 0 || r.maxAge < 0 {
+		return nil, errors.New("rolling file writer: negative option")
+	}
+
+	go r.runMill()
+
+	if err := r.open(); err != nil {
+		r.stopMill()
+		return nil, err
+	}
+
+	// finish the work possibly interrupted by a crash
+	r.triggerMill()
+
+	return r, nil
+}
+
+// Write writes p to the file, rotating it first if needed.
+// When the rotation fails, p is still written to the file, and the error of the rotation is returned with n.
+// Otherwise, the error of a compression or cleanup which failed since the previous Write is returned with n.
+func (r *RollingFileWriter) Write(p []byte) (n int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ensureOpen(); err != nil {
+		return 0, err
+	}
+
+	var rotateErr error
+	if r.shouldRotate(int64(len(p))) {
+		if rotateErr = r.rotate(); r.f == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err = r.f.Write(p)
+	r.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	if err == nil && r.millErr != nil {
+		err, r.millErr = r.millErr, nil
+	}
+
+	return n, err
+}
+
+func (r *RollingFileWriter) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ensureOpen(); err != nil {
+		return err
+	}
+
+	return r.f.Sync()
+}
+
+// Close closes the file and waits for the pending compression and cleanup.
+func (r *RollingFileWriter) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return os.ErrClosed
+	}
+	r.closed = true
+	var err error
+	if r.f != nil {
+		err = r.f.Close()
+		r.f = nil
+	}
+	r.mu.Unlock()
+
+	r.stopMill()
+
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.millErr
+}
+
+// Rotate rotates the file immediately.
+func (r *RollingFileWriter) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.ensureOpen(); err != nil {
+		return err
+	}
+
+	return r.rotate()
+}
+
+// FileName returns the path of the file being written.
+func (r *RollingFileWriter) FileName() string {
+	return r.path
+}
+
+func (r *RollingFileWriter) shouldRotate(n int64) bool {
+	if r.now().Before(r.retryRotate) {
+		return false
+	}
+
+	if r.maxSize > 0 && r.size > 0 && r.size+n > r.maxSize {
+		return true
+	}
+
+	if r.interval > 0 && !r.now().Before(r.nextRotate) {
+		return true
+	}
+
+	return false
+}
+
+// ensureOpen reopens the file when a failed rotation left it closed.
+func (r *RollingFileWriter) ensureOpen() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.f != nil {
+		return nil
+	}
+
+	return r.openFile()
+}
+
+// open opens the file for appending, rotating it first if it belongs to a past interval.
+func (r *RollingFileWriter) open() error {
+	now := r.now()
+
+	info, err := os.Stat(r.path)
+	if err == nil && r.interval > 0 && info.ModTime().Before(r.intervalStart(now)) {
+		if err := r.backup(); err != nil {
+			return err
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := r.openFile(); err != nil {
+		return err
+	}
+
+	if r.interval > 0 {
+		r.nextRotate = r.intervalStart(now).Add(r.interval)
+	}
+
+	return nil
+}
+
+// openFile opens the file for appending.
+func (r *RollingFileWriter) openFile() error {
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	r.f = f
+	r.size = info.Size()
+
+	return nil
+}
+
+// intervalStart returns the start of the interval of t, counted from the unix epoch.
+func (r *RollingFileWriter) intervalStart(t time.Time) time.Time {
+	ns := t.UnixNano()
+	offset := ns % int64(r.interval)
+	if offset < 0 {
+		offset += int64(r.interval)
+	}
+
+	return time.Unix(0, ns-offset)
+}
+
+// rotate rotates the file. On error, the file is reopened as is, and the rotation delayed by rotateRetryDelay.
+func (r *RollingFileWriter) rotate() error {
+	err := r.f.Close()
+	r.f = nil
+
+	if err == nil {
+		if err = r.backup(); err == nil {
+			r.triggerMill()
+			err = r.open()
+		}
+	}
+
+	if err != nil {
+		r.retryRotate = r.now().Add(rotateRetryDelay)
+		if r.f == nil {
+			// retried by the next write if it fails again
+			_ = r.openFile()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// backup renames the file to a unique backup name.
+func (r *RollingFileWriter) backup() error {
+	t := r.now()
+	name := r.backupName(t)
+	for exists(name) || exists(name+compressSuffix) {
+		t = t.Add(time.Millisecond)
+		name = r.backupName(t)
+	}
+
+	return os.Rename(r.path, name)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func (r *RollingFileWriter) backupName(t time.Time) string {
+	return filepath.Join(r.dir, r.prefix+t.UTC().Format(backupTimeFormat)+r.ext)
+}
+
+type backupFile struct {
+	name       string
+	t          time.Time
+	compressed bool
+}
+
+// backups lists the rotated files, the newest first, and removes the stale temporary files.
+func (r *RollingFileWriter) backups() ([]backupFile, error) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []backupFile
+	names := make(map[string]struct{})
+	for _, entry := range entries {
+		names[entry.Name()] = struct{}{}
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		if !strings.HasPrefix(name, r.prefix) {
+			continue
+		}
+
+		if strings.HasSuffix(name, compressSuffix+tmpSuffix) {
+			_ = os.Remove(filepath.Join(r.dir, name))
+			continue
+		}
+
+		compressed := strings.HasSuffix(name, compressSuffix)
+		ts := strings.TrimSuffix(strings.TrimPrefix(name, r.prefix), compressSuffix)
+		if !strings.HasSuffix(ts, r.ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(ts, r.ext))
+		if err != nil {
+			continue
+		}
+
+		if !compressed {
+			// the compression finished but the crash happened before removing the source
+			if _, ok := names[name+compressSuffix]; ok {
+				_ = os.Remove(filepath.Join(r.dir, name))
+				continue
+			}
+		}
+
+		files = append(files, backupFile{name: filepath.Join(r.dir, name), t: t, compressed: compressed})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].t.After(files[j].t)
+	})
+
+	return files, nil
+}
+
+func (r *RollingFileWriter) triggerMill() {
+	select {
+	case r.mill <- struct{}{}:
+	default:
+	}
+}
+
+func (r *RollingFileWriter) stopMill() {
+	close(r.mill)
+	<-r.millDone
+}
+
+func (r *RollingFileWriter) runMill() {
+	defer close(r.millDone)
+
+	for range r.mill {
+		if err := r.millOnce(); err != nil {
+			r.mu.Lock()
+			r.millErr = err
+			r.mu.Unlock()
+		}
+	}
+}
+
+// millOnce removes the expired backups and compresses the remaining ones.
+func (r *RollingFileWriter) millOnce() error {
+	if r.maxBackups == 0 && r.maxAge == 0 && !r.compress {
+		return nil
+	}
+
+	files, err := r.backups()
+	if err != nil {
+		return err
+	}
+
+	var errs []string
+	var cutoff time.Time
+	if r.maxAge > 0 {
+		cutoff = r.now().Add(-r.maxAge)
+	}
+
+	for i, f := range files {
+		if (r.maxBackups > 0 && i >= r.maxBackups) || (r.maxAge > 0 && f.t.Before(cutoff)) {
+			if err := os.Remove(f.name); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+			continue
+		}
+
+		if r.compress && !f.compressed {
+			if err := compressFile(f.name); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("rolling file writer: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// compressFile compresses src into src.gz through a temporary file, then removes src.
+func compressFile(src string) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := src + compressSuffix
+	tmp := dst + tmpSuffix
+
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp, dst); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,310 @@
+package writer
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func readFile(t *testing.T, name string) string {
+	b, err := os.ReadFile(name)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+func TestRollingFileWriterBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	w, err := NewRollingFileWriter(path, WithMaxSize(10), withClock(clock.now))
+	assert.Nil(t, err)
+
+	for _, s := range []string{"12345\n", "67890\n", "abcde\n"} {
+		_, err := w.Write([]byte(s))
+		assert.Nil(t, err)
+		clock.add(time.Second)
+	}
+	assert.Nil(t, w.Flush())
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{
+		"app-2021-01-01T00-00-01.000.log",
+		"app-2021-01-01T00-00-02.000.log",
+		"app.log",
+	}, listDir(t, dir))
+	assert.Equal(t, "12345\n", readFile(t, filepath.Join(dir, "app-2021-01-01T00-00-01.000.log")))
+	assert.Equal(t, "67890\n", readFile(t, filepath.Join(dir, "app-2021-01-01T00-00-02.000.log")))
+	assert.Equal(t, "abcde\n", readFile(t, path))
+
+	_, err = w.Write([]byte("closed"))
+	assert.Equal(t, os.ErrClosed, err)
+}
+
+func TestRollingFileWriterByInterval(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 30, 0, 0, time.UTC)}
+
+	w, err := NewRollingFileWriter(path, WithInterval(time.Hour), withClock(clock.now))
+	assert.Nil(t, err)
+
+	_, err = w.Write([]byte("first\n"))
+	assert.Nil(t, err)
+
+	clock.add(20 * time.Minute)
+	_, err = w.Write([]byte("second\n"))
+	assert.Nil(t, err)
+
+	clock.add(20 * time.Minute)
+	_, err = w.Write([]byte("third\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{"app-2021-01-01T01-10-00.000.log", "app.log"}, listDir(t, dir))
+	assert.Equal(t, "first\nsecond\n", readFile(t, filepath.Join(dir, "app-2021-01-01T01-10-00.000.log")))
+	assert.Equal(t, "third\n", readFile(t, path))
+}
+
+func TestRollingFileWriterIntervalFromEpoch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 30, 0, 0, time.UTC)}
+
+	// the intervals of 7 hours since the epoch start at 21:00, 04:00... on 2021-01-01, UTC
+	w, err := NewRollingFileWriter(path, WithInterval(7*time.Hour), withClock(clock.now))
+	assert.Nil(t, err)
+
+	_, err = w.Write([]byte("first\n"))
+	assert.Nil(t, err)
+
+	clock.add(3*time.Hour + 29*time.Minute)
+	_, err = w.Write([]byte("second\n"))
+	assert.Nil(t, err)
+
+	clock.add(time.Minute)
+	_, err = w.Write([]byte("third\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{"app-2021-01-01T04-00-00.000.log", "app.log"}, listDir(t, dir))
+	assert.Equal(t, "third\n", readFile(t, path))
+}
+
+func TestRollingFileWriterMillError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	// the compression fails, its temporary file being a directory
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "app-2021-01-01T00-00-00.000.log.gz.tmp", "x"), 0755))
+
+	w, err := NewRollingFileWriter(path, WithCompress(true), withClock(clock.now))
+	assert.Nil(t, err)
+	_, err = w.Write([]byte("line\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Rotate())
+
+	// returned by the next Write once
+	assert.Eventually(t, func() bool {
+		n, err := w.Write([]byte("next\n"))
+		assert.Equal(t, 5, n)
+		return err != nil
+	}, time.Second, time.Millisecond)
+	_, err = w.Write([]byte("next\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+}
+
+func TestRollingFileWriterRotateError(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	w, err := NewRollingFileWriter(path, WithMaxSize(10), withClock(clock.now))
+	assert.Nil(t, err)
+
+	_, err = w.Write([]byte("12345\n"))
+	assert.Nil(t, err)
+
+	// the backup fails, the file being gone
+	assert.Nil(t, os.Remove(path))
+	n, err := w.Write([]byte("67890\n"))
+	assert.Equal(t, 6, n)
+	assert.True(t, os.IsNotExist(err), err)
+
+	// written to the file reopened, without retrying the rotation
+	n, err = w.Write([]byte("abcde\n"))
+	assert.Equal(t, 6, n)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Flush())
+	assert.Equal(t, "67890\nabcde\n", readFile(t, path))
+
+	// retried later
+	clock.add(rotateRetryDelay)
+	_, err = w.Write([]byte("fghij\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{"app-2021-01-01T00-00-01.000.log", "app.log"}, listDir(t, dir))
+	assert.Equal(t, "67890\nabcde\n", readFile(t, filepath.Join(dir, "app-2021-01-01T00-00-01.000.log")))
+	assert.Equal(t, "fghij\n", readFile(t, path))
+
+	_, err = w.Write([]byte("closed"))
+	assert.Equal(t, os.ErrClosed, err)
+	assert.Equal(t, os.ErrClosed, w.Close())
+}
+
+func TestRollingFileWriterRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	w, err := NewRollingFileWriter(path, WithMaxBackups(2), WithMaxAge(time.Hour), withClock(clock.now))
+	assert.Nil(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err = w.Write([]byte("line\n"))
+		assert.Nil(t, err)
+		clock.add(time.Minute)
+		assert.Nil(t, w.Rotate())
+	}
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{
+		"app-2021-01-01T00-03-00.000.log",
+		"app-2021-01-01T00-04-00.000.log",
+		"app.log",
+	}, listDir(t, dir))
+
+	clock.add(2 * time.Hour)
+	w, err = NewRollingFileWriter(path, WithMaxBackups(2), WithMaxAge(time.Hour), withClock(clock.now))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{"app.log"}, listDir(t, dir))
+}
+
+func TestRollingFileWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	w, err := NewRollingFileWriter(path, WithCompress(true), withClock(clock.now))
+	assert.Nil(t, err)
+
+	_, err = w.Write([]byte("compressed\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Rotate())
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{"app-2021-01-01T00-00-00.000.log.gz", "app.log"}, listDir(t, dir))
+
+	f, err := os.Open(filepath.Join(dir, "app-2021-01-01T00-00-00.000.log.gz"))
+	assert.Nil(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	assert.Nil(t, err)
+	b, err := io.ReadAll(gz)
+	assert.Nil(t, err)
+	assert.Equal(t, "compressed\n", string(b))
+}
+
+func TestRollingFileWriterRecoverFromCrash(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	clock := &fakeClock{t: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	write := func(name, content string) {
+		assert.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0666))
+	}
+
+	// the crash happened in the middle of a compression
+	write("app-2020-12-31T00-00-00.000.log", "interrupted\n")
+	write("app-2020-12-31T00-00-00.000.log.gz.tmp", "partial")
+	// the crash happened after the compression but before the removal of the source
+	write("app-2020-12-30T00-00-00.000.log", "done\n")
+	assert.Nil(t, compressFile(filepath.Join(dir, "app-2020-12-30T00-00-00.000.log")))
+	write("app-2020-12-30T00-00-00.000.log", "done\n")
+	// the current file survived
+	write("app.log", "current\n")
+	// unrelated files are untouched
+	write("app-other.log", "other\n")
+
+	w, err := NewRollingFileWriter(path, WithCompress(true), withClock(clock.now))
+	assert.Nil(t, err)
+	_, err = w.Write([]byte("appended\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+
+	assert.Equal(t, []string{
+		"app-2020-12-30T00-00-00.000.log.gz",
+		"app-2020-12-31T00-00-00.000.log.gz",
+		"app-other.log",
+		"app.log",
+	}, listDir(t, dir))
+	assert.Equal(t, "current\nappended\n", readFile(t, path))
+}
+
+func TestRollingFileWriterConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	w, err := NewRollingFileWriter(path, WithMaxSize(1024), WithMaxBackups(100))
+	assert.Nil(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := w.Write([]byte("concurrent line\n"))
+				assert.Nil(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, w.Close())
+
+	total := 0
+	for _, name := range listDir(t, dir) {
+		total += strings.Count(readFile(t, filepath.Join(dir, name)), "concurrent line\n")
+	}
+	assert.Equal(t, 1000, total)
+}